@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Toggle is a feature toggle as returned by the API. Group listings use
+// capitalized field names, JSON decoding matches them case-insensitively.
+type Toggle struct {
+	Key         string     `json:"key"`
+	Value       string     `json:"value"`
+	ActiveAt    *time.Time `json:"activeAt"`
+	DisabledAt  *time.Time `json:"disabledAt"`
+	RemoveAt    *time.Time `json:"removeAt"`
+	Tags        []string   `json:"tags"`
+	Description string     `json:"description"`
+	Owner       string     `json:"owner"`
+	Kind        string     `json:"kind"`
+	CreatedAt   *time.Time `json:"createdAt,omitempty"`
+	UpdatedAt   *time.Time `json:"updatedAt,omitempty"`
+	// Secret is only returned when a toggle creates a new group
+	Secret string `json:"secret,omitempty"`
+}
+
+// apiError is an error response of the API
+type apiError struct {
+	Status  int
+	Message string
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("%s (HTTP %d)", e.Message, e.Status)
+}
+
+// client calls the HTTP API of a YaFT instance
+type client struct {
+	baseURL string
+	http    *http.Client
+}
+
+func newClient(baseURL string) *client {
+	return &client{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		http:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// path joins escaped path segments
+func path(segments ...string) string {
+	escaped := make([]string, len(segments))
+	for i, segment := range segments {
+		escaped[i] = url.PathEscape(segment)
+	}
+	return "/" + strings.Join(escaped, "/")
+}
+
+// do sends a request with an optional JSON body and decodes the JSON
+// response into out, error responses are returned as *apiError
+func (c *client) do(method string, path string, query url.Values, body interface{}, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	target := c.baseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	req, err := http.NewRequest(method, target, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 400 {
+		var response struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(data, &response) != nil || response.Error == "" {
+			response.Error = http.StatusText(resp.StatusCode)
+		}
+		return &apiError{Status: resp.StatusCode, Message: response.Error}
+	}
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
+
+// listPage is a page of a group listing
+type listPage struct {
+	Toggles    []Toggle `json:"toggles"`
+	Total      int      `json:"total"`
+	NextCursor string   `json:"nextCursor"`
+}
+
+// list returns all toggles of a group matching the tags expression and,
+// unless empty, the stored value, following the pages of the listing
+func (c *client) list(group string, tags string, value string) ([]Toggle, error) {
+	query := url.Values{"limit": {"1000"}}
+	if tags != "" {
+		query.Set("tags", tags)
+	}
+	if value != "" {
+		query.Set("value", value)
+	}
+
+	toggles := []Toggle{}
+	for {
+		var page listPage
+		if err := c.do(http.MethodGet, path("features", group), query, nil, &page); err != nil {
+			return nil, err
+		}
+		toggles = append(toggles, page.Toggles...)
+		if page.NextCursor == "" {
+			return toggles, nil
+		}
+		query.Set("cursor", page.NextCursor)
+	}
+}
+
+// isNotFound reports whether the API answered 404, e.g. for a listing
+// without matching toggles
+func isNotFound(err error) bool {
+	var apiErr *apiError
+	return errors.As(err, &apiErr) && apiErr.Status == http.StatusNotFound
+}
@@ -0,0 +1,611 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// cli is the state of one invocation
+type cli struct {
+	stdin   io.Reader
+	stdout  io.Writer
+	stderr  io.Writer
+	getenv  func(string) string
+	command command
+
+	// flags shared by all commands
+	profileFlag string
+	urlFlag     string
+	groupFlag   string
+	secretFlag  string
+	outputFlag  string
+
+	// resolved from flags, environment and profile
+	profileName  string
+	profilesPath string
+	profiles     *profiles
+	url          string
+	group        string
+	secret       string
+	printer      *printer
+	client       *client
+}
+
+// flags returns the flag set of the command with the shared flags registered
+func (c *cli) flags() *flag.FlagSet {
+	fs := flag.NewFlagSet("yaft "+c.command.name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(c.stderr, "Usage: yaft %s %s\n\n%s, see yaft help for the flags of every command\n", c.command.name, c.command.args, c.command.usage)
+	}
+	fs.StringVar(&c.profileFlag, "profile", "", "profile to use")
+	fs.StringVar(&c.urlFlag, "url", "", "API address")
+	fs.StringVar(&c.groupFlag, "group", "", "group of the toggles")
+	fs.StringVar(&c.secretFlag, "secret", "", "secret of the group")
+	fs.StringVar(&c.outputFlag, "output", "", "table or json")
+	return fs
+}
+
+// parse parses flags before and after the arguments, resolves the profile
+// and checks the number of arguments
+func (c *cli) parse(fs *flag.FlagSet, args []string, minArgs int, maxArgs int) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return nil, err
+			}
+			return nil, &usageError{message: err.Error()}
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+	if len(positional) < minArgs || len(positional) > maxArgs {
+		return nil, usagef("expected %s", strings.TrimSpace("yaft "+c.command.name+" "+c.command.args))
+	}
+
+	if err := c.resolve(); err != nil {
+		return nil, err
+	}
+	return positional, nil
+}
+
+// first returns the first non-empty value
+func first(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// resolve applies flags over environment over profile
+func (c *cli) resolve() error {
+	var err error
+	if c.profilesPath, err = profilesPath(c.getenv); err != nil {
+		return err
+	}
+	if c.profiles, err = loadProfiles(c.profilesPath); err != nil {
+		return err
+	}
+	c.profileName = first(c.profileFlag, c.getenv("YAFT_PROFILE"), c.profiles.Current, "default")
+	profile := c.profiles.Profiles[c.profileName]
+
+	c.url = first(c.urlFlag, c.getenv("YAFT_URL"), profile.URL, defaultURL)
+	c.group = first(c.groupFlag, c.getenv("YAFT_GROUP"), profile.Group)
+	c.secret = first(c.secretFlag, c.getenv("YAFT_SECRET"), profile.Secret)
+
+	format := first(c.outputFlag, c.getenv("YAFT_OUTPUT"), outputTable)
+	if format != outputTable && format != outputJSON {
+		return usagef("invalid output %q, expected table or json", format)
+	}
+	c.printer = &printer{out: c.stdout, format: format}
+	c.client = newClient(c.url)
+	return nil
+}
+
+// key returns the key of a toggle name in the group
+func (c *cli) key(name string) (string, error) {
+	if strings.Contains(name, "|") {
+		return name, nil
+	}
+	if c.group == "" {
+		return "", usagef("no group, pass --group or use a profile with a group")
+	}
+	return c.group + "|" + name, nil
+}
+
+// requireGroup checks that group and secret are known
+func (c *cli) requireGroup() error {
+	if c.group == "" {
+		return usagef("no group, pass --group or use a profile with a group")
+	}
+	return c.requireSecret()
+}
+
+func (c *cli) requireSecret() error {
+	if c.secret == "" {
+		return usagef("no secret, pass --secret or use a profile with a secret")
+	}
+	return nil
+}
+
+// remember saves new credentials of the group to the profile, unless the
+// profile belongs to another group
+func (c *cli) remember(group string, secret string) error {
+	c.group, c.secret = group, secret
+
+	profile := c.profiles.Profiles[c.profileName]
+	if profile.Group != "" && profile.Group != group {
+		fmt.Fprintf(c.stderr, "Profile %q belongs to another group, the credentials of group %s were not saved\n", c.profileName, group)
+		return nil
+	}
+	profile.URL = c.url
+	profile.Group = group
+	profile.Secret = secret
+	c.profiles.Profiles[c.profileName] = profile
+	if err := c.profiles.save(c.profilesPath); err != nil {
+		return fmt.Errorf("failed to save profile: %w", err)
+	}
+	fmt.Fprintf(c.stderr, "Saved the credentials of group %s to profile %q\n", group, c.profileName)
+	return nil
+}
+
+func (c *cli) create(args []string) error {
+	fs := c.flags()
+	value := fs.Bool("value", false, "activate the toggle")
+	tags := fs.String("tags", "", "comma separated tags")
+	description := fs.String("description", "", "description")
+	owner := fs.String("owner", "", "owning team")
+	kind := fs.String("kind", "", "release, experiment, ops or permission")
+	positional, err := c.parse(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	body := map[string]interface{}{
+		"key":         positional[0],
+		"value":       fmt.Sprint(*value),
+		"tags":        splitList(*tags),
+		"description": *description,
+		"owner":       *owner,
+		"kind":        *kind,
+	}
+	if c.group != "" {
+		if err := c.requireSecret(); err != nil {
+			return err
+		}
+		if body["key"], err = c.key(positional[0]); err != nil {
+			return err
+		}
+		body["secret"] = c.secret
+	}
+
+	var toggle Toggle
+	if err := c.client.do(http.MethodPost, "/features", nil, body, &toggle); err != nil {
+		return err
+	}
+	if toggle.Secret != "" {
+		if err := c.remember(strings.Split(toggle.Key, "|")[0], toggle.Secret); err != nil {
+			return err
+		}
+	}
+	return c.printer.toggles(toggle, []Toggle{toggle})
+}
+
+func (c *cli) get(args []string) error {
+	positional, err := c.parse(c.flags(), args, 1, 1)
+	if err != nil {
+		return err
+	}
+	key, err := c.key(positional[0])
+	if err != nil {
+		return err
+	}
+
+	var toggle Toggle
+	if err := c.client.do(http.MethodGet, path("features", key), nil, nil, &toggle); err != nil {
+		return err
+	}
+	return c.printer.toggles(toggle, []Toggle{toggle})
+}
+
+func (c *cli) list(args []string) error {
+	fs := c.flags()
+	tags := fs.String("tags", "", "tag expression, e.g. beta,!legacy|sprint-12")
+	if _, err := c.parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+	if c.group == "" {
+		return usagef("no group, pass --group or use a profile with a group")
+	}
+
+	toggles, err := c.client.list(c.group, *tags, "")
+	if err != nil {
+		return err
+	}
+	return c.printer.toggles(toggles, toggles)
+}
+
+// setValue activates or deactivates a toggle
+func (c *cli) setValue(args []string, route string) error {
+	positional, err := c.parse(c.flags(), args, 1, 1)
+	if err != nil {
+		return err
+	}
+	key, err := c.key(positional[0])
+	if err != nil {
+		return err
+	}
+	if err := c.requireSecret(); err != nil {
+		return err
+	}
+
+	var toggle Toggle
+	if err := c.client.do(http.MethodPut, path("features", route, key, c.secret), nil, nil, &toggle); err != nil {
+		return err
+	}
+	return c.printer.toggles(toggle, []Toggle{toggle})
+}
+
+func (c *cli) activate(args []string) error {
+	return c.setValue(args, "activate")
+}
+
+func (c *cli) deactivate(args []string) error {
+	return c.setValue(args, "deactivate")
+}
+
+func (c *cli) schedule(args []string) error {
+	fs := c.flags()
+	activateAt := fs.String("activate-at", "", "activation date, RFC3339, date-time or date")
+	deactivateAt := fs.String("deactivate-at", "", "deactivation date, RFC3339, date-time or date")
+	timeZone := fs.String("tz", "", "IANA time zone of dates without offset, defaults to UTC")
+	clearActivate := fs.Bool("clear-activate", false, "remove the activation date")
+	clearDeactivate := fs.Bool("clear-deactivate", false, "remove the deactivation date")
+	positional, err := c.parse(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	if *activateAt == "" && *deactivateAt == "" && !*clearActivate && !*clearDeactivate {
+		return usagef("expected --activate-at, --deactivate-at, --clear-activate or --clear-deactivate")
+	}
+	if (*activateAt != "" && *clearActivate) || (*deactivateAt != "" && *clearDeactivate) {
+		return usagef("a date cannot be set and cleared at once")
+	}
+	key, err := c.key(positional[0])
+	if err != nil {
+		return err
+	}
+	if err := c.requireSecret(); err != nil {
+		return err
+	}
+
+	var query url.Values
+	if *timeZone != "" {
+		query = url.Values{"tz": {*timeZone}}
+	}
+	var toggle Toggle
+	steps := []struct {
+		run    bool
+		method string
+		path   string
+	}{
+		{*clearActivate, http.MethodDelete, path("features", "activateAt", key, c.secret)},
+		{*clearDeactivate, http.MethodDelete, path("features", "deactivateAt", key, c.secret)},
+		{*activateAt != "", http.MethodPut, path("features", "activateAt", key, *activateAt, c.secret)},
+		{*deactivateAt != "", http.MethodPut, path("features", "deactivateAt", key, *deactivateAt, c.secret)},
+	}
+	for _, step := range steps {
+		if !step.run {
+			continue
+		}
+		if err := c.client.do(step.method, step.path, query, nil, &toggle); err != nil {
+			return err
+		}
+	}
+	return c.printer.toggles(toggle, []Toggle{toggle})
+}
+
+func (c *cli) delete(args []string) error {
+	positional, err := c.parse(c.flags(), args, 1, 1)
+	if err != nil {
+		return err
+	}
+	key, err := c.key(positional[0])
+	if err != nil {
+		return err
+	}
+	if err := c.requireSecret(); err != nil {
+		return err
+	}
+
+	if err := c.client.do(http.MethodDelete, path("features", key, c.secret), nil, nil, nil); err != nil {
+		return err
+	}
+	return c.printer.message("Deleted %s", toggleName(key))
+}
+
+// rotation is the response of a secret rotation
+type rotation struct {
+	Key                     string    `json:"key"`
+	Secret                  string    `json:"secret,omitempty"`
+	PreviousSecretExpiresAt time.Time `json:"previousSecretExpiresAt"`
+}
+
+func (c *cli) rotateSecret(args []string) error {
+	fs := c.flags()
+	overlap := fs.String("overlap", "", "how long the previous secret stays valid, defaults to the server setting")
+	newSecret := fs.String("new-secret", "", "the new secret, generated by the server if omitted")
+	if _, err := c.parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+	if err := c.requireGroup(); err != nil {
+		return err
+	}
+
+	var query url.Values
+	if *overlap != "" {
+		query = url.Values{"overlap": {*overlap}}
+	}
+	segments := []string{"secret", "rotate", c.group, c.secret}
+	if *newSecret != "" {
+		segments = append(segments, *newSecret)
+	}
+	var response rotation
+	if err := c.client.do(http.MethodPut, path(segments...), query, nil, &response); err != nil {
+		return err
+	}
+
+	secret := first(response.Secret, *newSecret)
+	if err := c.remember(c.group, secret); err != nil {
+		return err
+	}
+	response.Secret = secret
+	return c.printer.print(response, []string{"GROUP", "PREVIOUS SECRET VALID UNTIL"}, [][]string{
+		{response.Key, response.PreviousSecretExpiresAt.Format(time.RFC3339)},
+	})
+}
+
+// exportFile is the format of export and import, toggles are stored by name
+// so they can be imported into another group
+type exportFile struct {
+	Group   string         `json:"group,omitempty"`
+	Toggles []exportToggle `json:"toggles"`
+}
+
+type exportToggle struct {
+	Name        string     `json:"name"`
+	Value       string     `json:"value"`
+	ActiveAt    *time.Time `json:"activeAt,omitempty"`
+	DisabledAt  *time.Time `json:"disabledAt,omitempty"`
+	RemoveAt    *time.Time `json:"removeAt,omitempty"`
+	Tags        []string   `json:"tags,omitempty"`
+	Description string     `json:"description,omitempty"`
+	Owner       string     `json:"owner,omitempty"`
+	Kind        string     `json:"kind,omitempty"`
+}
+
+func (c *cli) export(args []string) error {
+	fs := c.flags()
+	tags := fs.String("tags", "", "tag expression selecting the exported toggles")
+	file := fs.String("file", "", "write to the file instead of stdout")
+	if _, err := c.parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+	if c.group == "" {
+		return usagef("no group, pass --group or use a profile with a group")
+	}
+
+	toggles, err := c.client.list(c.group, *tags, "")
+	if err != nil {
+		return err
+	}
+	// Listings serve effective values, the stored values are exported so that
+	// imported schedules behave the same
+	active, err := c.client.list(c.group, *tags, "true")
+	if err != nil && !isNotFound(err) {
+		return err
+	}
+	stored := map[string]bool{}
+	for _, toggle := range active {
+		stored[toggle.Key] = true
+	}
+
+	export := exportFile{Group: c.group, Toggles: []exportToggle{}}
+	for _, toggle := range toggles {
+		export.Toggles = append(export.Toggles, exportToggle{
+			Name:        toggleName(toggle.Key),
+			Value:       fmt.Sprint(stored[toggle.Key]),
+			ActiveAt:    toggle.ActiveAt,
+			DisabledAt:  toggle.DisabledAt,
+			RemoveAt:    toggle.RemoveAt,
+			Tags:        toggle.Tags,
+			Description: toggle.Description,
+			Owner:       toggle.Owner,
+			Kind:        toggle.Kind,
+		})
+	}
+
+	data, err := json.MarshalIndent(export, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if *file != "" {
+		return os.WriteFile(*file, data, 0o600)
+	}
+	_, err = c.stdout.Write(data)
+	return err
+}
+
+// importResult counts the toggles of an import
+type importResult struct {
+	Group   string `json:"group"`
+	Created int    `json:"created"`
+	Updated int    `json:"updated"`
+}
+
+func (c *cli) importToggles(args []string) error {
+	positional, err := c.parse(c.flags(), args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	var data []byte
+	if positional[0] == "-" {
+		data, err = io.ReadAll(c.stdin)
+	} else {
+		data, err = os.ReadFile(positional[0])
+	}
+	if err != nil {
+		return err
+	}
+	var file exportFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("invalid export: %w", err)
+	}
+
+	existing := map[string]bool{}
+	if c.group != "" {
+		if err := c.requireSecret(); err != nil {
+			return err
+		}
+		toggles, err := c.client.list(c.group, "", "")
+		if err != nil && !isNotFound(err) {
+			return err
+		}
+		for _, toggle := range toggles {
+			existing[toggleName(toggle.Key)] = true
+		}
+	}
+
+	result := importResult{}
+	for _, toggle := range file.Toggles {
+		body := map[string]interface{}{
+			"value":       toggle.Value,
+			"activeAt":    toggle.ActiveAt,
+			"disabledAt":  toggle.DisabledAt,
+			"removeAt":    toggle.RemoveAt,
+			"tags":        toggle.Tags,
+			"description": toggle.Description,
+			"owner":       toggle.Owner,
+			"kind":        toggle.Kind,
+		}
+
+		if existing[toggle.Name] {
+			body["secret"] = c.secret
+			if err := c.client.do(http.MethodPatch, path("features", c.group+"|"+toggle.Name), nil, body, nil); err != nil {
+				return fmt.Errorf("failed to update %s: %w", toggle.Name, err)
+			}
+			result.Updated++
+			continue
+		}
+
+		body["key"] = toggle.Name
+		if c.group != "" {
+			body["key"] = c.group + "|" + toggle.Name
+			body["secret"] = c.secret
+		}
+		var created Toggle
+		if err := c.client.do(http.MethodPost, "/features", nil, body, &created); err != nil {
+			return fmt.Errorf("failed to create %s: %w", toggle.Name, err)
+		}
+		// The first toggle without group creates one, the others join it
+		if created.Secret != "" {
+			if err := c.remember(strings.Split(created.Key, "|")[0], created.Secret); err != nil {
+				return err
+			}
+		}
+		existing[toggle.Name] = true
+		result.Created++
+	}
+
+	result.Group = c.group
+	return c.printer.print(result, []string{"GROUP", "CREATED", "UPDATED"}, [][]string{
+		{orDash(result.Group), fmt.Sprint(result.Created), fmt.Sprint(result.Updated)},
+	})
+}
+
+func (c *cli) profile(args []string) error {
+	positional, err := c.parse(c.flags(), args, 1, 2)
+	if err != nil {
+		return err
+	}
+	action := positional[0]
+	name := ""
+	if len(positional) == 2 {
+		name = positional[1]
+	}
+
+	switch {
+	case action == "list" && name == "":
+		rows := [][]string{}
+		list := []map[string]interface{}{}
+		for _, profileName := range c.profiles.names() {
+			profile := c.profiles.Profiles[profileName]
+			current := profileName == c.profiles.Current
+			marker := ""
+			if current {
+				marker = "*"
+			}
+			rows = append(rows, []string{marker, profileName, orDash(profile.URL), orDash(profile.Group)})
+			list = append(list, map[string]interface{}{"name": profileName, "url": profile.URL, "group": profile.Group, "current": current})
+		}
+		return c.printer.print(list, []string{"", "NAME", "URL", "GROUP"}, rows)
+	case name == "":
+		return usagef("expected yaft profile %s <name>", action)
+	case action == "set":
+		profile := c.profiles.Profiles[name]
+		profile.URL = first(c.urlFlag, profile.URL)
+		profile.Group = first(c.groupFlag, profile.Group)
+		profile.Secret = first(c.secretFlag, profile.Secret)
+		c.profiles.Profiles[name] = profile
+		if c.profiles.Current == "" {
+			c.profiles.Current = name
+		}
+	case action == "use":
+		if _, ok := c.profiles.Profiles[name]; !ok {
+			return usagef("unknown profile %q", name)
+		}
+		c.profiles.Current = name
+	case action == "delete":
+		if _, ok := c.profiles.Profiles[name]; !ok {
+			return usagef("unknown profile %q", name)
+		}
+		delete(c.profiles.Profiles, name)
+		if c.profiles.Current == name {
+			c.profiles.Current = ""
+		}
+	default:
+		return usagef("unknown profile action %q, expected set, use, list or delete", action)
+	}
+
+	if err := c.profiles.save(c.profilesPath); err != nil {
+		return err
+	}
+	return c.printer.message("Saved profiles to %s", c.profilesPath)
+}
+
+// splitList splits a comma separated list, dropping empty items
+func splitList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
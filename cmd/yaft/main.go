@@ -0,0 +1,138 @@
+// Command yaft is a command line client for the YaFT HTTP API. It keeps the
+// address and credentials of groups in profiles, so secrets do not have to be
+// pasted into URLs.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+)
+
+// Exit codes, scripts can tell missing toggles and invalid credentials from
+// other failures
+const (
+	exitOK = iota
+	exitError
+	exitUsage
+	exitNotFound
+	exitUnauthorized
+	exitUnavailable
+)
+
+// usageError is an invalid invocation, it exits with exitUsage
+type usageError struct {
+	message string
+}
+
+func (e *usageError) Error() string {
+	return e.message
+}
+
+func usagef(format string, args ...interface{}) error {
+	return &usageError{message: fmt.Sprintf(format, args...)}
+}
+
+// command is a subcommand of yaft
+type command struct {
+	name  string
+	args  string
+	usage string
+	run   func(c *cli, args []string) error
+}
+
+var commands = []command{
+	{"create", "<name> [--value] [--tags a,b] [--description text] [--owner team] [--kind release]", "create a toggle, without group a new group is created and saved to the profile", (*cli).create},
+	{"get", "<name>", "show a toggle", (*cli).get},
+	{"list", "[--tags expression]", "list the toggles of the group", (*cli).list},
+	{"activate", "<name>", "activate a toggle", (*cli).activate},
+	{"deactivate", "<name>", "deactivate a toggle", (*cli).deactivate},
+	{"schedule", "<name> [--activate-at date] [--deactivate-at date] [--tz zone] [--clear-activate] [--clear-deactivate]", "set or clear the activation and deactivation dates", (*cli).schedule},
+	{"delete", "<name>", "delete a toggle", (*cli).delete},
+	{"rotate-secret", "[--overlap 24h] [--new-secret secret]", "rotate the secret of the group and save it to the profile", (*cli).rotateSecret},
+	{"export", "[--tags expression] [--file path]", "write the toggles of the group as JSON", (*cli).export},
+	{"import", "<file|->", "create or update the toggles of an export in the group", (*cli).importToggles},
+	{"profile", "set|use|list|delete [name]", "manage the profiles, `profile set` stores --url, --group and --secret", (*cli).profile},
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr, os.Getenv))
+}
+
+// run executes a command and returns its exit code
+func run(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer, getenv func(string) string) int {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		usage(stdout)
+		return exitOK
+	}
+
+	for _, cmd := range commands {
+		if cmd.name != args[0] {
+			continue
+		}
+		c := &cli{stdin: stdin, stdout: stdout, stderr: stderr, getenv: getenv, command: cmd}
+		err := cmd.run(c, args[1:])
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		if err != nil {
+			fmt.Fprintln(stderr, "yaft "+cmd.name+": "+err.Error())
+		}
+		return exitCode(err)
+	}
+
+	fmt.Fprintf(stderr, "yaft: unknown command %q, see yaft help\n", args[0])
+	return exitUsage
+}
+
+// exitCode maps an error to the exit code of the process
+func exitCode(err error) int {
+	if err == nil {
+		return exitOK
+	}
+
+	var usageErr *usageError
+	if errors.As(err, &usageErr) {
+		return exitUsage
+	}
+	var apiErr *apiError
+	if errors.As(err, &apiErr) {
+		switch {
+		case apiErr.Status == http.StatusNotFound:
+			return exitNotFound
+		case apiErr.Status == http.StatusUnauthorized || apiErr.Status == http.StatusForbidden:
+			return exitUnauthorized
+		case apiErr.Status == http.StatusTooManyRequests || apiErr.Status >= 500:
+			return exitUnavailable
+		}
+		return exitError
+	}
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return exitUnavailable
+	}
+	return exitError
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: yaft <command> [flags] [arguments]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-14s %s\n", cmd.name, cmd.usage)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Flags of every command:")
+	fmt.Fprintln(w, "  --profile name   profile to use (YAFT_PROFILE), defaults to the current profile")
+	fmt.Fprintln(w, "  --url url        API address (YAFT_URL), defaults to "+defaultURL)
+	fmt.Fprintln(w, "  --group uuid     group of the toggles (YAFT_GROUP)")
+	fmt.Fprintln(w, "  --secret secret  secret of the group (YAFT_SECRET)")
+	fmt.Fprintln(w, "  --output format  table or json (YAFT_OUTPUT), defaults to table")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Exit codes: 0 success, 1 error, 2 invalid usage, 3 not found, 4 invalid credentials, 5 API unavailable")
+	fmt.Fprintln(w, "Profiles are stored in yaft/profiles.json of the user configuration directory, YAFT_PROFILES overrides the path")
+}
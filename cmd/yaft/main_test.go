@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"tehwolf.de/tehw0lf/yaft/server"
+	"tehwolf.de/tehw0lf/yaft/store"
+)
+
+// setupCLI starts a YaFT server and returns a function running the CLI
+// against it with its own profile file
+func setupCLI(t *testing.T) func(args ...string) (int, string, string) {
+	gin.SetMode(gin.TestMode)
+	srv := server.NewServer(store.NewMemory(), server.Options{})
+	t.Cleanup(func() { srv.Close() })
+	api := httptest.NewServer(srv)
+	t.Cleanup(api.Close)
+
+	environment := map[string]string{
+		"YAFT_PROFILES": filepath.Join(t.TempDir(), "profiles.json"),
+		"YAFT_URL":      api.URL,
+	}
+	return func(args ...string) (int, string, string) {
+		var stdout, stderr bytes.Buffer
+		code := run(args, strings.NewReader(""), &stdout, &stderr, func(name string) string { return environment[name] })
+		return code, stdout.String(), stderr.String()
+	}
+}
+
+func TestCLI(t *testing.T) {
+	yaft := setupCLI(t)
+
+	code, _, stderr := yaft("create", "checkout", "--kind", "release")
+	require.Equal(t, exitOK, code, stderr)
+	assert.Contains(t, stderr, `to profile "default"`)
+	code, _, stderr = yaft("create", "beta", "--value", "--tags", "web,mobile")
+	require.Equal(t, exitOK, code, stderr)
+
+	code, stdout, _ := yaft("list", "--output", "json")
+	require.Equal(t, exitOK, code)
+	var toggles []Toggle
+	require.NoError(t, json.Unmarshal([]byte(stdout), &toggles))
+	require.Len(t, toggles, 2)
+	assert.Equal(t, "true", toggles[0].Value)
+	assert.Equal(t, []string{"web", "mobile"}, toggles[0].Tags)
+
+	code, stdout, _ = yaft("list", "--tags", "web")
+	require.Equal(t, exitOK, code)
+	assert.Contains(t, stdout, "NAME")
+	assert.Contains(t, stdout, "beta")
+	assert.NotContains(t, stdout, "checkout")
+
+	code, stdout, _ = yaft("activate", "checkout", "--output", "json")
+	require.Equal(t, exitOK, code)
+	var toggle Toggle
+	require.NoError(t, json.Unmarshal([]byte(stdout), &toggle))
+	assert.Equal(t, "true", toggle.Value)
+
+	code, _, stderr = yaft("schedule", "checkout", "--deactivate-at", "2020-01-01")
+	require.Equal(t, exitOK, code, stderr)
+	code, stdout, _ = yaft("get", "checkout", "--output", "json")
+	require.Equal(t, exitOK, code)
+	require.NoError(t, json.Unmarshal([]byte(stdout), &toggle))
+	assert.Equal(t, "false", toggle.Value)
+	require.NotNil(t, toggle.DisabledAt)
+
+	// The export keeps the stored value besides the schedule
+	exportPath := filepath.Join(t.TempDir(), "export.json")
+	code, _, stderr = yaft("export", "--file", exportPath)
+	require.Equal(t, exitOK, code, stderr)
+	data, err := os.ReadFile(exportPath)
+	require.NoError(t, err)
+	var export exportFile
+	require.NoError(t, json.Unmarshal(data, &export))
+	require.Len(t, export.Toggles, 2)
+	assert.Equal(t, "checkout", export.Toggles[1].Name)
+	assert.Equal(t, "true", export.Toggles[1].Value)
+
+	// Importing without group creates one for the copy profile
+	code, _, stderr = yaft("import", exportPath, "--profile", "copy")
+	require.Equal(t, exitOK, code, stderr)
+	code, stdout, _ = yaft("list", "--profile", "copy", "--output", "json")
+	require.Equal(t, exitOK, code)
+	require.NoError(t, json.Unmarshal([]byte(stdout), &toggles))
+	assert.Len(t, toggles, 2)
+
+	// Importing again updates the toggles
+	code, stdout, _ = yaft("import", exportPath, "--profile", "copy", "--output", "json")
+	require.Equal(t, exitOK, code)
+	var result importResult
+	require.NoError(t, json.Unmarshal([]byte(stdout), &result))
+	assert.Equal(t, 0, result.Created)
+	assert.Equal(t, 2, result.Updated)
+
+	code, _, stderr = yaft("rotate-secret", "--overlap", "0s")
+	require.Equal(t, exitOK, code, stderr)
+	code, _, _ = yaft("deactivate", "beta")
+	assert.Equal(t, exitOK, code, "the rotated secret is saved to the profile")
+
+	code, _, _ = yaft("delete", "checkout")
+	require.Equal(t, exitOK, code)
+
+	code, stdout, _ = yaft("profile", "list")
+	require.Equal(t, exitOK, code)
+	assert.Contains(t, stdout, "copy")
+	assert.Contains(t, stdout, "default")
+}
+
+func TestCLIExitCodes(t *testing.T) {
+	yaft := setupCLI(t)
+	code, _, stderr := yaft("create", "checkout")
+	require.Equal(t, exitOK, code, stderr)
+
+	tests := []struct {
+		name string
+		args []string
+		code int
+	}{
+		{"help", []string{"help"}, exitOK},
+		{"unknown command", []string{"toggle"}, exitUsage},
+		{"missing name", []string{"get"}, exitUsage},
+		{"unknown flag", []string{"list", "--all"}, exitUsage},
+		{"invalid output", []string{"list", "--output", "yaml"}, exitUsage},
+		{"schedule without dates", []string{"schedule", "checkout"}, exitUsage},
+		{"unknown toggle", []string{"get", "unknown"}, exitNotFound},
+		{"deleted toggle", []string{"delete", "unknown"}, exitNotFound},
+		{"invalid secret", []string{"activate", "checkout", "--secret", "wrong"}, exitUnauthorized},
+		{"invalid date", []string{"schedule", "checkout", "--activate-at", "soon"}, exitError},
+		{"unreachable API", []string{"get", "checkout", "--url", "http://127.0.0.1:1"}, exitUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, _ := yaft(tt.args...)
+			assert.Equal(t, tt.code, code)
+		})
+	}
+}
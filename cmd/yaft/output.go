@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Output formats
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+// printer writes results as JSON for scripts or as aligned table for people
+type printer struct {
+	out    io.Writer
+	format string
+}
+
+// print writes v as indented JSON, or the rows below the header as table
+func (p *printer) print(v interface{}, header []string, rows [][]string) error {
+	if p.format == outputJSON {
+		encoder := json.NewEncoder(p.out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(v)
+	}
+
+	w := tabwriter.NewWriter(p.out, 0, 0, 2, ' ', 0)
+	if len(header) > 0 {
+		fmt.Fprintln(w, strings.Join(header, "\t"))
+	}
+	for _, row := range rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	return w.Flush()
+}
+
+// toggles prints toggles with their names, the group is the same for all of them
+func (p *printer) toggles(v interface{}, toggles []Toggle) error {
+	rows := make([][]string, 0, len(toggles))
+	for _, toggle := range toggles {
+		rows = append(rows, []string{
+			toggleName(toggle.Key),
+			toggle.Value,
+			formatTime(toggle.ActiveAt),
+			formatTime(toggle.DisabledAt),
+			formatList(toggle.Tags),
+			orDash(toggle.Kind),
+			orDash(toggle.Owner),
+		})
+	}
+	return p.print(v, []string{"NAME", "VALUE", "ACTIVE AT", "DISABLED AT", "TAGS", "KIND", "OWNER"}, rows)
+}
+
+// message prints a confirmation, as {"message": ...} in JSON
+func (p *printer) message(format string, args ...interface{}) error {
+	message := fmt.Sprintf(format, args...)
+	return p.print(map[string]string{"message": message}, nil, [][]string{{message}})
+}
+
+// toggleName strips the group from a key
+func toggleName(key string) string {
+	if i := strings.Index(key, "|"); i >= 0 {
+		return key[i+1:]
+	}
+	return key
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Format(time.RFC3339)
+}
+
+func formatList(items []string) string {
+	if len(items) == 0 {
+		return "-"
+	}
+	return strings.Join(items, ",")
+}
+
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// defaultURL is the API address used when neither profile nor flag sets one
+const defaultURL = "http://127.0.0.1:8080"
+
+// Profile holds the address of a YaFT instance and the credentials of a group
+type Profile struct {
+	URL    string `json:"url,omitempty"`
+	Group  string `json:"group,omitempty"`
+	Secret string `json:"secret,omitempty"`
+}
+
+// profiles is the profile file, Current is used when no profile is selected
+type profiles struct {
+	Current  string             `json:"current,omitempty"`
+	Profiles map[string]Profile `json:"profiles"`
+}
+
+// profilesPath returns the profile file, YAFT_PROFILES overrides the default
+// in the user configuration directory
+func profilesPath(getenv func(string) string) (string, error) {
+	if path := getenv("YAFT_PROFILES"); path != "" {
+		return path, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "yaft", "profiles.json"), nil
+}
+
+// loadProfiles reads the profile file, a missing file has no profiles
+func loadProfiles(path string) (*profiles, error) {
+	p := &profiles{Profiles: map[string]Profile{}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return p, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, p); err != nil {
+		return nil, fmt.Errorf("failed to parse profiles %s: %w", path, err)
+	}
+	if p.Profiles == nil {
+		p.Profiles = map[string]Profile{}
+	}
+	return p, nil
+}
+
+// save writes the profile file readable for the user only, it contains secrets
+func (p *profiles) save(path string) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o600)
+}
+
+// names returns the profile names in order
+func (p *profiles) names() []string {
+	names := make([]string, 0, len(p.Profiles))
+	for name := range p.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
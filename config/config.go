@@ -0,0 +1,571 @@
+// Package config loads the YaFT configuration from defaults, a YAML file,
+// environment variables and command-line flags, in that order of precedence.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+type Config struct {
+	// Listen is the address the HTTP API listens on
+	Listen    string          `yaml:"listen"`
+	TLS       TLSConfig       `yaml:"tls"`
+	Log       LogConfig       `yaml:"log"`
+	Database  DatabaseConfig  `yaml:"database"`
+	CORS      CORSConfig      `yaml:"cors"`
+	Cache     CacheConfig     `yaml:"cache"`
+	RateLimit RateLimitConfig `yaml:"rateLimit"`
+	Shutdown  ShutdownConfig  `yaml:"shutdown"`
+	Tracing   TracingConfig   `yaml:"tracing"`
+	Secrets   SecretsConfig   `yaml:"secrets"`
+	Admin     AdminConfig     `yaml:"admin"`
+	Admission AdmissionConfig `yaml:"admission"`
+	GRPC      GRPCConfig      `yaml:"grpc"`
+	UI        UIConfig        `yaml:"ui"`
+	OpenAPI   OpenAPIConfig   `yaml:"openapi"`
+
+	// TrustedProxies are the IPs or CIDR ranges of reverse proxies whose
+	// X-Forwarded-For header sets the client IP
+	TrustedProxies []string `yaml:"trustedProxies"`
+}
+
+// TLSConfig enables HTTPS when both files are set
+type TLSConfig struct {
+	CertFile string `yaml:"certFile"`
+	KeyFile  string `yaml:"keyFile"`
+}
+
+type LogConfig struct {
+	// Level is one of the logrus levels, e.g. "debug" or "info"
+	Level string `yaml:"level"`
+	// Format is "json" or "text"
+	Format string `yaml:"format"`
+}
+
+type DatabaseConfig struct {
+	// DSN selects the store, see the README for the supported backends
+	DSN             string        `yaml:"dsn"`
+	MaxOpenConns    int           `yaml:"maxOpenConns"`
+	MaxIdleConns    int           `yaml:"maxIdleConns"`
+	ConnMaxLifetime time.Duration `yaml:"connMaxLifetime"`
+	// Retries is how often connecting is attempted before giving up
+	Retries       int           `yaml:"retries"`
+	RetryInterval time.Duration `yaml:"retryInterval"`
+}
+
+type CORSConfig struct {
+	// AllowedOrigins lists the origins that may call the API, e.g.
+	// "https://app.example.com" or "https://*.example.com", "*" allows all.
+	// Empty only allows same-origin requests.
+	AllowedOrigins []string `yaml:"allowedOrigins"`
+	// AllowedMethods limits the methods browsers may use, empty allows all
+	AllowedMethods   []string      `yaml:"allowedMethods"`
+	AllowCredentials bool          `yaml:"allowCredentials"`
+	MaxAge           time.Duration `yaml:"maxAge"`
+}
+
+type CacheConfig struct {
+	// ToggleTTL is how long toggle reads are cached, 0 disables the cache
+	ToggleTTL time.Duration `yaml:"toggleTTL"`
+	// StatsFlushInterval is how often read statistics are written to the store
+	StatsFlushInterval time.Duration `yaml:"statsFlushInterval"`
+}
+
+type RateLimitConfig struct {
+	// RequestsPerSecond limits the requests per client IP, 0 disables the limit
+	RequestsPerSecond float64 `yaml:"requestsPerSecond"`
+	Burst             int     `yaml:"burst"`
+	// GroupRequestsPerSecond limits the requests per group, 0 disables the limit
+	GroupRequestsPerSecond float64 `yaml:"groupRequestsPerSecond"`
+	GroupBurst             int     `yaml:"groupBurst"`
+	// GroupCreationsPerHour limits the new groups per client IP, 0 disables the limit
+	GroupCreationsPerHour float64       `yaml:"groupCreationsPerHour"`
+	GroupCreationBurst    int           `yaml:"groupCreationBurst"`
+	Lockout               LockoutConfig `yaml:"lockout"`
+}
+
+// LockoutConfig locks a group after repeated invalid secrets
+type LockoutConfig struct {
+	// Failures is the number of invalid secrets in a row before locking, 0 disables the lockout
+	Failures    int           `yaml:"failures"`
+	Duration    time.Duration `yaml:"duration"`
+	MaxDuration time.Duration `yaml:"maxDuration"`
+}
+
+// ShutdownConfig controls the graceful shutdown on SIGTERM and SIGINT
+type ShutdownConfig struct {
+	// Delay keeps serving while /readyz reports not-ready, so load balancers
+	// can take the instance out of rotation
+	Delay time.Duration `yaml:"delay"`
+	// DrainTimeout is how long in-flight requests may take to finish
+	DrainTimeout time.Duration `yaml:"drainTimeout"`
+}
+
+// TracingConfig exports OpenTelemetry spans over OTLP/HTTP
+type TracingConfig struct {
+	// Endpoint is the OTLP/HTTP traces URL, e.g. "http://collector:4318/v1/traces",
+	// empty disables the export
+	Endpoint    string `yaml:"endpoint"`
+	ServiceName string `yaml:"serviceName"`
+	// SampleRatio is the share of new traces that are recorded, traces
+	// continued from a sampled traceparent are always recorded
+	SampleRatio float64 `yaml:"sampleRatio"`
+}
+
+// SecretsConfig controls how long a rotated secret stays valid
+type SecretsConfig struct {
+	// RotationOverlap is how long the previous secret stays valid when a
+	// rotation does not ask for an overlap
+	RotationOverlap time.Duration `yaml:"rotationOverlap"`
+	// MaxRotationOverlap is the longest overlap a rotation may ask for
+	MaxRotationOverlap time.Duration `yaml:"maxRotationOverlap"`
+}
+
+// AdminConfig enables the admin API
+type AdminConfig struct {
+	// Token is the master credential of the admin API, empty disables the admin API
+	Token string `yaml:"token"`
+}
+
+// AdmissionConfig decides who may create new groups
+type AdmissionConfig struct {
+	// Policy is "open", "invite", "admin" or "quota"
+	Policy string `yaml:"policy"`
+	// GroupsPerIP is how many groups a client IP may have created under the quota policy
+	GroupsPerIP int `yaml:"groupsPerIP"`
+}
+
+// GRPCConfig enables the gRPC API, it uses the TLS configuration of the HTTP API
+type GRPCConfig struct {
+	// Listen is the address the gRPC API listens on, empty disables the gRPC API
+	Listen string `yaml:"listen"`
+	// Reflection lets tools like grpcurl discover the API
+	Reflection bool `yaml:"reflection"`
+	// WatchInterval is how often watched groups are polled for changes
+	WatchInterval time.Duration `yaml:"watchInterval"`
+}
+
+// UIConfig controls the web admin UI
+type UIConfig struct {
+	// Enabled serves the web admin UI at /ui
+	Enabled bool `yaml:"enabled"`
+}
+
+// OpenAPIConfig checks requests and responses against the OpenAPI document
+type OpenAPIConfig struct {
+	// ValidateRequests answers requests that do not match the document with 400
+	ValidateRequests bool `yaml:"validateRequests"`
+	// ValidateResponses logs responses that do not match the document
+	ValidateResponses bool `yaml:"validateResponses"`
+}
+
+// Default returns the configuration used when nothing is configured
+func Default() Config {
+	return Config{
+		Listen: ":8080",
+		Log: LogConfig{
+			Level:  "info",
+			Format: "json",
+		},
+		Database: DatabaseConfig{
+			Retries:       10,
+			RetryInterval: 5 * time.Second,
+		},
+		Cache: CacheConfig{
+			StatsFlushInterval: 10 * time.Second,
+		},
+		RateLimit: RateLimitConfig{
+			Burst:                 20,
+			GroupBurst:            20,
+			GroupCreationsPerHour: 60,
+			GroupCreationBurst:    10,
+			Lockout: LockoutConfig{
+				Failures:    5,
+				Duration:    time.Minute,
+				MaxDuration: time.Hour,
+			},
+		},
+		Shutdown: ShutdownConfig{
+			DrainTimeout: 30 * time.Second,
+		},
+		Tracing: TracingConfig{
+			ServiceName: "yaft",
+			SampleRatio: 1,
+		},
+		Secrets: SecretsConfig{
+			RotationOverlap:    24 * time.Hour,
+			MaxRotationOverlap: 30 * 24 * time.Hour,
+		},
+		Admission: AdmissionConfig{
+			Policy:      "open",
+			GroupsPerIP: 10,
+		},
+		GRPC: GRPCConfig{
+			WatchInterval: time.Second,
+		},
+		UI: UIConfig{
+			Enabled: true,
+		},
+	}
+}
+
+// setting is a configuration value that can be set by an environment variable and a flag
+type setting struct {
+	flag  string
+	env   string
+	usage string
+	set   func(c *Config, value string) error
+}
+
+func setString(target func(c *Config) *string) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		*target(c) = value
+		return nil
+	}
+}
+
+func setInt(target func(c *Config) *int) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		*target(c) = parsed
+		return nil
+	}
+}
+
+func setFloat(target func(c *Config) *float64) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		*target(c) = parsed
+		return nil
+	}
+}
+
+func setDuration(target func(c *Config) *time.Duration) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		*target(c) = parsed
+		return nil
+	}
+}
+
+// setBool accepts the values of strconv.ParseBool, e.g. true, false, 1 or 0
+func setBool(target func(c *Config) *bool) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		*target(c) = parsed
+		return nil
+	}
+}
+
+// setList splits a comma separated list
+func setList(target func(c *Config) *[]string) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		list := []string{}
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		*target(c) = list
+		return nil
+	}
+}
+
+var settings = []setting{
+	{"listen", "YAFT_LISTEN", "listen address", setString(func(c *Config) *string { return &c.Listen })},
+	{"trusted-proxies", "YAFT_TRUSTED_PROXIES", "comma separated IPs or CIDR ranges of proxies trusted to forward the client IP", setList(func(c *Config) *[]string { return &c.TrustedProxies })},
+	{"tls-cert-file", "YAFT_TLS_CERT_FILE", "TLS certificate file", setString(func(c *Config) *string { return &c.TLS.CertFile })},
+	{"tls-key-file", "YAFT_TLS_KEY_FILE", "TLS key file", setString(func(c *Config) *string { return &c.TLS.KeyFile })},
+	{"log-level", "YAFT_LOG_LEVEL", "log level (trace, debug, info, warn, error)", setString(func(c *Config) *string { return &c.Log.Level })},
+	{"log-format", "YAFT_LOG_FORMAT", "log format (json, text)", setString(func(c *Config) *string { return &c.Log.Format })},
+	{"db-dsn", "YAFT_DB_DSN", "store DSN (postgres://..., sqlite:<path>, memory:)", setString(func(c *Config) *string { return &c.Database.DSN })},
+	{"db-max-open-conns", "YAFT_DB_MAX_OPEN_CONNS", "maximum open database connections, 0 is unlimited", setInt(func(c *Config) *int { return &c.Database.MaxOpenConns })},
+	{"db-max-idle-conns", "YAFT_DB_MAX_IDLE_CONNS", "maximum idle database connections", setInt(func(c *Config) *int { return &c.Database.MaxIdleConns })},
+	{"db-conn-max-lifetime", "YAFT_DB_CONN_MAX_LIFETIME", "maximum lifetime of a database connection, 0 is unlimited", setDuration(func(c *Config) *time.Duration { return &c.Database.ConnMaxLifetime })},
+	{"db-retries", "YAFT_DB_RETRIES", "database connection attempts on startup", setInt(func(c *Config) *int { return &c.Database.Retries })},
+	{"db-retry-interval", "YAFT_DB_RETRY_INTERVAL", "wait between database connection attempts", setDuration(func(c *Config) *time.Duration { return &c.Database.RetryInterval })},
+	{"cors-allowed-origins", "YAFT_CORS_ALLOWED_ORIGINS", "comma separated origins allowed to call the API, * allows all, empty allows same-origin only", setList(func(c *Config) *[]string { return &c.CORS.AllowedOrigins })},
+	{"cors-allowed-methods", "YAFT_CORS_ALLOWED_METHODS", "comma separated methods browsers may use, empty allows all", setList(func(c *Config) *[]string { return &c.CORS.AllowedMethods })},
+	{"cors-allow-credentials", "YAFT_CORS_ALLOW_CREDENTIALS", "allow browsers to send credentials", setBool(func(c *Config) *bool { return &c.CORS.AllowCredentials })},
+	{"cors-max-age", "YAFT_CORS_MAX_AGE", "how long browsers may cache preflight responses", setDuration(func(c *Config) *time.Duration { return &c.CORS.MaxAge })},
+	{"cache-toggle-ttl", "YAFT_CACHE_TOGGLE_TTL", "how long toggle reads are cached, 0 disables the cache", setDuration(func(c *Config) *time.Duration { return &c.Cache.ToggleTTL })},
+	{"cache-stats-flush-interval", "YAFT_CACHE_STATS_FLUSH_INTERVAL", "how often read statistics are written", setDuration(func(c *Config) *time.Duration { return &c.Cache.StatsFlushInterval })},
+	{"rate-limit-requests-per-second", "YAFT_RATE_LIMIT_REQUESTS_PER_SECOND", "requests per second per client IP, 0 disables the limit", setFloat(func(c *Config) *float64 { return &c.RateLimit.RequestsPerSecond })},
+	{"rate-limit-burst", "YAFT_RATE_LIMIT_BURST", "requests a client IP may send at once", setInt(func(c *Config) *int { return &c.RateLimit.Burst })},
+	{"rate-limit-group-requests-per-second", "YAFT_RATE_LIMIT_GROUP_REQUESTS_PER_SECOND", "requests per second per group, 0 disables the limit", setFloat(func(c *Config) *float64 { return &c.RateLimit.GroupRequestsPerSecond })},
+	{"rate-limit-group-burst", "YAFT_RATE_LIMIT_GROUP_BURST", "requests a group may receive at once", setInt(func(c *Config) *int { return &c.RateLimit.GroupBurst })},
+	{"rate-limit-group-creations-per-hour", "YAFT_RATE_LIMIT_GROUP_CREATIONS_PER_HOUR", "new groups per hour per client IP, 0 disables the limit", setFloat(func(c *Config) *float64 { return &c.RateLimit.GroupCreationsPerHour })},
+	{"rate-limit-group-creation-burst", "YAFT_RATE_LIMIT_GROUP_CREATION_BURST", "new groups a client IP may create at once", setInt(func(c *Config) *int { return &c.RateLimit.GroupCreationBurst })},
+	{"lockout-failures", "YAFT_LOCKOUT_FAILURES", "invalid secrets in a row before a group is locked, 0 disables the lockout", setInt(func(c *Config) *int { return &c.RateLimit.Lockout.Failures })},
+	{"lockout-duration", "YAFT_LOCKOUT_DURATION", "first lockout, doubled with every further invalid secret", setDuration(func(c *Config) *time.Duration { return &c.RateLimit.Lockout.Duration })},
+	{"lockout-max-duration", "YAFT_LOCKOUT_MAX_DURATION", "longest lockout", setDuration(func(c *Config) *time.Duration { return &c.RateLimit.Lockout.MaxDuration })},
+	{"shutdown-delay", "YAFT_SHUTDOWN_DELAY", "how long to keep serving as not-ready after a shutdown signal", setDuration(func(c *Config) *time.Duration { return &c.Shutdown.Delay })},
+	{"shutdown-drain-timeout", "YAFT_SHUTDOWN_DRAIN_TIMEOUT", "how long in-flight requests may take to finish on shutdown", setDuration(func(c *Config) *time.Duration { return &c.Shutdown.DrainTimeout })},
+	{"tracing-endpoint", "YAFT_TRACING_ENDPOINT", "OTLP/HTTP traces URL, empty disables the export", setString(func(c *Config) *string { return &c.Tracing.Endpoint })},
+	{"tracing-service-name", "YAFT_TRACING_SERVICE_NAME", "service name reported with the spans", setString(func(c *Config) *string { return &c.Tracing.ServiceName })},
+	{"tracing-sample-ratio", "YAFT_TRACING_SAMPLE_RATIO", "share of new traces that are recorded", setFloat(func(c *Config) *float64 { return &c.Tracing.SampleRatio })},
+	{"secret-rotation-overlap", "YAFT_SECRET_ROTATION_OVERLAP", "how long the previous secret stays valid after a rotation", setDuration(func(c *Config) *time.Duration { return &c.Secrets.RotationOverlap })},
+	{"secret-max-rotation-overlap", "YAFT_SECRET_MAX_ROTATION_OVERLAP", "the longest overlap a rotation may ask for", setDuration(func(c *Config) *time.Duration { return &c.Secrets.MaxRotationOverlap })},
+	{"admin-token", "YAFT_ADMIN_TOKEN", "master credential enabling the admin API", setString(func(c *Config) *string { return &c.Admin.Token })},
+	{"admission-policy", "YAFT_ADMISSION_POLICY", "who may create groups: open, invite, admin or quota", setString(func(c *Config) *string { return &c.Admission.Policy })},
+	{"admission-groups-per-ip", "YAFT_ADMISSION_GROUPS_PER_IP", "how many groups a client IP may have created under the quota policy", setInt(func(c *Config) *int { return &c.Admission.GroupsPerIP })},
+	{"grpc-listen", "YAFT_GRPC_LISTEN", "gRPC listen address, empty disables the gRPC API", setString(func(c *Config) *string { return &c.GRPC.Listen })},
+	{"grpc-reflection", "YAFT_GRPC_REFLECTION", "register the gRPC reflection service", setBool(func(c *Config) *bool { return &c.GRPC.Reflection })},
+	{"grpc-watch-interval", "YAFT_GRPC_WATCH_INTERVAL", "how often watched groups are polled for changes", setDuration(func(c *Config) *time.Duration { return &c.GRPC.WatchInterval })},
+	{"ui-enabled", "YAFT_UI_ENABLED", "serve the web admin UI at /ui", setBool(func(c *Config) *bool { return &c.UI.Enabled })},
+	{"openapi-validate-requests", "YAFT_OPENAPI_VALIDATE_REQUESTS", "answer requests that do not match the OpenAPI document with 400", setBool(func(c *Config) *bool { return &c.OpenAPI.ValidateRequests })},
+	{"openapi-validate-responses", "YAFT_OPENAPI_VALIDATE_RESPONSES", "log responses that do not match the OpenAPI document", setBool(func(c *Config) *bool { return &c.OpenAPI.ValidateResponses })},
+}
+
+// legacy environment variables, they are overridden by their YAFT_ counterparts
+var legacySettings = []setting{
+	{"", "DB_DSN", "", setString(func(c *Config) *string { return &c.Database.DSN })},
+	{"", "PORT", "", func(c *Config, value string) error {
+		c.Listen = ":" + value
+		return nil
+	}},
+}
+
+// Load builds the configuration from the defaults, the YAML file given by
+// --config or YAFT_CONFIG, the environment and the flags in args. It returns
+// whether --print-config was requested. The result is not validated.
+func Load(args []string, getenv func(string) string) (Config, bool, error) {
+	config := Default()
+
+	fs := flag.NewFlagSet("yaft", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	file := fs.String("config", getenv("YAFT_CONFIG"), "YAML configuration file")
+	printConfig := fs.Bool("print-config", false, "print the effective configuration and exit")
+	values := make(map[string]*string, len(settings))
+	for _, s := range settings {
+		values[s.flag] = fs.String(s.flag, "", s.usage)
+	}
+	if err := fs.Parse(args); err != nil {
+		return config, false, err
+	}
+
+	if *file != "" {
+		if err := loadFile(&config, *file); err != nil {
+			return config, false, err
+		}
+	}
+
+	for _, s := range append(legacySettings, settings...) {
+		if value := getenv(s.env); value != "" {
+			if err := s.set(&config, value); err != nil {
+				return config, false, fmt.Errorf("invalid value for %s: %w", s.env, err)
+			}
+		}
+	}
+
+	var err error
+	fs.Visit(func(f *flag.Flag) {
+		for _, s := range settings {
+			if err == nil && s.flag == f.Name {
+				if setErr := s.set(&config, *values[s.flag]); setErr != nil {
+					err = fmt.Errorf("invalid value for --%s: %w", s.flag, setErr)
+				}
+			}
+		}
+	})
+
+	return config, *printConfig, err
+}
+
+func loadFile(config *Config, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(config); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// Usage writes the documentation of all flags and environment variables
+func Usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage of yaft:")
+	fmt.Fprintln(w, "  --config (YAFT_CONFIG)\n    \tYAML configuration file")
+	fmt.Fprintln(w, "  --print-config\n    \tprint the effective configuration and exit")
+	for _, s := range settings {
+		fmt.Fprintf(w, "  --%s (%s)\n    \t%s\n", s.flag, s.env, s.usage)
+	}
+}
+
+// Validate reports every invalid value at once
+func (c Config) Validate() error {
+	var errs []error
+	if c.Listen == "" {
+		errs = append(errs, errors.New("listen address is required"))
+	}
+	if (c.TLS.CertFile == "") != (c.TLS.KeyFile == "") {
+		errs = append(errs, errors.New("tls requires both certFile and keyFile"))
+	}
+	if _, err := logrus.ParseLevel(c.Log.Level); err != nil {
+		errs = append(errs, fmt.Errorf("invalid log level %q", c.Log.Level))
+	}
+	if c.Log.Format != "json" && c.Log.Format != "text" {
+		errs = append(errs, fmt.Errorf("invalid log format %q, expected json or text", c.Log.Format))
+	}
+	if c.Database.DSN == "" {
+		errs = append(errs, errors.New("database dsn is required"))
+	}
+	if c.Database.MaxOpenConns < 0 || c.Database.MaxIdleConns < 0 || c.Database.ConnMaxLifetime < 0 {
+		errs = append(errs, errors.New("database pool settings must not be negative"))
+	}
+	if c.Database.Retries < 1 {
+		errs = append(errs, errors.New("database retries must be at least 1"))
+	}
+	if c.Database.RetryInterval < 0 {
+		errs = append(errs, errors.New("database retry interval must not be negative"))
+	}
+	for _, proxy := range c.TrustedProxies {
+		if net.ParseIP(proxy) == nil {
+			if _, _, err := net.ParseCIDR(proxy); err != nil {
+				errs = append(errs, fmt.Errorf("invalid trusted proxy %q, expected an IP or CIDR range", proxy))
+			}
+		}
+	}
+	for _, origin := range c.CORS.AllowedOrigins {
+		if origin != "*" && !isOrigin(origin) {
+			errs = append(errs, fmt.Errorf("invalid CORS origin %q", origin))
+		}
+	}
+	if c.CORS.AllowCredentials && slices.Contains(c.CORS.AllowedOrigins, "*") {
+		errs = append(errs, errors.New("CORS credentials cannot be allowed for every origin, list the origins instead of *"))
+	}
+	for _, method := range c.CORS.AllowedMethods {
+		if method == "" || strings.ToUpper(method) != method {
+			errs = append(errs, fmt.Errorf("invalid CORS method %q, expected upper case", method))
+		}
+	}
+	if c.CORS.MaxAge < 0 {
+		errs = append(errs, errors.New("CORS max age must not be negative"))
+	}
+	if c.Cache.ToggleTTL < 0 {
+		errs = append(errs, errors.New("cache toggle TTL must not be negative"))
+	}
+	if c.Cache.StatsFlushInterval <= 0 {
+		errs = append(errs, errors.New("cache stats flush interval must be positive"))
+	}
+	if c.RateLimit.RequestsPerSecond < 0 || c.RateLimit.GroupRequestsPerSecond < 0 || c.RateLimit.GroupCreationsPerHour < 0 {
+		errs = append(errs, errors.New("rate limits must not be negative"))
+	}
+	if (c.RateLimit.RequestsPerSecond > 0 && c.RateLimit.Burst < 1) ||
+		(c.RateLimit.GroupRequestsPerSecond > 0 && c.RateLimit.GroupBurst < 1) ||
+		(c.RateLimit.GroupCreationsPerHour > 0 && c.RateLimit.GroupCreationBurst < 1) {
+		errs = append(errs, errors.New("rate limit bursts must be at least 1"))
+	}
+	if c.RateLimit.Lockout.Failures < 0 {
+		errs = append(errs, errors.New("lockout failures must not be negative"))
+	}
+	if c.RateLimit.Lockout.Failures > 0 && (c.RateLimit.Lockout.Duration <= 0 || c.RateLimit.Lockout.MaxDuration < c.RateLimit.Lockout.Duration) {
+		errs = append(errs, errors.New("lockout duration must be positive and not exceed the max duration"))
+	}
+	if c.Shutdown.Delay < 0 || c.Shutdown.DrainTimeout < 0 {
+		errs = append(errs, errors.New("shutdown delay and drain timeout must not be negative"))
+	}
+	if c.Tracing.Endpoint != "" {
+		parsed, err := url.Parse(c.Tracing.Endpoint)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			errs = append(errs, fmt.Errorf("invalid tracing endpoint %q, expected an http or https URL", c.Tracing.Endpoint))
+		}
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		errs = append(errs, errors.New("tracing sample ratio must be between 0 and 1"))
+	}
+	if c.Secrets.RotationOverlap < 0 || c.Secrets.MaxRotationOverlap < c.Secrets.RotationOverlap {
+		errs = append(errs, errors.New("secret rotation overlap must not be negative and not exceed the max overlap"))
+	}
+	if c.Admin.Token != "" && len(c.Admin.Token) < 32 {
+		errs = append(errs, errors.New("admin token must have at least 32 characters"))
+	}
+	switch c.Admission.Policy {
+	case "open":
+	case "invite", "admin":
+		if c.Admin.Token == "" {
+			errs = append(errs, fmt.Errorf("admission policy %q requires an admin token", c.Admission.Policy))
+		}
+	case "quota":
+		if c.Admission.GroupsPerIP < 1 {
+			errs = append(errs, errors.New("admission groups per IP must be at least 1"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("unknown admission policy %q, expected open, invite, admin or quota", c.Admission.Policy))
+	}
+	if c.GRPC.Listen != "" && c.GRPC.Listen == c.Listen {
+		errs = append(errs, errors.New("grpc listen address must differ from the listen address"))
+	}
+	if c.GRPC.WatchInterval <= 0 {
+		errs = append(errs, errors.New("grpc watch interval must be positive"))
+	}
+	return errors.Join(errs...)
+}
+
+// isOrigin checks for a scheme and host without path, the host may start with a "*." wildcard
+func isOrigin(origin string) bool {
+	parsed, err := url.Parse(strings.Replace(origin, "://*.", "://", 1))
+	return err == nil && parsed.Scheme != "" && parsed.Host != "" && parsed.Path == "" && parsed.RawQuery == "" && !strings.Contains(parsed.Host, "*")
+}
+
+// Print writes the configuration as YAML, with the database password redacted
+func (c Config) Print(w io.Writer) error {
+	c.Database.DSN = redact(c.Database.DSN)
+	if c.Admin.Token != "" {
+		c.Admin.Token = "REDACTED"
+	}
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(c); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// passwordSetting matches the password of a key/value DSN like
+// "host=db password=secret", quoted or not, and of a DSN query parameter
+var passwordSetting = regexp.MustCompile(`(?i)(\bpassword\s*=\s*)('(?:[^'\\]|\\.)*'|[^\s&]*)`)
+
+// redact masks the password of URL and key/value DSNs
+func redact(dsn string) string {
+	dsn = passwordSetting.ReplaceAllString(dsn, "${1}REDACTED")
+	parsed, err := url.Parse(dsn)
+	if err != nil || parsed.User == nil {
+		return dsn
+	}
+	if _, ok := parsed.User.Password(); ok {
+		parsed.User = url.UserPassword(parsed.User.Username(), "REDACTED")
+	}
+	return parsed.String()
+}
@@ -0,0 +1,241 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func env(values map[string]string) func(string) string {
+	return func(name string) string {
+		return values[name]
+	}
+}
+
+func writeFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "yaft.yml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	file := writeFile(t, `
+listen: ":7000"
+log:
+  level: warn
+database:
+  dsn: postgres://file
+  retryInterval: 2s
+cors:
+  allowedOrigins: ["https://file.example.com"]
+`)
+
+	tests := []struct {
+		name  string
+		args  []string
+		env   map[string]string
+		check func(t *testing.T, c Config)
+	}{
+		{
+			name: "defaults",
+			check: func(t *testing.T, c Config) {
+				assert.Equal(t, Default(), c)
+			},
+		},
+		{
+			name: "file",
+			args: []string{"--config", file},
+			check: func(t *testing.T, c Config) {
+				assert.Equal(t, ":7000", c.Listen)
+				assert.Equal(t, "warn", c.Log.Level)
+				assert.Equal(t, "json", c.Log.Format)
+				assert.Equal(t, "postgres://file", c.Database.DSN)
+				assert.Equal(t, 2*time.Second, c.Database.RetryInterval)
+				assert.Equal(t, 10, c.Database.Retries)
+				assert.Equal(t, []string{"https://file.example.com"}, c.CORS.AllowedOrigins)
+			},
+		},
+		{
+			name: "environment overrides file",
+			env:  map[string]string{"YAFT_CONFIG": file, "YAFT_LOG_LEVEL": "error", "YAFT_CORS_ALLOWED_ORIGINS": "https://a.example.com, https://b.example.com"},
+			check: func(t *testing.T, c Config) {
+				assert.Equal(t, ":7000", c.Listen)
+				assert.Equal(t, "error", c.Log.Level)
+				assert.Equal(t, []string{"https://a.example.com", "https://b.example.com"}, c.CORS.AllowedOrigins)
+			},
+		},
+		{
+			name: "flags override environment",
+			args: []string{"--config", file, "--log-level", "debug", "--db-retries=3"},
+			env:  map[string]string{"YAFT_LOG_LEVEL": "error"},
+			check: func(t *testing.T, c Config) {
+				assert.Equal(t, "debug", c.Log.Level)
+				assert.Equal(t, 3, c.Database.Retries)
+			},
+		},
+		{
+			name: "legacy environment",
+			env:  map[string]string{"DB_DSN": "memory:", "PORT": "9090"},
+			check: func(t *testing.T, c Config) {
+				assert.Equal(t, "memory:", c.Database.DSN)
+				assert.Equal(t, ":9090", c.Listen)
+			},
+		},
+		{
+			name: "prefixed environment overrides legacy",
+			env:  map[string]string{"DB_DSN": "memory:", "YAFT_DB_DSN": "sqlite:yaft.db", "PORT": "9090", "YAFT_LISTEN": "127.0.0.1:8000"},
+			check: func(t *testing.T, c Config) {
+				assert.Equal(t, "sqlite:yaft.db", c.Database.DSN)
+				assert.Equal(t, "127.0.0.1:8000", c.Listen)
+			},
+		},
+		{
+			name: "disabled ui",
+			env:  map[string]string{"YAFT_UI_ENABLED": "false"},
+			check: func(t *testing.T, c Config) {
+				assert.False(t, c.UI.Enabled)
+			},
+		},
+		{
+			name: "openapi validation",
+			args: []string{"--openapi-validate-requests=true"},
+			env:  map[string]string{"YAFT_OPENAPI_VALIDATE_RESPONSES": "true"},
+			check: func(t *testing.T, c Config) {
+				assert.True(t, c.OpenAPI.ValidateRequests)
+				assert.True(t, c.OpenAPI.ValidateResponses)
+			},
+		},
+		{
+			name: "trusted proxies",
+			env:  map[string]string{"YAFT_TRUSTED_PROXIES": "10.0.0.1,192.168.0.0/16"},
+			check: func(t *testing.T, c Config) {
+				assert.Equal(t, []string{"10.0.0.1", "192.168.0.0/16"}, c.TrustedProxies)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, printConfig, err := Load(tt.args, env(tt.env))
+			require.NoError(t, err)
+			assert.False(t, printConfig)
+			tt.check(t, c)
+		})
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		env  map[string]string
+	}{
+		{"unknown flag", []string{"--port", "80"}, nil},
+		{"invalid flag value", []string{"--db-retries", "many"}, nil},
+		{"invalid environment value", nil, map[string]string{"YAFT_CACHE_TOGGLE_TTL": "soon"}},
+		{"missing file", []string{"--config", "missing.yml"}, nil},
+		{"unknown file field", []string{"--config", writeFile(t, "port: 80\n")}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := Load(tt.args, env(tt.env))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := Default()
+	valid.Database.DSN = "memory:"
+	valid.CORS.AllowedOrigins = []string{"https://app.example.com", "https://*.example.org"}
+	valid.CORS.AllowedMethods = []string{"GET"}
+	require.NoError(t, valid.Validate())
+
+	tests := []struct {
+		name   string
+		modify func(c *Config)
+	}{
+		{"missing dsn", func(c *Config) { c.Database.DSN = "" }},
+		{"missing listen address", func(c *Config) { c.Listen = "" }},
+		{"tls without key", func(c *Config) { c.TLS.CertFile = "cert.pem" }},
+		{"unknown log level", func(c *Config) { c.Log.Level = "loud" }},
+		{"unknown log format", func(c *Config) { c.Log.Format = "xml" }},
+		{"negative pool size", func(c *Config) { c.Database.MaxOpenConns = -1 }},
+		{"no retries", func(c *Config) { c.Database.Retries = 0 }},
+		{"invalid origin", func(c *Config) { c.CORS.AllowedOrigins = []string{"example.com"} }},
+		{"origin with path", func(c *Config) { c.CORS.AllowedOrigins = []string{"https://example.com/app"} }},
+		{"inner wildcard origin", func(c *Config) { c.CORS.AllowedOrigins = []string{"https://a.*.example.com"} }},
+		{"invalid trusted proxy", func(c *Config) { c.TrustedProxies = []string{"proxy.example.com"} }},
+		{"credentials for every origin", func(c *Config) {
+			c.CORS = CORSConfig{AllowedOrigins: []string{"*"}, AllowCredentials: true}
+		}},
+		{"lower case method", func(c *Config) { c.CORS.AllowedMethods = []string{"get"} }},
+		{"negative cache ttl", func(c *Config) { c.Cache.ToggleTTL = -time.Second }},
+		{"rate limit without burst", func(c *Config) { c.RateLimit = RateLimitConfig{RequestsPerSecond: 1} }},
+		{"group creation limit without burst", func(c *Config) { c.RateLimit.GroupCreationBurst = 0 }},
+		{"lockout without duration", func(c *Config) { c.RateLimit.Lockout.Duration = 0 }},
+		{"lockout longer than max", func(c *Config) { c.RateLimit.Lockout.Duration = 2 * time.Hour }},
+		{"negative drain timeout", func(c *Config) { c.Shutdown.DrainTimeout = -time.Second }},
+		{"tracing endpoint without scheme", func(c *Config) { c.Tracing.Endpoint = "collector:4318" }},
+		{"tracing sample ratio above 1", func(c *Config) { c.Tracing.SampleRatio = 2 }},
+		{"short admin token", func(c *Config) { c.Admin.Token = "admin" }},
+		{"unknown admission policy", func(c *Config) { c.Admission.Policy = "closed" }},
+		{"invite policy without admin token", func(c *Config) { c.Admission.Policy = "invite" }},
+		{"quota policy without quota", func(c *Config) { c.Admission = AdmissionConfig{Policy: "quota"} }},
+		{"rotation overlap longer than max", func(c *Config) { c.Secrets.RotationOverlap = 31 * 24 * time.Hour }},
+		{"grpc on the http address", func(c *Config) { c.GRPC.Listen = c.Listen }},
+		{"no grpc watch interval", func(c *Config) { c.GRPC.WatchInterval = 0 }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := valid
+			tt.modify(&c)
+			assert.Error(t, c.Validate())
+		})
+	}
+}
+
+func TestPrint(t *testing.T) {
+	c, printConfig, err := Load([]string{"--print-config", "--db-dsn", "postgres://yaft:hunter2@db:5432/yaft"}, env(nil))
+	require.NoError(t, err)
+	assert.True(t, printConfig)
+
+	var out bytes.Buffer
+	admin := c
+	admin.Admin.Token = "master-token-master-token-master-token"
+	require.NoError(t, admin.Print(&out))
+	assert.NotContains(t, out.String(), "master-token")
+	assert.Contains(t, out.String(), "token: REDACTED")
+
+	out.Reset()
+	require.NoError(t, c.Print(&out))
+	assert.NotContains(t, out.String(), "hunter2")
+	assert.Contains(t, out.String(), "postgres://yaft:REDACTED@db:5432/yaft")
+	assert.Contains(t, out.String(), "retryInterval: 5s")
+
+	tests := []struct {
+		dsn      string
+		expected string
+	}{
+		{"host=db user=yaft password=hunter2 dbname=yaft", "host=db user=yaft password=REDACTED dbname=yaft"},
+		{"host=db password = 'it\\'s hunter2' sslmode=disable", "host=db password = REDACTED sslmode=disable"},
+		{"postgres://db/yaft?user=yaft&password=hunter2", "postgres://db/yaft?user=yaft&password=REDACTED"},
+		{"sqlite:yaft.db", "sqlite:yaft.db"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, redact(tt.dsn))
+	}
+
+	// The printed configuration can be loaded again
+	path := writeFile(t, out.String())
+	loaded, _, err := Load([]string{"--config", path}, env(nil))
+	require.NoError(t, err)
+	assert.Equal(t, Default().Database.RetryInterval, loaded.Database.RetryInterval)
+}
@@ -5,14 +5,14 @@ CREATE TABLE "feature_toggles" ("id" bigserial,"key" text NOT NULL,"value" text
 SELECT cron.schedule('* * * * *', $$
     UPDATE feature_toggles
     SET value = CASE
-        WHEN active_at <= CURRENT_DATE THEN 'true'
+        WHEN active_at <= now() THEN 'true'
         ELSE value
     END;
 $$);
 SELECT cron.schedule('* * * * *', $$
     UPDATE feature_toggles
     SET value = CASE
-        WHEN disabled_at <= CURRENT_DATE THEN 'false'
+        WHEN disabled_at <= now() THEN 'false'
         ELSE value
     END;
 $$);
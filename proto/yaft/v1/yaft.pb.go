@@ -0,0 +1,1187 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: yaft/v1/yaft.proto
+
+package yaftv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type WatchResponse_Type int32
+
+const (
+	WatchResponse_TYPE_UNSPECIFIED WatchResponse_Type = 0
+	// TYPE_CHANGED is sent for new and changed toggles and for every toggle
+	// when the watch starts.
+	WatchResponse_TYPE_CHANGED WatchResponse_Type = 1
+	// TYPE_DELETED is sent for removed toggles, only the key is set.
+	WatchResponse_TYPE_DELETED WatchResponse_Type = 2
+)
+
+// Enum value maps for WatchResponse_Type.
+var (
+	WatchResponse_Type_name = map[int32]string{
+		0: "TYPE_UNSPECIFIED",
+		1: "TYPE_CHANGED",
+		2: "TYPE_DELETED",
+	}
+	WatchResponse_Type_value = map[string]int32{
+		"TYPE_UNSPECIFIED": 0,
+		"TYPE_CHANGED":     1,
+		"TYPE_DELETED":     2,
+	}
+)
+
+func (x WatchResponse_Type) Enum() *WatchResponse_Type {
+	p := new(WatchResponse_Type)
+	*p = x
+	return p
+}
+
+func (x WatchResponse_Type) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (WatchResponse_Type) Descriptor() protoreflect.EnumDescriptor {
+	return file_yaft_v1_yaft_proto_enumTypes[0].Descriptor()
+}
+
+func (WatchResponse_Type) Type() protoreflect.EnumType {
+	return &file_yaft_v1_yaft_proto_enumTypes[0]
+}
+
+func (x WatchResponse_Type) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use WatchResponse_Type.Descriptor instead.
+func (WatchResponse_Type) EnumDescriptor() ([]byte, []int) {
+	return file_yaft_v1_yaft_proto_rawDescGZIP(), []int{17, 0}
+}
+
+type Toggle struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// key is "<group UUID>|<name>".
+	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Value         bool                   `protobuf:"varint,2,opt,name=value,proto3" json:"value,omitempty"`
+	ActiveAt      *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=active_at,json=activeAt,proto3" json:"active_at,omitempty"`
+	DisabledAt    *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=disabled_at,json=disabledAt,proto3" json:"disabled_at,omitempty"`
+	RemoveAt      *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=remove_at,json=removeAt,proto3" json:"remove_at,omitempty"`
+	Tags          []string               `protobuf:"bytes,6,rep,name=tags,proto3" json:"tags,omitempty"`
+	Description   string                 `protobuf:"bytes,7,opt,name=description,proto3" json:"description,omitempty"`
+	Owner         string                 `protobuf:"bytes,8,opt,name=owner,proto3" json:"owner,omitempty"`
+	Kind          string                 `protobuf:"bytes,9,opt,name=kind,proto3" json:"kind,omitempty"`
+	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,10,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	UpdatedAt     *timestamppb.Timestamp `protobuf:"bytes,11,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Toggle) Reset() {
+	*x = Toggle{}
+	mi := &file_yaft_v1_yaft_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Toggle) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Toggle) ProtoMessage() {}
+
+func (x *Toggle) ProtoReflect() protoreflect.Message {
+	mi := &file_yaft_v1_yaft_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Toggle.ProtoReflect.Descriptor instead.
+func (*Toggle) Descriptor() ([]byte, []int) {
+	return file_yaft_v1_yaft_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Toggle) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *Toggle) GetValue() bool {
+	if x != nil {
+		return x.Value
+	}
+	return false
+}
+
+func (x *Toggle) GetActiveAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ActiveAt
+	}
+	return nil
+}
+
+func (x *Toggle) GetDisabledAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.DisabledAt
+	}
+	return nil
+}
+
+func (x *Toggle) GetRemoveAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.RemoveAt
+	}
+	return nil
+}
+
+func (x *Toggle) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *Toggle) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *Toggle) GetOwner() string {
+	if x != nil {
+		return x.Owner
+	}
+	return ""
+}
+
+func (x *Toggle) GetKind() string {
+	if x != nil {
+		return x.Kind
+	}
+	return ""
+}
+
+func (x *Toggle) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *Toggle) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+type GetToggleRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetToggleRequest) Reset() {
+	*x = GetToggleRequest{}
+	mi := &file_yaft_v1_yaft_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetToggleRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetToggleRequest) ProtoMessage() {}
+
+func (x *GetToggleRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_yaft_v1_yaft_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetToggleRequest.ProtoReflect.Descriptor instead.
+func (*GetToggleRequest) Descriptor() ([]byte, []int) {
+	return file_yaft_v1_yaft_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *GetToggleRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+type GetToggleResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Toggle        *Toggle                `protobuf:"bytes,1,opt,name=toggle,proto3" json:"toggle,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetToggleResponse) Reset() {
+	*x = GetToggleResponse{}
+	mi := &file_yaft_v1_yaft_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetToggleResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetToggleResponse) ProtoMessage() {}
+
+func (x *GetToggleResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_yaft_v1_yaft_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetToggleResponse.ProtoReflect.Descriptor instead.
+func (*GetToggleResponse) Descriptor() ([]byte, []int) {
+	return file_yaft_v1_yaft_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *GetToggleResponse) GetToggle() *Toggle {
+	if x != nil {
+		return x.Toggle
+	}
+	return nil
+}
+
+type ListGroupRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Group string                 `protobuf:"bytes,1,opt,name=group,proto3" json:"group,omitempty"`
+	// tags is a tag expression like the "tags" query parameter of the HTTP
+	// API, e.g. "beta,!legacy|sprint-12".
+	Tags          string `protobuf:"bytes,2,opt,name=tags,proto3" json:"tags,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListGroupRequest) Reset() {
+	*x = ListGroupRequest{}
+	mi := &file_yaft_v1_yaft_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListGroupRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListGroupRequest) ProtoMessage() {}
+
+func (x *ListGroupRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_yaft_v1_yaft_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListGroupRequest.ProtoReflect.Descriptor instead.
+func (*ListGroupRequest) Descriptor() ([]byte, []int) {
+	return file_yaft_v1_yaft_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ListGroupRequest) GetGroup() string {
+	if x != nil {
+		return x.Group
+	}
+	return ""
+}
+
+func (x *ListGroupRequest) GetTags() string {
+	if x != nil {
+		return x.Tags
+	}
+	return ""
+}
+
+type ListGroupResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Toggles       []*Toggle              `protobuf:"bytes,1,rep,name=toggles,proto3" json:"toggles,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListGroupResponse) Reset() {
+	*x = ListGroupResponse{}
+	mi := &file_yaft_v1_yaft_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListGroupResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListGroupResponse) ProtoMessage() {}
+
+func (x *ListGroupResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_yaft_v1_yaft_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListGroupResponse.ProtoReflect.Descriptor instead.
+func (*ListGroupResponse) Descriptor() ([]byte, []int) {
+	return file_yaft_v1_yaft_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ListGroupResponse) GetToggles() []*Toggle {
+	if x != nil {
+		return x.Toggles
+	}
+	return nil
+}
+
+type EvaluateRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Keys          []string               `protobuf:"bytes,1,rep,name=keys,proto3" json:"keys,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EvaluateRequest) Reset() {
+	*x = EvaluateRequest{}
+	mi := &file_yaft_v1_yaft_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EvaluateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EvaluateRequest) ProtoMessage() {}
+
+func (x *EvaluateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_yaft_v1_yaft_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EvaluateRequest.ProtoReflect.Descriptor instead.
+func (*EvaluateRequest) Descriptor() ([]byte, []int) {
+	return file_yaft_v1_yaft_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *EvaluateRequest) GetKeys() []string {
+	if x != nil {
+		return x.Keys
+	}
+	return nil
+}
+
+type Evaluation struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Key   string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Value bool                   `protobuf:"varint,2,opt,name=value,proto3" json:"value,omitempty"`
+	// found is false for unknown keys, their value is false.
+	Found         bool `protobuf:"varint,3,opt,name=found,proto3" json:"found,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Evaluation) Reset() {
+	*x = Evaluation{}
+	mi := &file_yaft_v1_yaft_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Evaluation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Evaluation) ProtoMessage() {}
+
+func (x *Evaluation) ProtoReflect() protoreflect.Message {
+	mi := &file_yaft_v1_yaft_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Evaluation.ProtoReflect.Descriptor instead.
+func (*Evaluation) Descriptor() ([]byte, []int) {
+	return file_yaft_v1_yaft_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *Evaluation) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *Evaluation) GetValue() bool {
+	if x != nil {
+		return x.Value
+	}
+	return false
+}
+
+func (x *Evaluation) GetFound() bool {
+	if x != nil {
+		return x.Found
+	}
+	return false
+}
+
+type EvaluateResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Evaluations   []*Evaluation          `protobuf:"bytes,1,rep,name=evaluations,proto3" json:"evaluations,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EvaluateResponse) Reset() {
+	*x = EvaluateResponse{}
+	mi := &file_yaft_v1_yaft_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EvaluateResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EvaluateResponse) ProtoMessage() {}
+
+func (x *EvaluateResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_yaft_v1_yaft_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EvaluateResponse.ProtoReflect.Descriptor instead.
+func (*EvaluateResponse) Descriptor() ([]byte, []int) {
+	return file_yaft_v1_yaft_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *EvaluateResponse) GetEvaluations() []*Evaluation {
+	if x != nil {
+		return x.Evaluations
+	}
+	return nil
+}
+
+type CreateToggleRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Toggle        *Toggle                `protobuf:"bytes,1,opt,name=toggle,proto3" json:"toggle,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateToggleRequest) Reset() {
+	*x = CreateToggleRequest{}
+	mi := &file_yaft_v1_yaft_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateToggleRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateToggleRequest) ProtoMessage() {}
+
+func (x *CreateToggleRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_yaft_v1_yaft_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateToggleRequest.ProtoReflect.Descriptor instead.
+func (*CreateToggleRequest) Descriptor() ([]byte, []int) {
+	return file_yaft_v1_yaft_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *CreateToggleRequest) GetToggle() *Toggle {
+	if x != nil {
+		return x.Toggle
+	}
+	return nil
+}
+
+type CreateToggleResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Toggle        *Toggle                `protobuf:"bytes,1,opt,name=toggle,proto3" json:"toggle,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateToggleResponse) Reset() {
+	*x = CreateToggleResponse{}
+	mi := &file_yaft_v1_yaft_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateToggleResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateToggleResponse) ProtoMessage() {}
+
+func (x *CreateToggleResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_yaft_v1_yaft_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateToggleResponse.ProtoReflect.Descriptor instead.
+func (*CreateToggleResponse) Descriptor() ([]byte, []int) {
+	return file_yaft_v1_yaft_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *CreateToggleResponse) GetToggle() *Toggle {
+	if x != nil {
+		return x.Toggle
+	}
+	return nil
+}
+
+type SetValueRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Value         bool                   `protobuf:"varint,2,opt,name=value,proto3" json:"value,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetValueRequest) Reset() {
+	*x = SetValueRequest{}
+	mi := &file_yaft_v1_yaft_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetValueRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetValueRequest) ProtoMessage() {}
+
+func (x *SetValueRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_yaft_v1_yaft_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetValueRequest.ProtoReflect.Descriptor instead.
+func (*SetValueRequest) Descriptor() ([]byte, []int) {
+	return file_yaft_v1_yaft_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *SetValueRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *SetValueRequest) GetValue() bool {
+	if x != nil {
+		return x.Value
+	}
+	return false
+}
+
+type SetValueResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Toggle        *Toggle                `protobuf:"bytes,1,opt,name=toggle,proto3" json:"toggle,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetValueResponse) Reset() {
+	*x = SetValueResponse{}
+	mi := &file_yaft_v1_yaft_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetValueResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetValueResponse) ProtoMessage() {}
+
+func (x *SetValueResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_yaft_v1_yaft_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetValueResponse.ProtoReflect.Descriptor instead.
+func (*SetValueResponse) Descriptor() ([]byte, []int) {
+	return file_yaft_v1_yaft_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *SetValueResponse) GetToggle() *Toggle {
+	if x != nil {
+		return x.Toggle
+	}
+	return nil
+}
+
+type SetScheduleRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	ActiveAt      *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=active_at,json=activeAt,proto3" json:"active_at,omitempty"`
+	DisabledAt    *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=disabled_at,json=disabledAt,proto3" json:"disabled_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetScheduleRequest) Reset() {
+	*x = SetScheduleRequest{}
+	mi := &file_yaft_v1_yaft_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetScheduleRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetScheduleRequest) ProtoMessage() {}
+
+func (x *SetScheduleRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_yaft_v1_yaft_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetScheduleRequest.ProtoReflect.Descriptor instead.
+func (*SetScheduleRequest) Descriptor() ([]byte, []int) {
+	return file_yaft_v1_yaft_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *SetScheduleRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *SetScheduleRequest) GetActiveAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ActiveAt
+	}
+	return nil
+}
+
+func (x *SetScheduleRequest) GetDisabledAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.DisabledAt
+	}
+	return nil
+}
+
+type SetScheduleResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Toggle        *Toggle                `protobuf:"bytes,1,opt,name=toggle,proto3" json:"toggle,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetScheduleResponse) Reset() {
+	*x = SetScheduleResponse{}
+	mi := &file_yaft_v1_yaft_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetScheduleResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetScheduleResponse) ProtoMessage() {}
+
+func (x *SetScheduleResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_yaft_v1_yaft_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetScheduleResponse.ProtoReflect.Descriptor instead.
+func (*SetScheduleResponse) Descriptor() ([]byte, []int) {
+	return file_yaft_v1_yaft_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *SetScheduleResponse) GetToggle() *Toggle {
+	if x != nil {
+		return x.Toggle
+	}
+	return nil
+}
+
+type DeleteToggleRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteToggleRequest) Reset() {
+	*x = DeleteToggleRequest{}
+	mi := &file_yaft_v1_yaft_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteToggleRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteToggleRequest) ProtoMessage() {}
+
+func (x *DeleteToggleRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_yaft_v1_yaft_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteToggleRequest.ProtoReflect.Descriptor instead.
+func (*DeleteToggleRequest) Descriptor() ([]byte, []int) {
+	return file_yaft_v1_yaft_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *DeleteToggleRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+type DeleteToggleResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteToggleResponse) Reset() {
+	*x = DeleteToggleResponse{}
+	mi := &file_yaft_v1_yaft_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteToggleResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteToggleResponse) ProtoMessage() {}
+
+func (x *DeleteToggleResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_yaft_v1_yaft_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteToggleResponse.ProtoReflect.Descriptor instead.
+func (*DeleteToggleResponse) Descriptor() ([]byte, []int) {
+	return file_yaft_v1_yaft_proto_rawDescGZIP(), []int{15}
+}
+
+type WatchRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Group string                 `protobuf:"bytes,1,opt,name=group,proto3" json:"group,omitempty"`
+	// tags is a tag expression selecting the watched toggles.
+	Tags          string `protobuf:"bytes,2,opt,name=tags,proto3" json:"tags,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchRequest) Reset() {
+	*x = WatchRequest{}
+	mi := &file_yaft_v1_yaft_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchRequest) ProtoMessage() {}
+
+func (x *WatchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_yaft_v1_yaft_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchRequest.ProtoReflect.Descriptor instead.
+func (*WatchRequest) Descriptor() ([]byte, []int) {
+	return file_yaft_v1_yaft_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *WatchRequest) GetGroup() string {
+	if x != nil {
+		return x.Group
+	}
+	return ""
+}
+
+func (x *WatchRequest) GetTags() string {
+	if x != nil {
+		return x.Tags
+	}
+	return ""
+}
+
+type WatchResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Type          WatchResponse_Type     `protobuf:"varint,1,opt,name=type,proto3,enum=yaft.v1.WatchResponse_Type" json:"type,omitempty"`
+	Toggle        *Toggle                `protobuf:"bytes,2,opt,name=toggle,proto3" json:"toggle,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchResponse) Reset() {
+	*x = WatchResponse{}
+	mi := &file_yaft_v1_yaft_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchResponse) ProtoMessage() {}
+
+func (x *WatchResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_yaft_v1_yaft_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchResponse.ProtoReflect.Descriptor instead.
+func (*WatchResponse) Descriptor() ([]byte, []int) {
+	return file_yaft_v1_yaft_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *WatchResponse) GetType() WatchResponse_Type {
+	if x != nil {
+		return x.Type
+	}
+	return WatchResponse_TYPE_UNSPECIFIED
+}
+
+func (x *WatchResponse) GetToggle() *Toggle {
+	if x != nil {
+		return x.Toggle
+	}
+	return nil
+}
+
+var File_yaft_v1_yaft_proto protoreflect.FileDescriptor
+
+const file_yaft_v1_yaft_proto_rawDesc = "" +
+	"\n" +
+	"\x12yaft/v1/yaft.proto\x12\ayaft.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\xb5\x03\n" +
+	"\x06Toggle\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\bR\x05value\x127\n" +
+	"\tactive_at\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\bactiveAt\x12;\n" +
+	"\vdisabled_at\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"disabledAt\x127\n" +
+	"\tremove_at\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\bremoveAt\x12\x12\n" +
+	"\x04tags\x18\x06 \x03(\tR\x04tags\x12 \n" +
+	"\vdescription\x18\a \x01(\tR\vdescription\x12\x14\n" +
+	"\x05owner\x18\b \x01(\tR\x05owner\x12\x12\n" +
+	"\x04kind\x18\t \x01(\tR\x04kind\x129\n" +
+	"\n" +
+	"created_at\x18\n" +
+	" \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x129\n" +
+	"\n" +
+	"updated_at\x18\v \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\"$\n" +
+	"\x10GetToggleRequest\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\"<\n" +
+	"\x11GetToggleResponse\x12'\n" +
+	"\x06toggle\x18\x01 \x01(\v2\x0f.yaft.v1.ToggleR\x06toggle\"<\n" +
+	"\x10ListGroupRequest\x12\x14\n" +
+	"\x05group\x18\x01 \x01(\tR\x05group\x12\x12\n" +
+	"\x04tags\x18\x02 \x01(\tR\x04tags\">\n" +
+	"\x11ListGroupResponse\x12)\n" +
+	"\atoggles\x18\x01 \x03(\v2\x0f.yaft.v1.ToggleR\atoggles\"%\n" +
+	"\x0fEvaluateRequest\x12\x12\n" +
+	"\x04keys\x18\x01 \x03(\tR\x04keys\"J\n" +
+	"\n" +
+	"Evaluation\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\bR\x05value\x12\x14\n" +
+	"\x05found\x18\x03 \x01(\bR\x05found\"I\n" +
+	"\x10EvaluateResponse\x125\n" +
+	"\vevaluations\x18\x01 \x03(\v2\x13.yaft.v1.EvaluationR\vevaluations\">\n" +
+	"\x13CreateToggleRequest\x12'\n" +
+	"\x06toggle\x18\x01 \x01(\v2\x0f.yaft.v1.ToggleR\x06toggle\"?\n" +
+	"\x14CreateToggleResponse\x12'\n" +
+	"\x06toggle\x18\x01 \x01(\v2\x0f.yaft.v1.ToggleR\x06toggle\"9\n" +
+	"\x0fSetValueRequest\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\bR\x05value\";\n" +
+	"\x10SetValueResponse\x12'\n" +
+	"\x06toggle\x18\x01 \x01(\v2\x0f.yaft.v1.ToggleR\x06toggle\"\x9c\x01\n" +
+	"\x12SetScheduleRequest\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x127\n" +
+	"\tactive_at\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\bactiveAt\x12;\n" +
+	"\vdisabled_at\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"disabledAt\">\n" +
+	"\x13SetScheduleResponse\x12'\n" +
+	"\x06toggle\x18\x01 \x01(\v2\x0f.yaft.v1.ToggleR\x06toggle\"'\n" +
+	"\x13DeleteToggleRequest\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\"\x16\n" +
+	"\x14DeleteToggleResponse\"8\n" +
+	"\fWatchRequest\x12\x14\n" +
+	"\x05group\x18\x01 \x01(\tR\x05group\x12\x12\n" +
+	"\x04tags\x18\x02 \x01(\tR\x04tags\"\xab\x01\n" +
+	"\rWatchResponse\x12/\n" +
+	"\x04type\x18\x01 \x01(\x0e2\x1b.yaft.v1.WatchResponse.TypeR\x04type\x12'\n" +
+	"\x06toggle\x18\x02 \x01(\v2\x0f.yaft.v1.ToggleR\x06toggle\"@\n" +
+	"\x04Type\x12\x14\n" +
+	"\x10TYPE_UNSPECIFIED\x10\x00\x12\x10\n" +
+	"\fTYPE_CHANGED\x10\x01\x12\x10\n" +
+	"\fTYPE_DELETED\x10\x022\xb7\x04\n" +
+	"\rToggleService\x12B\n" +
+	"\tGetToggle\x12\x19.yaft.v1.GetToggleRequest\x1a\x1a.yaft.v1.GetToggleResponse\x12B\n" +
+	"\tListGroup\x12\x19.yaft.v1.ListGroupRequest\x1a\x1a.yaft.v1.ListGroupResponse\x12?\n" +
+	"\bEvaluate\x12\x18.yaft.v1.EvaluateRequest\x1a\x19.yaft.v1.EvaluateResponse\x12K\n" +
+	"\fCreateToggle\x12\x1c.yaft.v1.CreateToggleRequest\x1a\x1d.yaft.v1.CreateToggleResponse\x12?\n" +
+	"\bSetValue\x12\x18.yaft.v1.SetValueRequest\x1a\x19.yaft.v1.SetValueResponse\x12H\n" +
+	"\vSetSchedule\x12\x1b.yaft.v1.SetScheduleRequest\x1a\x1c.yaft.v1.SetScheduleResponse\x12K\n" +
+	"\fDeleteToggle\x12\x1c.yaft.v1.DeleteToggleRequest\x1a\x1d.yaft.v1.DeleteToggleResponse\x128\n" +
+	"\x05Watch\x12\x15.yaft.v1.WatchRequest\x1a\x16.yaft.v1.WatchResponse0\x01B.Z,tehwolf.de/tehw0lf/yaft/proto/yaft/v1;yaftv1b\x06proto3"
+
+var (
+	file_yaft_v1_yaft_proto_rawDescOnce sync.Once
+	file_yaft_v1_yaft_proto_rawDescData []byte
+)
+
+func file_yaft_v1_yaft_proto_rawDescGZIP() []byte {
+	file_yaft_v1_yaft_proto_rawDescOnce.Do(func() {
+		file_yaft_v1_yaft_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_yaft_v1_yaft_proto_rawDesc), len(file_yaft_v1_yaft_proto_rawDesc)))
+	})
+	return file_yaft_v1_yaft_proto_rawDescData
+}
+
+var file_yaft_v1_yaft_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_yaft_v1_yaft_proto_msgTypes = make([]protoimpl.MessageInfo, 18)
+var file_yaft_v1_yaft_proto_goTypes = []any{
+	(WatchResponse_Type)(0),       // 0: yaft.v1.WatchResponse.Type
+	(*Toggle)(nil),                // 1: yaft.v1.Toggle
+	(*GetToggleRequest)(nil),      // 2: yaft.v1.GetToggleRequest
+	(*GetToggleResponse)(nil),     // 3: yaft.v1.GetToggleResponse
+	(*ListGroupRequest)(nil),      // 4: yaft.v1.ListGroupRequest
+	(*ListGroupResponse)(nil),     // 5: yaft.v1.ListGroupResponse
+	(*EvaluateRequest)(nil),       // 6: yaft.v1.EvaluateRequest
+	(*Evaluation)(nil),            // 7: yaft.v1.Evaluation
+	(*EvaluateResponse)(nil),      // 8: yaft.v1.EvaluateResponse
+	(*CreateToggleRequest)(nil),   // 9: yaft.v1.CreateToggleRequest
+	(*CreateToggleResponse)(nil),  // 10: yaft.v1.CreateToggleResponse
+	(*SetValueRequest)(nil),       // 11: yaft.v1.SetValueRequest
+	(*SetValueResponse)(nil),      // 12: yaft.v1.SetValueResponse
+	(*SetScheduleRequest)(nil),    // 13: yaft.v1.SetScheduleRequest
+	(*SetScheduleResponse)(nil),   // 14: yaft.v1.SetScheduleResponse
+	(*DeleteToggleRequest)(nil),   // 15: yaft.v1.DeleteToggleRequest
+	(*DeleteToggleResponse)(nil),  // 16: yaft.v1.DeleteToggleResponse
+	(*WatchRequest)(nil),          // 17: yaft.v1.WatchRequest
+	(*WatchResponse)(nil),         // 18: yaft.v1.WatchResponse
+	(*timestamppb.Timestamp)(nil), // 19: google.protobuf.Timestamp
+}
+var file_yaft_v1_yaft_proto_depIdxs = []int32{
+	19, // 0: yaft.v1.Toggle.active_at:type_name -> google.protobuf.Timestamp
+	19, // 1: yaft.v1.Toggle.disabled_at:type_name -> google.protobuf.Timestamp
+	19, // 2: yaft.v1.Toggle.remove_at:type_name -> google.protobuf.Timestamp
+	19, // 3: yaft.v1.Toggle.created_at:type_name -> google.protobuf.Timestamp
+	19, // 4: yaft.v1.Toggle.updated_at:type_name -> google.protobuf.Timestamp
+	1,  // 5: yaft.v1.GetToggleResponse.toggle:type_name -> yaft.v1.Toggle
+	1,  // 6: yaft.v1.ListGroupResponse.toggles:type_name -> yaft.v1.Toggle
+	7,  // 7: yaft.v1.EvaluateResponse.evaluations:type_name -> yaft.v1.Evaluation
+	1,  // 8: yaft.v1.CreateToggleRequest.toggle:type_name -> yaft.v1.Toggle
+	1,  // 9: yaft.v1.CreateToggleResponse.toggle:type_name -> yaft.v1.Toggle
+	1,  // 10: yaft.v1.SetValueResponse.toggle:type_name -> yaft.v1.Toggle
+	19, // 11: yaft.v1.SetScheduleRequest.active_at:type_name -> google.protobuf.Timestamp
+	19, // 12: yaft.v1.SetScheduleRequest.disabled_at:type_name -> google.protobuf.Timestamp
+	1,  // 13: yaft.v1.SetScheduleResponse.toggle:type_name -> yaft.v1.Toggle
+	0,  // 14: yaft.v1.WatchResponse.type:type_name -> yaft.v1.WatchResponse.Type
+	1,  // 15: yaft.v1.WatchResponse.toggle:type_name -> yaft.v1.Toggle
+	2,  // 16: yaft.v1.ToggleService.GetToggle:input_type -> yaft.v1.GetToggleRequest
+	4,  // 17: yaft.v1.ToggleService.ListGroup:input_type -> yaft.v1.ListGroupRequest
+	6,  // 18: yaft.v1.ToggleService.Evaluate:input_type -> yaft.v1.EvaluateRequest
+	9,  // 19: yaft.v1.ToggleService.CreateToggle:input_type -> yaft.v1.CreateToggleRequest
+	11, // 20: yaft.v1.ToggleService.SetValue:input_type -> yaft.v1.SetValueRequest
+	13, // 21: yaft.v1.ToggleService.SetSchedule:input_type -> yaft.v1.SetScheduleRequest
+	15, // 22: yaft.v1.ToggleService.DeleteToggle:input_type -> yaft.v1.DeleteToggleRequest
+	17, // 23: yaft.v1.ToggleService.Watch:input_type -> yaft.v1.WatchRequest
+	3,  // 24: yaft.v1.ToggleService.GetToggle:output_type -> yaft.v1.GetToggleResponse
+	5,  // 25: yaft.v1.ToggleService.ListGroup:output_type -> yaft.v1.ListGroupResponse
+	8,  // 26: yaft.v1.ToggleService.Evaluate:output_type -> yaft.v1.EvaluateResponse
+	10, // 27: yaft.v1.ToggleService.CreateToggle:output_type -> yaft.v1.CreateToggleResponse
+	12, // 28: yaft.v1.ToggleService.SetValue:output_type -> yaft.v1.SetValueResponse
+	14, // 29: yaft.v1.ToggleService.SetSchedule:output_type -> yaft.v1.SetScheduleResponse
+	16, // 30: yaft.v1.ToggleService.DeleteToggle:output_type -> yaft.v1.DeleteToggleResponse
+	18, // 31: yaft.v1.ToggleService.Watch:output_type -> yaft.v1.WatchResponse
+	24, // [24:32] is the sub-list for method output_type
+	16, // [16:24] is the sub-list for method input_type
+	16, // [16:16] is the sub-list for extension type_name
+	16, // [16:16] is the sub-list for extension extendee
+	0,  // [0:16] is the sub-list for field type_name
+}
+
+func init() { file_yaft_v1_yaft_proto_init() }
+func file_yaft_v1_yaft_proto_init() {
+	if File_yaft_v1_yaft_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_yaft_v1_yaft_proto_rawDesc), len(file_yaft_v1_yaft_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   18,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_yaft_v1_yaft_proto_goTypes,
+		DependencyIndexes: file_yaft_v1_yaft_proto_depIdxs,
+		EnumInfos:         file_yaft_v1_yaft_proto_enumTypes,
+		MessageInfos:      file_yaft_v1_yaft_proto_msgTypes,
+	}.Build()
+	File_yaft_v1_yaft_proto = out.File
+	file_yaft_v1_yaft_proto_goTypes = nil
+	file_yaft_v1_yaft_proto_depIdxs = nil
+}
@@ -0,0 +1,342 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"tehwolf.de/tehw0lf/yaft/store"
+)
+
+const (
+	// defaultAuditLimit and maxAuditLimit bound the audit entries per request
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+)
+
+// adminRoutes registers the admin API, it is only called with an admin token configured
+func (s *Server) adminRoutes(router *gin.Engine) {
+	admin := router.Group(s.options.Prefix + "/admin")
+	admin.Use(s.rateLimit(), s.audit, s.authenticateAdmin)
+
+	admin.GET("/groups", s.listGroups)
+	admin.DELETE("/groups/:uuid", s.deleteGroup)
+	admin.PUT("/groups/:uuid/secret", s.resetGroupSecret)
+	admin.PUT("/groups/:uuid/freeze", s.freezeGroup(true))
+	admin.DELETE("/groups/:uuid/freeze", s.freezeGroup(false))
+	admin.GET("/stats", s.getInstanceStats)
+	admin.GET("/audit", s.listAudit)
+	admin.POST("/invites", s.createInvite)
+	admin.GET("/invites", s.listInvites)
+	admin.DELETE("/invites/:id", s.deleteInvite)
+}
+
+// adminLockout is the lockout key of the admin token for the client, it
+// cannot collide with a group UUID. The lockout is per client IP, so invalid
+// tokens from one client do not lock the operator out.
+func adminLockout(c *gin.Context) string {
+	return "admin:" + c.ClientIP()
+}
+
+// checkAdminToken reports whether the request carries the admin token as
+// bearer token. Invalid tokens count towards the lockout of the client, a
+// locked out client is answered with 429 and the request is aborted.
+func (s *Server) checkAdminToken(c *gin.Context) bool {
+	if !s.allowGroup(c, adminLockout(c), true) {
+		return false
+	}
+
+	token, found := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	match := s.options.AdminToken != "" && found && subtle.ConstantTimeCompare([]byte(token), []byte(s.options.AdminToken)) == 1
+	s.recordSecretCheck(c.Request.Context(), adminLockout(c), match)
+	return match
+}
+
+// authenticateAdmin requires the admin token as bearer token. Invalid tokens
+// count towards a lockout like invalid group secrets.
+func (s *Server) authenticateAdmin(c *gin.Context) {
+	if !s.checkAdminToken(c) {
+		if c.IsAborted() {
+			return
+		}
+		s.log(c.Request.Context()).WithFields(logrus.Fields{
+			"method": c.Request.Method,
+			"path":   c.Request.URL.Path,
+			"client": c.ClientIP(),
+		}).Warn("Invalid admin token, returning 401")
+
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid admin token"})
+		return
+	}
+	c.Next()
+}
+
+// audit records every request to the admin API after it was handled,
+// including rejected ones
+func (s *Server) audit(c *gin.Context) {
+	c.Next()
+
+	entry := store.AuditEntry{
+		Action:    c.Request.Method + " " + strings.TrimPrefix(c.FullPath(), s.options.Prefix),
+		Group:     c.Param("uuid"),
+		Status:    c.Writer.Status(),
+		ClientIP:  c.ClientIP(),
+		RequestID: RequestID(c.Request.Context()),
+	}
+	if err := s.store.RecordAudit(c.Request.Context(), &entry); err != nil {
+		s.log(c.Request.Context()).WithFields(logrus.Fields{
+			"action": entry.Action,
+			"group":  entry.Group,
+			"error":  err.Error(),
+		}).Error("Failed to record audit entry")
+	}
+}
+
+func (s *Server) listGroups(c *gin.Context) {
+	groups, err := s.store.ListGroups(c.Request.Context())
+	if err != nil {
+		s.log(c.Request.Context()).WithFields(logrus.Fields{
+			"method": "GET",
+			"path":   "/admin/groups",
+			"error":  err.Error(),
+		}).Error("Failed to list groups")
+
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list groups"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"groups": groups, "total": len(groups)})
+}
+
+// resetGroupSecret replaces the secret of a group with a generated one, the
+// old secret stops working at once
+func (s *Server) resetGroupSecret(c *gin.Context) {
+	uuid := c.Param("uuid")
+	if !s.adminGroupExists(c, uuid) {
+		return
+	}
+
+	secret := generateSecret()
+	if err := s.store.UpdateSecret(c.Request.Context(), uuid, secret); err != nil {
+		s.log(c.Request.Context()).WithFields(logrus.Fields{
+			"method": "PUT",
+			"path":   "/admin/groups/" + uuid + "/secret",
+			"uuid":   uuid,
+			"error":  err.Error(),
+		}).Error("Failed to reset secret")
+
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset secret"})
+		return
+	}
+
+	s.log(c.Request.Context()).WithFields(logrus.Fields{
+		"method": "PUT",
+		"path":   "/admin/groups/" + uuid + "/secret",
+		"uuid":   uuid,
+	}).Info("Successfully reset secret")
+
+	c.JSON(http.StatusOK, gin.H{"key": uuid, "secret": secret})
+}
+
+func (s *Server) deleteGroup(c *gin.Context) {
+	uuid := c.Param("uuid")
+
+	err := s.store.DeleteGroup(c.Request.Context(), uuid)
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No feature toggles found for provided UUID"})
+		return
+	}
+	if err != nil {
+		s.log(c.Request.Context()).WithFields(logrus.Fields{
+			"method": "DELETE",
+			"path":   "/admin/groups/" + uuid,
+			"uuid":   uuid,
+			"error":  err.Error(),
+		}).Error("Failed to delete group")
+
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete group"})
+		return
+	}
+
+	s.log(c.Request.Context()).WithFields(logrus.Fields{
+		"method": "DELETE",
+		"path":   "/admin/groups/" + uuid,
+		"uuid":   uuid,
+	}).Info("Successfully deleted group")
+
+	c.JSON(http.StatusOK, gin.H{"message": "Group deleted"})
+}
+
+// freezeGroup returns a handler freezing or thawing a group, frozen groups
+// can be read but every route changing them answers 403
+func (s *Server) freezeGroup(frozen bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		uuid := c.Param("uuid")
+		if !s.adminGroupExists(c, uuid) {
+			return
+		}
+
+		settings, err := s.store.GetGroupSettings(c.Request.Context(), uuid)
+		if err == nil || errors.Is(err, store.ErrNotFound) {
+			settings.Group = uuid
+			settings.Frozen = frozen
+			err = s.store.SaveGroupSettings(c.Request.Context(), &settings)
+		}
+		if err != nil {
+			s.log(c.Request.Context()).WithFields(logrus.Fields{
+				"method": c.Request.Method,
+				"path":   "/admin/groups/" + uuid + "/freeze",
+				"uuid":   uuid,
+				"error":  err.Error(),
+			}).Error("Failed to save group settings")
+
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to freeze group"})
+			return
+		}
+
+		s.log(c.Request.Context()).WithFields(logrus.Fields{
+			"method": c.Request.Method,
+			"path":   "/admin/groups/" + uuid + "/freeze",
+			"uuid":   uuid,
+			"frozen": frozen,
+		}).Info("Successfully changed group freeze")
+
+		c.JSON(http.StatusOK, gin.H{"key": uuid, "frozen": frozen})
+	}
+}
+
+func (s *Server) getInstanceStats(c *gin.Context) {
+	groups, err := s.store.ListGroups(c.Request.Context())
+	var stats []store.ToggleStat
+	if err == nil {
+		stats, err = s.store.ListStats(c.Request.Context(), "")
+	}
+	if err != nil {
+		s.log(c.Request.Context()).WithFields(logrus.Fields{
+			"method": "GET",
+			"path":   "/admin/stats",
+			"error":  err.Error(),
+		}).Error("Failed to get instance statistics")
+
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get instance statistics"})
+		return
+	}
+
+	toggles, frozen := 0, 0
+	for _, group := range groups {
+		toggles += group.Toggles
+		if group.Frozen {
+			frozen++
+		}
+	}
+	var reads, evaluations int64
+	for _, stat := range stats {
+		reads += stat.Reads
+		evaluations += stat.Evaluations
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"groups":       len(groups),
+		"frozenGroups": frozen,
+		"toggles":      toggles,
+		"reads":        reads,
+		"evaluations":  evaluations,
+	})
+}
+
+func (s *Server) listAudit(c *gin.Context) {
+	limit := defaultAuditLimit
+	if value := c.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxAuditLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit, expected 1 to 1000"})
+			return
+		}
+		limit = parsed
+	}
+
+	entries, err := s.store.ListAudit(c.Request.Context(), c.Query("group"), limit)
+	if err != nil {
+		s.log(c.Request.Context()).WithFields(logrus.Fields{
+			"method": "GET",
+			"path":   "/admin/audit",
+			"error":  err.Error(),
+		}).Error("Failed to list audit entries")
+
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list audit entries"})
+		return
+	}
+	if entries == nil {
+		entries = []store.AuditEntry{}
+	}
+	c.JSON(http.StatusOK, gin.H{"entries": entries})
+}
+
+// adminGroupExists responds with 404 and returns false if the group has no toggles
+func (s *Server) adminGroupExists(c *gin.Context, group string) bool {
+	exists, err := s.store.GroupExists(c.Request.Context(), group)
+	if err != nil {
+		s.log(c.Request.Context()).WithFields(logrus.Fields{
+			"method": c.Request.Method,
+			"path":   c.Request.URL.Path,
+			"uuid":   group,
+			"error":  err.Error(),
+		}).Error("Failed to check group")
+
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check group"})
+		return false
+	}
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No feature toggles found for provided UUID"})
+		return false
+	}
+	return true
+}
+
+// rejectFrozen answers 403 to requests changing a frozen group. Cloning only
+// reads the source group, the clone is a new group that cannot be frozen yet.
+func (s *Server) rejectFrozen(c *gin.Context) {
+	if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead || c.FullPath() == s.options.Prefix+"/clone/:uuid/:secret" {
+		c.Next()
+		return
+	}
+	if group := routeGroup(c); group != "" && !s.allowChange(c, group) {
+		return
+	}
+	c.Next()
+}
+
+// allowChange responds with 403 and returns false if the group is frozen
+func (s *Server) allowChange(c *gin.Context, group string) bool {
+	if s.groupFrozen(c.Request.Context(), group) {
+		s.log(c.Request.Context()).WithFields(logrus.Fields{
+			"method": c.Request.Method,
+			"path":   c.Request.URL.Path,
+			"group":  group,
+		}).Warn("Group is frozen, returning 403")
+
+		s.fail(c, http.StatusForbidden, codeGroupFrozen, "Group is frozen")
+		return false
+	}
+	return true
+}
+
+// groupFrozen reports whether an admin froze the group, errors are logged and
+// let the change pass
+func (s *Server) groupFrozen(ctx context.Context, group string) bool {
+	settings, err := s.store.GetGroupSettings(ctx, group)
+	if err != nil {
+		if !errors.Is(err, store.ErrNotFound) {
+			s.log(ctx).WithFields(logrus.Fields{
+				"group": group,
+				"error": err.Error(),
+			}).Error("Failed to check group freeze")
+		}
+		return false
+	}
+	return settings.Frozen
+}
@@ -0,0 +1,311 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"tehwolf.de/tehw0lf/yaft/store"
+)
+
+// AdmissionPolicy decides who may create new groups
+type AdmissionPolicy string
+
+const (
+	// AdmissionOpen lets anyone create groups, limited by the group creation rate
+	AdmissionOpen AdmissionPolicy = "open"
+	// AdmissionInvite requires an invite token issued through the admin API
+	AdmissionInvite AdmissionPolicy = "invite"
+	// AdmissionAdmin requires the admin token
+	AdmissionAdmin AdmissionPolicy = "admin"
+	// AdmissionQuota limits the groups each client IP may have created
+	AdmissionQuota AdmissionPolicy = "quota"
+)
+
+// defaultInviteTTL is how long invite tokens are valid if the admin does not say
+const defaultInviteTTL = 7 * 24 * time.Hour
+
+// Admission configures group creation. The zero value is the open policy.
+type Admission struct {
+	Policy AdmissionPolicy
+	// GroupsPerIP is the quota of the quota policy
+	GroupsPerIP int
+}
+
+// newInvite is the request body of invite creation
+type newInvite struct {
+	// MaxToggles limits the toggles of groups created with the invite, 0 is unlimited
+	MaxToggles int `json:"maxToggles"`
+	// Uses defaults to 1
+	Uses int `json:"uses"`
+	// ExpiresIn is a duration, it defaults to 7 days
+	ExpiresIn string `json:"expiresIn"`
+}
+
+// admittedGroup is a group creation the admission policy allowed
+type admittedGroup struct {
+	// settings are what the new group starts with
+	settings store.GroupSettings
+	// invite is the hash of the invite token whose use admitted the group
+	invite string
+}
+
+func hashInvite(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// admitGroup applies the admission policy to a request creating a group. It
+// responds and returns false if the group must not be created, otherwise it
+// returns the settings the new group starts with. A valid "invite" query
+// parameter admits the group under every policy but admin and passes its
+// limits on, callers give its use back with releaseAdmission if creating the
+// group fails. The quota counts per client IP, which only trusted proxies can
+// set through X-Forwarded-For.
+func (s *Server) admitGroup(c *gin.Context) (admittedGroup, bool) {
+	settings := store.GroupSettings{CreatorIP: c.ClientIP()}
+	policy := s.options.Admission.Policy
+
+	if policy == AdmissionAdmin {
+		// Guesses of the admin token are locked out like on the admin API
+		if !s.checkAdminToken(c) {
+			if !c.IsAborted() {
+				s.rejectGroup(c, http.StatusForbidden, codeAdminTokenRequired, "Creating groups requires the admin token")
+			}
+			return admittedGroup{}, false
+		}
+		return admittedGroup{settings: settings}, true
+	}
+
+	if !s.allowGroupCreation(c) {
+		return admittedGroup{}, false
+	}
+
+	if token := c.Query("invite"); token != "" {
+		invite, err := s.store.UseInvite(c.Request.Context(), hashInvite(token))
+		if errors.Is(err, store.ErrNotFound) {
+			s.rejectGroup(c, http.StatusForbidden, codeInvalidInvite, "Invalid invite token")
+			return admittedGroup{}, false
+		}
+		if err != nil {
+			s.log(c.Request.Context()).WithFields(logrus.Fields{
+				"method": c.Request.Method,
+				"path":   c.Request.URL.Path,
+				"error":  err.Error(),
+			}).Error("Failed to use invite token")
+
+			s.fail(c, http.StatusInternalServerError, codeInternal, "Failed to check invite token")
+			return admittedGroup{}, false
+		}
+		settings.MaxToggles = invite.MaxToggles
+		return admittedGroup{settings: settings, invite: invite.TokenHash}, true
+	}
+
+	switch policy {
+	case AdmissionInvite:
+		s.rejectGroup(c, http.StatusForbidden, codeInviteRequired, "Creating groups requires an invite token")
+		return admittedGroup{}, false
+	case AdmissionQuota:
+		count, err := s.store.CountCreatedGroups(c.Request.Context(), c.ClientIP())
+		if err != nil {
+			s.log(c.Request.Context()).WithFields(logrus.Fields{
+				"method": c.Request.Method,
+				"path":   c.Request.URL.Path,
+				"error":  err.Error(),
+			}).Error("Failed to count created groups")
+
+			s.fail(c, http.StatusInternalServerError, codeInternal, "Failed to check group quota")
+			return admittedGroup{}, false
+		}
+		if count >= s.options.Admission.GroupsPerIP {
+			s.rejectGroup(c, http.StatusForbidden, codeGroupQuotaExceeded, "Group quota exceeded")
+			return admittedGroup{}, false
+		}
+	}
+	return admittedGroup{settings: settings}, true
+}
+
+func (s *Server) rejectGroup(c *gin.Context, status int, code string, message string) {
+	s.log(c.Request.Context()).WithFields(logrus.Fields{
+		"method": c.Request.Method,
+		"path":   c.Request.URL.Path,
+		"client": c.ClientIP(),
+		"policy": s.options.Admission.Policy,
+	}).Warn(message + ", returning " + strconv.Itoa(status))
+
+	s.fail(c, status, code, message)
+}
+
+// saveAdmission stores the settings of a new group, failures are logged
+// because the group already exists
+func (s *Server) saveAdmission(c *gin.Context, group string, admitted admittedGroup) {
+	settings := admitted.settings
+	settings.Group = group
+	if err := s.store.SaveGroupSettings(c.Request.Context(), &settings); err != nil {
+		s.log(c.Request.Context()).WithFields(logrus.Fields{
+			"method": c.Request.Method,
+			"path":   c.Request.URL.Path,
+			"group":  group,
+			"error":  err.Error(),
+		}).Error("Failed to save group settings")
+	}
+}
+
+// releaseAdmission gives back the invite use of an admitted group that could
+// not be created, failures are logged because the request failed already
+func (s *Server) releaseAdmission(c *gin.Context, admitted admittedGroup) {
+	if admitted.invite == "" {
+		return
+	}
+	if err := s.store.ReturnInvite(c.Request.Context(), admitted.invite); err != nil {
+		s.log(c.Request.Context()).WithFields(logrus.Fields{
+			"method": c.Request.Method,
+			"path":   c.Request.URL.Path,
+			"error":  err.Error(),
+		}).Error("Failed to return invite use")
+	}
+}
+
+// allowToggles responds with 403 and returns false if adding toggles would
+// exceed the toggle limit of the group
+func (s *Server) allowToggles(c *gin.Context, group string, adding int) bool {
+	if maxToggles, reached := s.toggleLimitReached(c.Request.Context(), group, adding); reached {
+		s.log(c.Request.Context()).WithFields(logrus.Fields{
+			"method":     c.Request.Method,
+			"path":       c.Request.URL.Path,
+			"group":      group,
+			"maxToggles": maxToggles,
+		}).Warn("Toggle limit reached, returning 403")
+
+		s.fail(c, http.StatusForbidden, codeToggleLimitReached, "Toggle limit of the group reached")
+		return false
+	}
+	return true
+}
+
+// toggleLimitReached reports whether adding toggles would exceed the toggle
+// limit of the group and returns the limit, errors let the toggles pass
+func (s *Server) toggleLimitReached(ctx context.Context, group string, adding int) (int, bool) {
+	settings, err := s.store.GetGroupSettings(ctx, group)
+	if err != nil || settings.MaxToggles <= 0 {
+		return 0, false
+	}
+
+	toggles, err := s.store.List(ctx, store.Filter{Prefix: group + "|"})
+	if err != nil {
+		s.log(ctx).WithFields(logrus.Fields{
+			"group": group,
+			"error": err.Error(),
+		}).Error("Failed to count toggles")
+		return settings.MaxToggles, false
+	}
+	return settings.MaxToggles, len(toggles)+adding > settings.MaxToggles
+}
+
+func (s *Server) createInvite(c *gin.Context) {
+	body := newInvite{Uses: 1}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	ttl := defaultInviteTTL
+	if body.ExpiresIn != "" {
+		var err error
+		ttl, err = time.ParseDuration(body.ExpiresIn)
+		if err != nil || ttl <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid expiresIn, expected a positive duration"})
+			return
+		}
+	}
+	if body.Uses < 1 || body.MaxToggles < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invite, uses must be positive and maxToggles not negative"})
+		return
+	}
+
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create invite"})
+		return
+	}
+	token := hex.EncodeToString(random)
+
+	invite := store.InviteToken{
+		TokenHash:  hashInvite(token),
+		MaxToggles: body.MaxToggles,
+		UsesLeft:   body.Uses,
+		ExpiresAt:  time.Now().Add(ttl),
+	}
+	if err := s.store.CreateInvite(c.Request.Context(), &invite); err != nil {
+		s.log(c.Request.Context()).WithFields(logrus.Fields{
+			"method": "POST",
+			"path":   "/admin/invites",
+			"error":  err.Error(),
+		}).Error("Failed to create invite")
+
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create invite"})
+		return
+	}
+
+	s.log(c.Request.Context()).WithFields(logrus.Fields{
+		"method": "POST",
+		"path":   "/admin/invites",
+		"id":     invite.ID,
+	}).Info("Successfully created invite")
+
+	c.JSON(http.StatusCreated, gin.H{
+		"id":         invite.ID,
+		"token":      token,
+		"maxToggles": invite.MaxToggles,
+		"usesLeft":   invite.UsesLeft,
+		"expiresAt":  invite.ExpiresAt.UTC(),
+	})
+}
+
+func (s *Server) listInvites(c *gin.Context) {
+	invites, err := s.store.ListInvites(c.Request.Context())
+	if err != nil {
+		s.log(c.Request.Context()).WithFields(logrus.Fields{
+			"method": "GET",
+			"path":   "/admin/invites",
+			"error":  err.Error(),
+		}).Error("Failed to list invites")
+
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list invites"})
+		return
+	}
+	if invites == nil {
+		invites = []store.InviteToken{}
+	}
+	c.JSON(http.StatusOK, gin.H{"invites": invites})
+}
+
+func (s *Server) deleteInvite(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 0)
+	if err == nil {
+		err = s.store.DeleteInvite(c.Request.Context(), uint(id))
+	}
+	if errors.Is(err, store.ErrNotFound) || errors.Is(err, strconv.ErrSyntax) || errors.Is(err, strconv.ErrRange) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invite not found"})
+		return
+	}
+	if err != nil {
+		s.log(c.Request.Context()).WithFields(logrus.Fields{
+			"method": "DELETE",
+			"path":   "/admin/invites/" + c.Param("id"),
+			"error":  err.Error(),
+		}).Error("Failed to delete invite")
+
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete invite"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Invite deleted"})
+}
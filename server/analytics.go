@@ -0,0 +1,191 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"tehwolf.de/tehw0lf/yaft/store"
+)
+
+// How often aggregated read statistics are written to the store
+const statsFlushInterval = 10 * time.Second
+
+// statsRecorder aggregates reads in memory so the read path never waits for
+// the store. Aggregates are merged into the stored statistics by flush.
+type statsRecorder struct {
+	store   store.Store
+	mu      sync.Mutex
+	pending map[string]*store.ToggleStat
+}
+
+func newStatsRecorder(s store.Store) *statsRecorder {
+	return &statsRecorder{store: s, pending: make(map[string]*store.ToggleStat)}
+}
+
+func (r *statsRecorder) recordRead(key string, client string, value string, evaluation bool) {
+	now := time.Now()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stat, ok := r.pending[key]
+	if !ok {
+		stat = &store.ToggleStat{Key: key}
+		r.pending[key] = stat
+	}
+	if evaluation {
+		stat.Evaluations++
+	}
+	stat.Reads++
+	stat.LastReadAt = &now
+	stat.LastClient = client
+	stat.LastValue = value
+}
+
+// requeue merges statistics that could not be written back into the pending
+// set. Counts are added up, the details of the last read are taken from
+// whichever entry was read last.
+func (r *statsRecorder) requeue(failed map[string]*store.ToggleStat) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for key, old := range failed {
+		stat, ok := r.pending[key]
+		if ok {
+			old.Evaluations += stat.Evaluations
+			old.Reads += stat.Reads
+			if stat.LastReadAt != nil && (old.LastReadAt == nil || stat.LastReadAt.After(*old.LastReadAt)) {
+				old.LastReadAt = stat.LastReadAt
+				old.LastClient = stat.LastClient
+				old.LastValue = stat.LastValue
+			}
+		}
+		r.pending[key] = old
+	}
+}
+
+func (r *statsRecorder) flush(ctx context.Context) error {
+	r.mu.Lock()
+	pending := r.pending
+	r.pending = make(map[string]*store.ToggleStat)
+	r.mu.Unlock()
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	stats := make([]store.ToggleStat, 0, len(pending))
+	for _, stat := range pending {
+		stats = append(stats, *stat)
+	}
+	err := r.store.RecordStats(ctx, stats)
+	if err != nil {
+		r.requeue(pending)
+	}
+	return err
+}
+
+// flushStats flushes the aggregated statistics periodically until the server is closed
+func (s *Server) flushStats(interval time.Duration) {
+	defer close(s.stopped)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			if err := s.stats.flush(context.Background()); err != nil {
+				s.logger.WithFields(logrus.Fields{
+					"error": err.Error(),
+				}).Error("Failed to flush toggle statistics")
+			}
+		}
+	}
+}
+
+// clientID identifies the reading client by its X-Client-ID header, falling back to its IP
+func clientID(c *gin.Context) string {
+	if client := c.GetHeader("X-Client-ID"); client != "" {
+		return client
+	}
+	return c.ClientIP()
+}
+
+func (s *Server) getStats(c *gin.Context) {
+	uuid := c.Param("uuid")
+	secret := c.Param("secret")
+
+	if !s.secretsMatch(c.Request.Context(), uuid, secret) {
+		s.log(c.Request.Context()).WithFields(logrus.Fields{
+			"method": "GET",
+			"path":   "/stats/" + uuid,
+			"uuid":   uuid,
+		}).Error("Invalid secret, returning 401")
+
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid secret"})
+		return
+	}
+
+	s.log(c.Request.Context()).WithFields(logrus.Fields{
+		"method": "GET",
+		"path":   "/stats/" + uuid,
+		"uuid":   uuid,
+	}).Info("Received GET request for toggle statistics")
+
+	ctx := c.Request.Context()
+	var toggles []store.FeatureToggle
+	var rows []store.ToggleStat
+	err := s.stats.flush(ctx)
+	if err == nil {
+		toggles, err = s.store.List(ctx, store.Filter{Prefix: uuid})
+	}
+	if err == nil {
+		rows, err = s.store.ListStats(ctx, uuid)
+	}
+	if err != nil {
+		s.log(c.Request.Context()).WithFields(logrus.Fields{
+			"method": "GET",
+			"path":   "/stats/" + uuid,
+			"uuid":   uuid,
+			"error":  err.Error(),
+		}).Error("Failed to load toggle statistics")
+
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load toggle statistics"})
+		return
+	}
+
+	byKey := make(map[string]store.ToggleStat, len(rows))
+	for _, row := range rows {
+		byKey[row.Key] = row
+	}
+
+	// Toggles that were never read are reported with zero counts
+	result := make([]store.ToggleStat, 0, len(toggles))
+	for _, toggle := range toggles {
+		stat, ok := byKey[toggle.Key]
+		if !ok {
+			stat = store.ToggleStat{Key: toggle.Key}
+		}
+		result = append(result, stat)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Key < result[j].Key })
+
+	s.log(c.Request.Context()).WithFields(logrus.Fields{
+		"method": "GET",
+		"path":   "/stats/" + uuid,
+		"uuid":   uuid,
+		"length": len(result),
+	}).Info("Returning toggle statistics")
+
+	c.JSON(http.StatusOK, gin.H{
+		"stats": result,
+	})
+}
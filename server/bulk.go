@@ -0,0 +1,201 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"tehwolf.de/tehw0lf/yaft/store"
+)
+
+var (
+	errNoSelection = errors.New("A tags expression or keys are required")
+	errInvalidTag  = errors.New("Invalid tag, tags must not be empty, contain \",\" or \"|\" or start with \"!\"")
+)
+
+// invalidToggleError rejects a bulk update because of one of the selected toggles
+type invalidToggleError struct {
+	key string
+	err error
+}
+
+func (e *invalidToggleError) Error() string {
+	return e.err.Error() + " (" + e.key + ")"
+}
+
+func (e *invalidToggleError) Unwrap() error {
+	return e.err
+}
+
+// bulkSelection is the request body of the tags route, the action routes select by query only
+type bulkSelection struct {
+	// Keys are toggle names within the group
+	Keys   []string `json:"keys"`
+	Add    []string `json:"add"`
+	Remove []string `json:"remove"`
+}
+
+// bulkUpdate returns a handler applying an update to every toggle of a group
+// that matches the "tags" expression of the query and, if given, the keys.
+// prepare parses the request into the update. With "dryRun=true" nothing is
+// saved, the response lists the keys that would be updated.
+func (s *Server) bulkUpdate(route string, prepare func(c *gin.Context) (func(toggle *store.FeatureToggle) error, []string, error)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		uuid := c.Param("uuid")
+		secret := c.Param("secret")
+		path := "/bulk/" + route + "/" + uuid
+		dryRun := c.Query("dryRun") == "true"
+
+		if !startsWithUUID(uuid) || !s.secretsMatch(c.Request.Context(), uuid, secret) {
+			s.log(c.Request.Context()).WithFields(logrus.Fields{
+				"method": "PUT",
+				"path":   path,
+				"uuid":   uuid,
+			}).Error("Invalid secret, returning 401")
+
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid secret"})
+			return
+		}
+
+		update, names, err := prepare(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		tags, err := store.ParseTagExpr(c.Query("tags"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tags expression"})
+			return
+		}
+		if len(tags) == 0 && len(names) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": errNoSelection.Error()})
+			return
+		}
+
+		filter := store.Filter{Prefix: uuid + "|", TagExpr: tags}
+		for _, name := range names {
+			filter.Keys = append(filter.Keys, uuid+"|"+name)
+		}
+
+		s.log(c.Request.Context()).WithFields(logrus.Fields{
+			"method": "PUT",
+			"path":   path,
+			"uuid":   uuid,
+			"tags":   tags.String(),
+			"keys":   names,
+			"dryRun": dryRun,
+		}).Info("Received bulk update request")
+
+		var toggles []store.FeatureToggle
+		if dryRun {
+			toggles, err = s.store.List(c.Request.Context(), filter)
+			for i := 0; err == nil && i < len(toggles); i++ {
+				err = update(&toggles[i])
+			}
+		} else {
+			toggles, err = s.store.UpdateAll(c.Request.Context(), filter, update)
+		}
+
+		var invalid *invalidToggleError
+		if errors.As(err, &invalid) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": invalid.err.Error(), "key": invalid.key})
+			return
+		}
+		if err != nil {
+			s.log(c.Request.Context()).WithFields(logrus.Fields{
+				"method": "PUT",
+				"path":   path,
+				"uuid":   uuid,
+				"error":  err.Error(),
+			}).Error("Failed to update feature toggles")
+
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update feature toggles"})
+			return
+		}
+
+		keys := []string{}
+		for _, toggle := range toggles {
+			keys = append(keys, toggle.Key)
+		}
+
+		s.log(c.Request.Context()).WithFields(logrus.Fields{
+			"method": "PUT",
+			"path":   path,
+			"uuid":   uuid,
+			"count":  len(keys),
+			"dryRun": dryRun,
+		}).Info("Successfully updated feature toggles")
+
+		c.JSON(http.StatusOK, gin.H{"keys": keys, "count": len(keys), "dryRun": dryRun})
+	}
+}
+
+// setValue prepares a bulk activation or deactivation
+func setValue(value string) func(c *gin.Context) (func(toggle *store.FeatureToggle) error, []string, error) {
+	return func(c *gin.Context) (func(toggle *store.FeatureToggle) error, []string, error) {
+		return func(toggle *store.FeatureToggle) error {
+			toggle.Value = value
+			return nil
+		}, nil, nil
+	}
+}
+
+// setSchedule prepares a bulk activateAt or deactivateAt from the date parameter
+func setSchedule(set func(toggle *store.FeatureToggle, date time.Time)) func(c *gin.Context) (func(toggle *store.FeatureToggle) error, []string, error) {
+	return func(c *gin.Context) (func(toggle *store.FeatureToggle) error, []string, error) {
+		date, err := parseScheduleDate(c.Param("date"), c.Query("tz"))
+		if err != nil {
+			return nil, nil, err
+		}
+		return func(toggle *store.FeatureToggle) error {
+			set(toggle, date)
+			if err := validateSchedule(toggle); err != nil {
+				return &invalidToggleError{key: toggle.Key, err: err}
+			}
+			return nil
+		}, nil, nil
+	}
+}
+
+// isValidTag rejects tags that cannot be used in tag expressions
+func isValidTag(tag string) bool {
+	return strings.TrimSpace(tag) == tag && tag != "" && !strings.ContainsAny(tag, ",|") && !strings.HasPrefix(tag, "!")
+}
+
+// updateTags prepares adding and removing tags, toggles are selected by the
+// keys of the body and the tags expression
+func updateTags(c *gin.Context) (func(toggle *store.FeatureToggle) error, []string, error) {
+	var body bulkSelection
+	if err := c.ShouldBindJSON(&body); err != nil {
+		return nil, nil, err
+	}
+	if len(body.Add) == 0 && len(body.Remove) == 0 {
+		return nil, nil, errors.New("No tags to add or remove")
+	}
+	for _, tag := range append(append([]string{}, body.Add...), body.Remove...) {
+		if !isValidTag(tag) {
+			return nil, nil, errInvalidTag
+		}
+	}
+
+	return func(toggle *store.FeatureToggle) error {
+		var tags []string
+		for _, tag := range toggle.Tags {
+			if !slices.Contains(body.Remove, tag) {
+				tags = append(tags, tag)
+			}
+		}
+		for _, tag := range body.Add {
+			if !slices.Contains(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+		toggle.Tags = tags
+		return nil
+	}, body.Keys, nil
+}
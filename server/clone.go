@@ -0,0 +1,136 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"tehwolf.de/tehw0lf/yaft/store"
+)
+
+// cloneGroup copies the toggles of a group into a new group with a generated
+// secret. The "tags" expression of the query selects the toggles to copy,
+// with "resetSchedules=true" the copies keep their current value but no
+// activation or deactivation date.
+func (s *Server) cloneGroup(c *gin.Context) {
+	uuid := c.Param("uuid")
+	secret := c.Param("secret")
+	path := "/clone/" + uuid
+	resetSchedules := c.Query("resetSchedules") == "true"
+
+	if !startsWithUUID(uuid) || !s.secretsMatch(c.Request.Context(), uuid, secret) {
+		s.log(c.Request.Context()).WithFields(logrus.Fields{
+			"method": "POST",
+			"path":   path,
+			"uuid":   uuid,
+		}).Error("Invalid secret, returning 401")
+
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid secret"})
+		return
+	}
+
+	tags, err := store.ParseTagExpr(c.Query("tags"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tags expression"})
+		return
+	}
+
+	s.log(c.Request.Context()).WithFields(logrus.Fields{
+		"method":         "POST",
+		"path":           path,
+		"uuid":           uuid,
+		"tags":           tags.String(),
+		"resetSchedules": resetSchedules,
+	}).Info("Received request to clone group")
+
+	toggles, err := s.store.List(c.Request.Context(), store.Filter{Prefix: uuid + "|", TagExpr: tags})
+	if err != nil {
+		s.log(c.Request.Context()).WithFields(logrus.Fields{
+			"method": "POST",
+			"path":   path,
+			"uuid":   uuid,
+			"error":  err.Error(),
+		}).Error("Failed to list feature toggles")
+
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clone group"})
+		return
+	}
+	if len(toggles) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No feature toggles found for provided UUID"})
+		return
+	}
+
+	admission, ok := s.admitGroup(c)
+	if !ok {
+		return
+	}
+	if admission.settings.MaxToggles > 0 && len(toggles) > admission.settings.MaxToggles {
+		s.releaseAdmission(c, admission)
+		c.JSON(http.StatusForbidden, gin.H{"error": "Toggle limit of the group reached"})
+		return
+	}
+
+	key, err := s.prependUUID(c.Request.Context(), "")
+	if err != nil {
+		s.log(c.Request.Context()).WithFields(logrus.Fields{
+			"method": "POST",
+			"path":   path,
+			"uuid":   uuid,
+			"error":  err.Error(),
+		}).Error("Failed to create feature toggle group")
+
+		s.releaseAdmission(c, admission)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clone group"})
+		return
+	}
+	group := store.Group(key)
+	newSecret := generateSecret()
+
+	now := time.Now()
+	for i := range toggles {
+		toggle := &toggles[i]
+		if resetSchedules {
+			toggle.Value = effectiveValue(*toggle, now)
+			toggle.ActiveAt = nil
+			toggle.DisabledAt = nil
+		}
+		// IDs and timestamps are maintained by the store
+		toggle.ID = 0
+		toggle.CreatedAt = time.Time{}
+		toggle.UpdatedAt = time.Time{}
+		toggle.Key = group + "|" + store.Name(toggle.Key)
+		toggle.Secret = newSecret
+	}
+
+	if err := s.store.CreateAll(c.Request.Context(), toggles); err != nil {
+		s.log(c.Request.Context()).WithFields(logrus.Fields{
+			"method": "POST",
+			"path":   path,
+			"uuid":   uuid,
+			"group":  group,
+			"error":  err.Error(),
+		}).Error("Failed to clone group")
+
+		s.releaseAdmission(c, admission)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clone group"})
+		return
+	}
+
+	s.saveAdmission(c, group, admission)
+
+	keys := make([]string, 0, len(toggles))
+	for _, toggle := range toggles {
+		keys = append(keys, toggle.Key)
+	}
+
+	s.log(c.Request.Context()).WithFields(logrus.Fields{
+		"method": "POST",
+		"path":   path,
+		"uuid":   uuid,
+		"group":  group,
+		"count":  len(keys),
+	}).Info("Successfully cloned group")
+
+	c.JSON(http.StatusCreated, gin.H{"uuid": group, "secret": newSecret, "keys": keys, "count": len(keys)})
+}
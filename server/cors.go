@@ -0,0 +1,333 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"tehwolf.de/tehw0lf/yaft/store"
+)
+
+// CORSPolicy decides which browser origins may call the API. Groups can
+// restrict their routes further with their own allowlist.
+type CORSPolicy struct {
+	// AllowedOrigins lists the origins whose requests are answered with CORS
+	// headers. Entries are exact origins like "https://app.example.com",
+	// wildcard subdomains like "https://*.example.com" or "*" for every
+	// origin. An empty list only allows same-origin requests.
+	AllowedOrigins []string
+	// AllowedMethods limits the methods browsers may use, empty allows every
+	// method registered for the requested route
+	AllowedMethods []string
+	// AllowCredentials allows browsers to send cookies and HTTP authentication
+	AllowCredentials bool
+	// MaxAge is how long browsers may cache preflight responses
+	MaxAge time.Duration
+}
+
+const corsAllowedHeaders = "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, X-Client-ID, X-Request-ID, X-Yaft-Secret, traceparent, tracestate, If-None-Match"
+
+const corsExposedHeaders = "Deprecation, Sunset, X-Request-ID, ETag, Location, Content-Location"
+
+// originAllowed matches an origin against exact and wildcard subdomain patterns
+func originAllowed(patterns []string, origin string) bool {
+	for _, pattern := range patterns {
+		if pattern == "*" || strings.EqualFold(pattern, origin) {
+			return true
+		}
+
+		scheme, host, ok := strings.Cut(pattern, "://*.")
+		if !ok {
+			continue
+		}
+		prefix := scheme + "://"
+		if len(origin) > len(prefix) && strings.EqualFold(origin[:len(prefix)], prefix) &&
+			strings.HasSuffix(strings.ToLower(origin[len(prefix):]), "."+strings.ToLower(host)) {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateOrigins checks that all origin patterns are "*", an origin or a wildcard subdomain origin
+func ValidateOrigins(patterns []string) error {
+	for _, pattern := range patterns {
+		if pattern == "*" {
+			continue
+		}
+		parsed, err := url.Parse(strings.Replace(pattern, "://*.", "://", 1))
+		if err != nil || parsed.Scheme == "" || parsed.Host == "" || parsed.Path != "" || parsed.RawQuery != "" || strings.Contains(parsed.Host, "*") {
+			return errors.New("Invalid origin " + strconv.Quote(pattern))
+		}
+	}
+	return nil
+}
+
+// sameOrigin reports whether the origin is the host the request was sent to
+func sameOrigin(r *http.Request, origin string) bool {
+	parsed, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(parsed.Host, r.Host)
+}
+
+// route is a registered route pattern with its methods
+type route struct {
+	segments []string
+	methods  []string
+}
+
+// match returns the path parameters if the path matches the route
+func (r route) match(path string) (map[string]string, bool) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	params := map[string]string{}
+	for i, segment := range r.segments {
+		if strings.HasPrefix(segment, "*") {
+			params[segment[1:]] = strings.Join(segments[i:], "/")
+			return params, true
+		}
+		if i >= len(segments) {
+			return nil, false
+		}
+		if strings.HasPrefix(segment, ":") {
+			params[segment[1:]] = segments[i]
+		} else if segment != segments[i] {
+			return nil, false
+		}
+	}
+	return params, len(segments) == len(r.segments)
+}
+
+func collectRoutes(info gin.RoutesInfo) []route {
+	byPath := map[string]*route{}
+	var paths []string
+	for _, ri := range info {
+		r, ok := byPath[ri.Path]
+		if !ok {
+			r = &route{segments: strings.Split(strings.Trim(ri.Path, "/"), "/")}
+			byPath[ri.Path] = r
+			paths = append(paths, ri.Path)
+		}
+		r.methods = append(r.methods, ri.Method)
+	}
+	sort.Strings(paths)
+
+	routes := make([]route, 0, len(paths))
+	for _, path := range paths {
+		routes = append(routes, *byPath[path])
+	}
+	return routes
+}
+
+// corsTarget returns the methods registered for the path and the group it belongs to
+func (s *Server) corsTarget(path string) ([]string, string) {
+	methods := map[string]bool{}
+	group := ""
+	for _, r := range s.routes {
+		params, ok := r.match(path)
+		if !ok {
+			continue
+		}
+		for _, method := range r.methods {
+			methods[method] = true
+		}
+		if group == "" {
+			if key, ok := params["key"]; ok && startsWithUUID(key) {
+				group = store.Group(key)
+			} else if uuid, ok := params["uuid"]; ok {
+				group = uuid
+			}
+		}
+	}
+
+	allowed := []string{}
+	for method := range methods {
+		if len(s.options.CORS.AllowedMethods) == 0 || containsFold(s.options.CORS.AllowedMethods, method) {
+			allowed = append(allowed, method)
+		}
+	}
+	sort.Strings(allowed)
+	return allowed, group
+}
+
+func containsFold(list []string, value string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// groupAllows checks the group's own allowlist, groups without one allow every origin the policy allows
+func (s *Server) groupAllows(c *gin.Context, group string, origin string) bool {
+	if group == "" {
+		return true
+	}
+	settings, err := s.store.GetGroupSettings(c.Request.Context(), group)
+	if errors.Is(err, store.ErrNotFound) {
+		return true
+	}
+	if err != nil {
+		s.log(c.Request.Context()).WithFields(logrus.Fields{
+			"group": group,
+			"error": err.Error(),
+		}).Error("Failed to load group settings")
+		return false
+	}
+	return len(settings.AllowedOrigins) == 0 || originAllowed(settings.AllowedOrigins, origin)
+}
+
+// cors answers preflight requests, adds the CORS headers for allowed origins
+// and rejects browser requests from other origins
+func (s *Server) cors() gin.HandlerFunc {
+	policy := s.options.CORS
+
+	return func(c *gin.Context) {
+		origin := c.Request.Header.Get("Origin")
+		preflight := c.Request.Method == http.MethodOptions
+		if origin == "" || (!preflight && sameOrigin(c.Request, origin)) {
+			if preflight {
+				c.AbortWithStatus(http.StatusNoContent)
+				return
+			}
+			c.Next()
+			return
+		}
+
+		methods, group := s.corsTarget(c.Request.URL.Path)
+		c.Writer.Header().Add("Vary", "Origin")
+
+		if !originAllowed(policy.AllowedOrigins, origin) || !s.groupAllows(c, group, origin) {
+			s.log(c.Request.Context()).WithFields(logrus.Fields{
+				"method": c.Request.Method,
+				"path":   c.Request.URL.Path,
+				"origin": origin,
+			}).Warn("Origin not allowed, returning 403")
+
+			s.fail(c, http.StatusForbidden, codeOriginNotAllowed, "Origin not allowed")
+			return
+		}
+
+		c.Writer.Header().Set("Access-Control-Allow-Origin", origin)
+		if policy.AllowCredentials {
+			c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if !preflight {
+			c.Writer.Header().Set("Access-Control-Expose-Headers", corsExposedHeaders)
+			c.Next()
+			return
+		}
+
+		if len(methods) > 0 {
+			c.Writer.Header().Set("Access-Control-Allow-Methods", strings.Join(append(methods, http.MethodOptions), ", "))
+			c.Writer.Header().Set("Access-Control-Allow-Headers", corsAllowedHeaders)
+			if policy.MaxAge > 0 {
+				c.Writer.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(policy.MaxAge.Seconds())))
+			}
+		}
+		c.AbortWithStatus(http.StatusNoContent)
+	}
+}
+
+type corsSettings struct {
+	AllowedOrigins []string `json:"allowedOrigins"`
+}
+
+func (s *Server) getGroupCORS(c *gin.Context) {
+	uuid := c.Param("uuid")
+	secret := c.Param("secret")
+
+	if !s.secretsMatch(c.Request.Context(), uuid, secret) {
+		s.log(c.Request.Context()).WithFields(logrus.Fields{
+			"method": "GET",
+			"path":   "/cors/" + uuid,
+			"uuid":   uuid,
+		}).Error("Invalid secret, returning 401")
+
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid secret"})
+		return
+	}
+
+	settings, err := s.store.GetGroupSettings(c.Request.Context(), uuid)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		s.log(c.Request.Context()).WithFields(logrus.Fields{
+			"method": "GET",
+			"path":   "/cors/" + uuid,
+			"uuid":   uuid,
+			"error":  err.Error(),
+		}).Error("Failed to load group settings")
+
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load CORS settings"})
+		return
+	}
+
+	origins := []string(settings.AllowedOrigins)
+	if origins == nil {
+		origins = []string{}
+	}
+	c.JSON(http.StatusOK, corsSettings{AllowedOrigins: origins})
+}
+
+func (s *Server) updateGroupCORS(c *gin.Context) {
+	uuid := c.Param("uuid")
+	secret := c.Param("secret")
+
+	if !s.secretsMatch(c.Request.Context(), uuid, secret) {
+		s.log(c.Request.Context()).WithFields(logrus.Fields{
+			"method": "PUT",
+			"path":   "/cors/" + uuid,
+			"uuid":   uuid,
+		}).Error("Invalid secret, returning 401")
+
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid secret"})
+		return
+	}
+
+	var body corsSettings
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := ValidateOrigins(body.AllowedOrigins); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	settings, err := s.store.GetGroupSettings(c.Request.Context(), uuid)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update CORS settings"})
+		return
+	}
+	settings.Group = uuid
+	settings.AllowedOrigins = body.AllowedOrigins
+
+	if err := s.store.SaveGroupSettings(c.Request.Context(), &settings); err != nil {
+		s.log(c.Request.Context()).WithFields(logrus.Fields{
+			"method": "PUT",
+			"path":   "/cors/" + uuid,
+			"uuid":   uuid,
+			"error":  err.Error(),
+		}).Error("Failed to save group settings")
+
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update CORS settings"})
+		return
+	}
+
+	s.log(c.Request.Context()).WithFields(logrus.Fields{
+		"method":         "PUT",
+		"path":           "/cors/" + uuid,
+		"uuid":           uuid,
+		"allowedOrigins": body.AllowedOrigins,
+	}).Info("Successfully updated CORS settings")
+
+	if body.AllowedOrigins == nil {
+		body.AllowedOrigins = []string{}
+	}
+	c.JSON(http.StatusOK, body)
+}
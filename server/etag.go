@@ -0,0 +1,37 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"tehwolf.de/tehw0lf/yaft/store"
+)
+
+// servedHash hashes toggles ordered by key as they are served, with their
+// schedules evaluated at now, so the hash changes when a schedule flips a value
+func servedHash(toggles []store.FeatureToggle, now time.Time) string {
+	served := make([]store.FeatureToggle, len(toggles))
+	for i, toggle := range toggles {
+		toggle.Value = effectiveValue(toggle, now)
+		served[i] = toggle
+	}
+	return store.HashToggles(served)
+}
+
+// notModified sets the ETag of a response and reports whether the client
+// already has it. In that case the response is answered with 304.
+func notModified(c *gin.Context, hash string) bool {
+	etag := `"` + hash + `"`
+	c.Header("ETag", etag)
+
+	for _, candidate := range strings.Split(c.GetHeader("If-None-Match"), ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			c.Status(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,512 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	yaftv1 "tehwolf.de/tehw0lf/yaft/proto/yaft/v1"
+	"tehwolf.de/tehw0lf/yaft/store"
+)
+
+// SecretMetadata is the gRPC metadata key carrying the secret of the group
+const SecretMetadata = "x-yaft-secret"
+
+// defaultWatchInterval is how often watched groups are polled by default
+const defaultWatchInterval = time.Second
+
+// maxWatchFailures is how many polls of a watched group may fail in a row
+// before the stream ends, so clients reconnect instead of waiting silently
+const maxWatchFailures = 5
+
+// GRPCOptions configure the gRPC API, the zero value polls watched groups
+// every second without reflection
+type GRPCOptions struct {
+	// Reflection registers the server reflection service, so tools like
+	// grpcurl can discover the API
+	Reflection bool
+	// WatchInterval is how often watched groups are polled for changes
+	WatchInterval time.Duration
+	// ServerOptions are passed to grpc.NewServer, e.g. TLS credentials or
+	// interceptors replacing the Authenticator of the HTTP API
+	ServerOptions []grpc.ServerOption
+}
+
+// NewGRPCServer serves the toggles of s over gRPC. Both APIs share the store,
+// the statistics, the rate limits and the lockout, the Authenticator of the
+// HTTP API does not apply.
+func NewGRPCServer(s *Server, options GRPCOptions) *grpc.Server {
+	if options.WatchInterval <= 0 {
+		options.WatchInterval = defaultWatchInterval
+	}
+
+	serverOptions := append([]grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(s.grpcUnaryAccess),
+		grpc.ChainStreamInterceptor(s.grpcStreamAccess),
+	}, options.ServerOptions...)
+	grpcServer := grpc.NewServer(serverOptions...)
+	yaftv1.RegisterToggleServiceServer(grpcServer, &toggleService{server: s, watchInterval: options.WatchInterval})
+	if options.Reflection {
+		reflection.Register(grpcServer)
+	}
+	return grpcServer
+}
+
+// grpcClientIP returns the address of the calling peer without port
+func grpcClientIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+// grpcClientID identifies the client for the read statistics like the
+// X-Client-ID header does over HTTP
+func grpcClientID(ctx context.Context) string {
+	if values := metadata.ValueFromIncomingContext(ctx, "x-client-id"); len(values) > 0 && values[0] != "" {
+		return values[0]
+	}
+	return grpcClientIP(ctx)
+}
+
+// grpcAllowClient applies the client IP rate limit to a call
+func (s *Server) grpcAllowClient(ctx context.Context) error {
+	limits := s.options.RateLimit
+	if wait := s.allowKey(ctx, "ip:"+grpcClientIP(ctx), limits.RequestsPerSecond, limits.Burst); wait > 0 {
+		return status.Error(codes.ResourceExhausted, "Too many requests")
+	}
+	return nil
+}
+
+// grpcLog logs a finished call like the access log of the HTTP API
+func (s *Server) grpcLog(ctx context.Context, method string, start time.Time, err error) {
+	s.log(ctx).WithFields(logrus.Fields{
+		"method":   method,
+		"code":     status.Code(err).String(),
+		"client":   grpcClientIP(ctx),
+		"duration": time.Since(start).String(),
+	}).Info("Handled gRPC call")
+}
+
+func (s *Server) grpcUnaryAccess(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	if err := s.grpcAllowClient(ctx); err != nil {
+		s.grpcLog(ctx, info.FullMethod, start, err)
+		return nil, err
+	}
+	resp, err := handler(ctx, req)
+	s.grpcLog(ctx, info.FullMethod, start, err)
+	return resp, err
+}
+
+func (s *Server) grpcStreamAccess(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	err := s.grpcAllowClient(stream.Context())
+	if err == nil {
+		err = handler(srv, stream)
+	}
+	s.grpcLog(stream.Context(), info.FullMethod, start, err)
+	return err
+}
+
+// toggleService implements the gRPC API on top of a Server
+type toggleService struct {
+	yaftv1.UnimplementedToggleServiceServer
+	server        *Server
+	watchInterval time.Duration
+}
+
+// toProto converts a toggle without its secret
+func toProto(toggle store.FeatureToggle) *yaftv1.Toggle {
+	timestamp := func(t *time.Time) *timestamppb.Timestamp {
+		if t == nil {
+			return nil
+		}
+		return timestamppb.New(*t)
+	}
+	return &yaftv1.Toggle{
+		Key:         toggle.Key,
+		Value:       toggle.Value == "true",
+		ActiveAt:    timestamp(toggle.ActiveAt),
+		DisabledAt:  timestamp(toggle.DisabledAt),
+		RemoveAt:    timestamp(toggle.RemoveAt),
+		Tags:        toggle.Tags,
+		Description: toggle.Description,
+		Owner:       toggle.Owner,
+		Kind:        toggle.Kind,
+		CreatedAt:   timestamppb.New(toggle.CreatedAt),
+		UpdatedAt:   timestamppb.New(toggle.UpdatedAt),
+	}
+}
+
+// fromTimestamp converts an optional timestamp, unset timestamps are nil
+func fromTimestamp(timestamp *timestamppb.Timestamp) *time.Time {
+	if timestamp == nil {
+		return nil
+	}
+	t := timestamp.AsTime()
+	return &t
+}
+
+func boolValue(value bool) string {
+	if value {
+		return "true"
+	}
+	return "false"
+}
+
+// allowGroup applies the group rate limit and, for calls checking the
+// secret, the lockout
+func (g *toggleService) allowGroup(ctx context.Context, group string, checksSecret bool) error {
+	limits := g.server.options.RateLimit
+	if wait := g.server.allowKey(ctx, "group:"+group, limits.GroupRequestsPerSecond, limits.GroupBurst); wait > 0 {
+		return status.Error(codes.ResourceExhausted, "Too many requests")
+	}
+	if checksSecret && g.server.lockedOut(ctx, group) > 0 {
+		return status.Error(codes.ResourceExhausted, "Too many invalid secrets")
+	}
+	return nil
+}
+
+// authorize checks the secret of the metadata against the group of the key
+// and rejects changes to frozen groups
+func (g *toggleService) authorize(ctx context.Context, key string) (string, error) {
+	if !startsWithUUID(key) {
+		return "", status.Error(codes.NotFound, "Feature not found")
+	}
+	group := store.Group(key)
+	if err := g.allowGroup(ctx, group, true); err != nil {
+		return "", err
+	}
+
+	secret := ""
+	if values := metadata.ValueFromIncomingContext(ctx, SecretMetadata); len(values) > 0 {
+		secret = values[0]
+	}
+	if !g.server.secretsMatch(ctx, key, secret) {
+		g.server.log(ctx).WithFields(logrus.Fields{
+			"key": key,
+		}).Error("Invalid secret, returning Unauthenticated")
+		return "", status.Error(codes.Unauthenticated, "Invalid secret")
+	}
+	if g.server.groupFrozen(ctx, group) {
+		return "", status.Error(codes.PermissionDenied, "Group is frozen")
+	}
+	return secret, nil
+}
+
+// storeError maps a store error to a status, logging unexpected errors
+func (g *toggleService) storeError(ctx context.Context, key string, err error, message string) error {
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		return status.Error(codes.NotFound, "Feature not found")
+	case errors.Is(err, store.ErrConflict):
+		return status.Error(codes.AlreadyExists, "Feature already exists")
+	}
+	g.server.log(ctx).WithFields(logrus.Fields{
+		"key":   key,
+		"error": err.Error(),
+	}).Error(message)
+	return status.Error(codes.Internal, message)
+}
+
+func (g *toggleService) GetToggle(ctx context.Context, req *yaftv1.GetToggleRequest) (*yaftv1.GetToggleResponse, error) {
+	if startsWithUUID(req.GetKey()) {
+		if err := g.allowGroup(ctx, store.Group(req.GetKey()), false); err != nil {
+			return nil, err
+		}
+	}
+
+	toggle, err := g.server.store.Get(ctx, req.GetKey())
+	// Old keys of moved toggles resolve like on the HTTP API
+	if errors.Is(err, store.ErrNotFound) && strings.Contains(req.GetKey(), "|") {
+		_, toggle, err = g.server.resolveAlias(ctx, req.GetKey())
+	}
+	if err != nil {
+		return nil, g.storeError(ctx, req.GetKey(), err, "Failed to find feature toggle")
+	}
+	toggle.Value = effectiveValue(toggle, time.Now())
+	g.server.stats.recordRead(toggle.Key, grpcClientID(ctx), toggle.Value, true)
+
+	return &yaftv1.GetToggleResponse{Toggle: toProto(toggle)}, nil
+}
+
+// list returns the toggles of a group matching the tag expression with their
+// effective values
+func (g *toggleService) list(ctx context.Context, group string, tags string) ([]store.FeatureToggle, error) {
+	if !startsWithUUID(group) || store.Group(group) != group {
+		return nil, status.Error(codes.InvalidArgument, "Invalid group, expected a UUID")
+	}
+	tagExpr, err := store.ParseTagExpr(tags)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "Invalid tags expression")
+	}
+
+	toggles, err := g.server.store.List(ctx, store.Filter{Prefix: group + "|", TagExpr: tagExpr})
+	if err != nil {
+		return nil, g.storeError(ctx, group, err, "Failed to find feature toggles")
+	}
+	now := time.Now()
+	for i := range toggles {
+		toggles[i].Value = effectiveValue(toggles[i], now)
+	}
+	return toggles, nil
+}
+
+func (g *toggleService) ListGroup(ctx context.Context, req *yaftv1.ListGroupRequest) (*yaftv1.ListGroupResponse, error) {
+	if startsWithUUID(req.GetGroup()) {
+		if err := g.allowGroup(ctx, store.Group(req.GetGroup()), false); err != nil {
+			return nil, err
+		}
+	}
+
+	toggles, err := g.list(ctx, req.GetGroup(), req.GetTags())
+	if err != nil {
+		return nil, err
+	}
+	if len(toggles) == 0 {
+		return nil, status.Error(codes.NotFound, "No feature toggles found for provided UUID")
+	}
+
+	client := grpcClientID(ctx)
+	response := &yaftv1.ListGroupResponse{}
+	for _, toggle := range toggles {
+		g.server.stats.recordRead(toggle.Key, client, toggle.Value, false)
+		response.Toggles = append(response.Toggles, toProto(toggle))
+	}
+	return response, nil
+}
+
+func (g *toggleService) Evaluate(ctx context.Context, req *yaftv1.EvaluateRequest) (*yaftv1.EvaluateResponse, error) {
+	if len(req.GetKeys()) > maxPageSize {
+		return nil, status.Errorf(codes.InvalidArgument, "Too many keys, expected at most %d", maxPageSize)
+	}
+
+	now := time.Now()
+	client := grpcClientID(ctx)
+	response := &yaftv1.EvaluateResponse{}
+	for _, key := range req.GetKeys() {
+		evaluation := &yaftv1.Evaluation{Key: key}
+		toggle, err := g.server.store.Get(ctx, key)
+		if err == nil {
+			toggle.Value = effectiveValue(toggle, now)
+			g.server.stats.recordRead(toggle.Key, client, toggle.Value, true)
+			evaluation.Value = toggle.Value == "true"
+			evaluation.Found = true
+		} else if !errors.Is(err, store.ErrNotFound) {
+			return nil, g.storeError(ctx, key, err, "Failed to find feature toggle")
+		}
+		response.Evaluations = append(response.Evaluations, evaluation)
+	}
+	return response, nil
+}
+
+func (g *toggleService) CreateToggle(ctx context.Context, req *yaftv1.CreateToggleRequest) (*yaftv1.CreateToggleResponse, error) {
+	in := req.GetToggle()
+	if !startsWithUUID(in.GetKey()) || store.Group(in.GetKey()) == in.GetKey() {
+		return nil, status.Error(codes.InvalidArgument, "Invalid key, expected <group UUID>|<name>, new groups are created over HTTP")
+	}
+	secret, err := g.authorize(ctx, in.GetKey())
+	if err != nil {
+		return nil, err
+	}
+	group := store.Group(in.GetKey())
+	if _, reached := g.server.toggleLimitReached(ctx, group, 1); reached {
+		return nil, status.Error(codes.PermissionDenied, "Toggle limit of the group reached")
+	}
+
+	toggle := store.FeatureToggle{
+		Key:         in.GetKey(),
+		Value:       boolValue(in.GetValue()),
+		ActiveAt:    fromTimestamp(in.GetActiveAt()),
+		DisabledAt:  fromTimestamp(in.GetDisabledAt()),
+		RemoveAt:    fromTimestamp(in.GetRemoveAt()),
+		Tags:        in.GetTags(),
+		Description: in.GetDescription(),
+		Owner:       in.GetOwner(),
+		Kind:        in.GetKind(),
+	}
+	if err := validateToggle(&toggle); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	toggle.Secret = g.server.currentSecret(ctx, group, secret)
+
+	if err := g.server.store.Create(ctx, &toggle); err != nil {
+		return nil, g.storeError(ctx, toggle.Key, err, "Failed to create feature toggle")
+	}
+
+	g.server.log(ctx).WithFields(logrus.Fields{
+		"key":   toggle.Key,
+		"value": toggle.Value,
+	}).Info("Successfully created feature toggle")
+
+	return &yaftv1.CreateToggleResponse{Toggle: toProto(toggle)}, nil
+}
+
+// update loads a toggle, applies the change and saves it
+func (g *toggleService) update(ctx context.Context, key string, change func(toggle *store.FeatureToggle) error) (*yaftv1.Toggle, error) {
+	if _, err := g.authorize(ctx, key); err != nil {
+		return nil, err
+	}
+
+	toggle, err := g.server.store.Get(ctx, key)
+	if err != nil {
+		return nil, g.storeError(ctx, key, err, "Failed to find feature toggle")
+	}
+	if err := change(&toggle); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := g.server.store.Save(ctx, &toggle); err != nil {
+		return nil, g.storeError(ctx, key, err, "Failed to update feature toggle")
+	}
+
+	g.server.log(ctx).WithFields(logrus.Fields{
+		"key":        key,
+		"value":      toggle.Value,
+		"activeAt":   toggle.ActiveAt,
+		"disabledAt": toggle.DisabledAt,
+	}).Info("Successfully updated feature toggle")
+
+	return toProto(toggle), nil
+}
+
+func (g *toggleService) SetValue(ctx context.Context, req *yaftv1.SetValueRequest) (*yaftv1.SetValueResponse, error) {
+	toggle, err := g.update(ctx, req.GetKey(), func(toggle *store.FeatureToggle) error {
+		toggle.Value = boolValue(req.GetValue())
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &yaftv1.SetValueResponse{Toggle: toggle}, nil
+}
+
+func (g *toggleService) SetSchedule(ctx context.Context, req *yaftv1.SetScheduleRequest) (*yaftv1.SetScheduleResponse, error) {
+	toggle, err := g.update(ctx, req.GetKey(), func(toggle *store.FeatureToggle) error {
+		toggle.ActiveAt = fromTimestamp(req.GetActiveAt())
+		toggle.DisabledAt = fromTimestamp(req.GetDisabledAt())
+		return validateSchedule(toggle)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &yaftv1.SetScheduleResponse{Toggle: toggle}, nil
+}
+
+func (g *toggleService) DeleteToggle(ctx context.Context, req *yaftv1.DeleteToggleRequest) (*yaftv1.DeleteToggleResponse, error) {
+	if _, err := g.authorize(ctx, req.GetKey()); err != nil {
+		return nil, err
+	}
+	if err := g.server.store.Delete(ctx, req.GetKey()); err != nil {
+		return nil, g.storeError(ctx, req.GetKey(), err, "Failed to delete feature toggle")
+	}
+
+	g.server.log(ctx).WithFields(logrus.Fields{
+		"key": req.GetKey(),
+	}).Info("Successfully deleted feature toggle")
+
+	return &yaftv1.DeleteToggleResponse{}, nil
+}
+
+// watchState is what a watcher last sent for a toggle, the effective value is
+// part of it so schedules taking effect are sent as changes
+type watchState struct {
+	hash  string
+	value string
+}
+
+func (g *toggleService) Watch(req *yaftv1.WatchRequest, stream grpc.ServerStreamingServer[yaftv1.WatchResponse]) error {
+	ctx := stream.Context()
+	if startsWithUUID(req.GetGroup()) {
+		if err := g.allowGroup(ctx, store.Group(req.GetGroup()), false); err != nil {
+			return err
+		}
+	}
+
+	sent := map[string]watchState{}
+	send := func(toggles []store.FeatureToggle) error {
+		seen := make(map[string]bool, len(toggles))
+		for _, toggle := range toggles {
+			seen[toggle.Key] = true
+			state := watchState{hash: store.HashToggles([]store.FeatureToggle{toggle}), value: toggle.Value}
+			if previous, ok := sent[toggle.Key]; ok && previous == state {
+				continue
+			}
+			if err := stream.Send(&yaftv1.WatchResponse{Type: yaftv1.WatchResponse_TYPE_CHANGED, Toggle: toProto(toggle)}); err != nil {
+				return err
+			}
+			sent[toggle.Key] = state
+		}
+		for key := range sent {
+			if seen[key] {
+				continue
+			}
+			if err := stream.Send(&yaftv1.WatchResponse{Type: yaftv1.WatchResponse_TYPE_DELETED, Toggle: &yaftv1.Toggle{Key: key}}); err != nil {
+				return err
+			}
+			delete(sent, key)
+		}
+		return nil
+	}
+
+	toggles, err := g.list(ctx, req.GetGroup(), req.GetTags())
+	if err != nil {
+		return err
+	}
+	if len(toggles) == 0 {
+		return status.Error(codes.NotFound, "No feature toggles found for provided UUID")
+	}
+	if err := send(toggles); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(g.watchInterval)
+	defer ticker.Stop()
+	failures := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		// Watchers reconnect to another instance while this one drains
+		if g.server.draining.Load() {
+			return status.Error(codes.Unavailable, "Server is shutting down")
+		}
+
+		toggles, err := g.list(ctx, req.GetGroup(), req.GetTags())
+		if err != nil {
+			failures++
+			g.server.log(ctx).WithFields(logrus.Fields{
+				"group":    req.GetGroup(),
+				"failures": failures,
+				"error":    err.Error(),
+			}).Warn("Failed to poll watched group")
+
+			if failures >= maxWatchFailures {
+				return status.Error(codes.Unavailable, "Failed to watch feature toggles")
+			}
+			// The store may recover, the next poll tries again
+			continue
+		}
+		failures = 0
+		if err := send(toggles); err != nil {
+			return err
+		}
+	}
+}
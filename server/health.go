@@ -0,0 +1,74 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"tehwolf.de/tehw0lf/yaft/store"
+)
+
+// readyTimeout bounds the store checks of a readiness probe
+const readyTimeout = 5 * time.Second
+
+// Drain marks the server as shutting down, /readyz reports not-ready from
+// now on so load balancers stop sending new requests
+func (s *Server) Drain() {
+	s.draining.Store(true)
+}
+
+// getHealth reports that the process is alive, it never checks dependencies
+func (s *Server) getHealth(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// getReady reports whether the server should receive traffic: the store is
+// reachable, its schema is migrated, the toggle cache is warmed and the
+// server is not draining
+func (s *Server) getReady(c *gin.Context) {
+	if s.draining.Load() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "draining"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), readyTimeout)
+	defer cancel()
+
+	checks := gin.H{"database": "ok", "migrations": "ok", "cache": "ok"}
+	ready := true
+	fail := func(check string, err error) {
+		ready = false
+		checks[check] = err.Error()
+		s.log(c.Request.Context()).WithFields(logrus.Fields{
+			"method": "GET",
+			"path":   "/readyz",
+			"check":  check,
+			"error":  err.Error(),
+		}).Warn("Readiness check failed")
+	}
+
+	if err := s.store.Ping(ctx); err != nil {
+		fail("database", err)
+	} else if err := s.store.Migrated(ctx); err != nil {
+		fail("migrations", err)
+	}
+
+	// The cache is warmed once, on the first probe that reaches the store
+	if warmer, ok := s.store.(store.Warmer); ok && !s.warmed.Load() {
+		if !ready {
+			checks["cache"] = "not warmed"
+		} else if err := warmer.Warm(ctx); err != nil {
+			fail("cache", err)
+		} else {
+			s.warmed.Store(true)
+		}
+	}
+
+	if !ready {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "unavailable", "checks": checks})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ready", "checks": checks})
+}
@@ -0,0 +1,237 @@
+package server
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"tehwolf.de/tehw0lf/yaft/store"
+)
+
+// maxPageSize caps the limit of a group listing
+const maxPageSize = 1000
+
+// listQuery is a parsed group listing request
+type listQuery struct {
+	filter store.Filter
+	// sort is "key", "created" or "updated"
+	sort       string
+	descending bool
+	// limit is the page size, 0 returns all toggles
+	limit  int
+	cursor *listCursor
+}
+
+// listCursor points behind the last toggle of a page, it stays valid when
+// toggles are added or removed in the meantime
+type listCursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	Key   string `json:"k"`
+}
+
+func encodeCursor(cursor listCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(encoded string) (*listCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, errors.New("Invalid cursor")
+	}
+	var cursor listCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, errors.New("Invalid cursor")
+	}
+	return &cursor, nil
+}
+
+// parseListQuery reads the filters, sort order and page of a group listing
+func parseListQuery(c *gin.Context, key string) (listQuery, error) {
+	query := listQuery{sort: "key"}
+
+	tags, err := store.ParseTagExpr(c.Query("tags"))
+	if err != nil {
+		return query, errors.New("Invalid tags expression")
+	}
+	query.filter = store.Filter{
+		Prefix:  key,
+		TagExpr: tags,
+		Owner:   c.Query("owner"),
+		Kind:    c.Query("kind"),
+		Search:  c.Query("search"),
+		Value:   c.Query("value"),
+	}
+	if prefix, ok := c.GetQuery("prefix"); ok {
+		query.filter.Prefix = store.Group(key) + "|" + prefix
+	}
+
+	switch schedule := store.ScheduleState(c.Query("schedule")); schedule {
+	case "", store.ScheduleScheduled, store.ScheduleActive, store.ScheduleExpired:
+		query.filter.Schedule = schedule
+	default:
+		return query, errors.New("Invalid schedule, expected scheduled, active or expired")
+	}
+
+	if sortBy := c.Query("sort"); sortBy != "" {
+		query.sort = strings.TrimPrefix(sortBy, "-")
+		query.descending = strings.HasPrefix(sortBy, "-")
+		if query.sort != "key" && query.sort != "created" && query.sort != "updated" {
+			return query, errors.New("Invalid sort, expected key, created or updated")
+		}
+	}
+
+	if limit := c.Query("limit"); limit != "" {
+		query.limit, err = strconv.Atoi(limit)
+		if err != nil || query.limit < 1 || query.limit > maxPageSize {
+			return query, errors.New("Invalid limit, expected 1 to " + strconv.Itoa(maxPageSize))
+		}
+	}
+
+	if cursor := c.Query("cursor"); cursor != "" {
+		query.cursor, err = decodeCursor(cursor)
+		if err != nil {
+			return query, err
+		}
+		if query.cursor.Sort != query.sortParam() {
+			return query, errors.New("Cursor does not match the sort order")
+		}
+	}
+	return query, nil
+}
+
+// sortParam returns the sort order in the syntax of the sort parameter
+func (q listQuery) sortParam() string {
+	if q.descending {
+		return "-" + q.sort
+	}
+	return q.sort
+}
+
+// sortValue is the value a toggle is sorted by, timestamps sort as text
+func sortValue(toggle store.FeatureToggle, sortBy string) string {
+	switch sortBy {
+	case "created":
+		return toggle.CreatedAt.UTC().Format("2006-01-02T15:04:05.000000000Z")
+	case "updated":
+		return toggle.UpdatedAt.UTC().Format("2006-01-02T15:04:05.000000000Z")
+	}
+	return toggle.Key
+}
+
+// page sorts the toggles and returns the page after the cursor together with
+// the cursor of the next page, "" on the last page
+func (q listQuery) page(toggles []store.FeatureToggle) ([]store.FeatureToggle, string) {
+	// before reports whether a sorts before b, keys break ties
+	before := func(valueA, keyA, valueB, keyB string) bool {
+		if valueA != valueB {
+			return (valueA < valueB) != q.descending
+		}
+		if keyA != keyB {
+			return (keyA < keyB) != q.descending
+		}
+		return false
+	}
+
+	sort.SliceStable(toggles, func(i, j int) bool {
+		return before(sortValue(toggles[i], q.sort), toggles[i].Key, sortValue(toggles[j], q.sort), toggles[j].Key)
+	})
+
+	start := 0
+	if q.cursor != nil {
+		start = sort.Search(len(toggles), func(i int) bool {
+			return before(q.cursor.Value, q.cursor.Key, sortValue(toggles[i], q.sort), toggles[i].Key)
+		})
+	}
+	toggles = toggles[start:]
+
+	if q.limit == 0 || len(toggles) <= q.limit {
+		return toggles, ""
+	}
+	last := toggles[q.limit-1]
+	return toggles[:q.limit], encodeCursor(listCursor{Sort: q.sortParam(), Value: sortValue(last, q.sort), Key: last.Key})
+}
+
+// listGroup answers a read of a key that is not a toggle with the matching
+// toggles of its group
+func (s *Server) listGroup(c *gin.Context, key string) {
+	query, err := parseListQuery(c, key)
+	if err != nil {
+		s.log(c.Request.Context()).WithFields(logrus.Fields{
+			"method": "GET",
+			"path":   "/features/" + key,
+			"key":    key,
+			"error":  err.Error(),
+		}).Error("Invalid listing parameters, returning 400")
+
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	toggles, err := s.store.List(c.Request.Context(), query.filter)
+	if err != nil {
+		s.log(c.Request.Context()).WithFields(logrus.Fields{
+			"method": "GET",
+			"path":   "/features/" + key,
+			"key":    key,
+			"error":  err.Error(),
+		}).Error("Failed to find feature toggles")
+
+		c.JSON(http.StatusNotFound, gin.H{"error": "No feature toggles found for provided UUID"})
+		return
+	}
+	if len(toggles) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No feature toggles found for provided UUID"})
+		return
+	}
+
+	now := time.Now()
+	total := len(toggles)
+	hash := servedHash(toggles, now)
+	toggles, nextCursor := query.page(toggles)
+
+	s.log(c.Request.Context()).WithFields(logrus.Fields{
+		"method": "GET",
+		"path":   "/features/" + key,
+		"key":    key,
+		"length": len(toggles),
+		"total":  total,
+	}).Info("Returning feature toggles without secrets")
+
+	client := clientID(c)
+	strippedToggles := []FeatureToggleDTO{}
+	for _, obj := range toggles {
+		obj.Value = effectiveValue(obj, now)
+		s.stats.recordRead(obj.Key, client, obj.Value, false)
+		newObj := FeatureToggleDTO{
+			Key:         obj.Key,
+			Value:       obj.Value,
+			ActiveAt:    obj.ActiveAt,
+			DisabledAt:  obj.DisabledAt,
+			RemoveAt:    obj.RemoveAt,
+			Tags:        obj.Tags,
+			Description: obj.Description,
+			Owner:       obj.Owner,
+			Kind:        obj.Kind,
+			CreatedAt:   obj.CreatedAt,
+			UpdatedAt:   obj.UpdatedAt,
+		}
+		strippedToggles = append(strippedToggles, newObj)
+	}
+	if notModified(c, hash) {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"toggles":    strippedToggles,
+		"total":      total,
+		"nextCursor": nextCursor,
+	})
+}
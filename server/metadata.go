@@ -0,0 +1,143 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"tehwolf.de/tehw0lf/yaft/store"
+)
+
+// Kinds of feature toggles, the kind is optional
+const (
+	kindRelease    = "release"
+	kindExperiment = "experiment"
+	kindOps        = "ops"
+	kindPermission = "permission"
+)
+
+var (
+	errInvalidKind  = errors.New("Invalid kind, expected one of release, experiment, ops, permission")
+	errInvalidValue = errors.New("Invalid value, expected \"true\" or \"false\"")
+)
+
+func isValidKind(kind string) bool {
+	switch kind {
+	case "", kindRelease, kindExperiment, kindOps, kindPermission:
+		return true
+	}
+	return false
+}
+
+// validateToggle checks a feature toggle before it is created or updated,
+// a missing value defaults to "false"
+func validateToggle(toggle *store.FeatureToggle) error {
+	if toggle.Value == "" {
+		toggle.Value = "false"
+	}
+	if toggle.Value != "true" && toggle.Value != "false" {
+		return errInvalidValue
+	}
+	if !isValidKind(toggle.Kind) {
+		return errInvalidKind
+	}
+	return validateSchedule(toggle)
+}
+
+// toggleMetadata is the request body for metadata updates, omitted fields are left unchanged
+type toggleMetadata struct {
+	Description *string    `json:"description"`
+	Owner       *string    `json:"owner"`
+	Kind        *string    `json:"kind"`
+	RemoveAt    *time.Time `json:"removeAt"`
+}
+
+func (s *Server) updateMetadata(c *gin.Context) {
+	key := c.Param("key")
+	secret := c.Param("secret")
+
+	if !s.secretsMatch(c.Request.Context(), key, secret) {
+		s.log(c.Request.Context()).WithFields(logrus.Fields{
+			"method": "PUT",
+			"path":   "/features/metadata/" + key,
+			"key":    key,
+		}).Error("Invalid secret, returning 401")
+
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid secret"})
+		return
+	}
+
+	var metadata toggleMetadata
+	if err := c.ShouldBindJSON(&metadata); err != nil {
+		s.log(c.Request.Context()).WithFields(logrus.Fields{
+			"method": "PUT",
+			"path":   "/features/metadata/" + key,
+			"key":    key,
+			"error":  err.Error(),
+		}).Error("Failed to bind JSON for feature toggle metadata")
+
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	s.log(c.Request.Context()).WithFields(logrus.Fields{
+		"method": "PUT",
+		"path":   "/features/metadata/" + key,
+		"key":    key,
+	}).Info("Received request to update feature toggle metadata")
+
+	toggle, err := s.store.Get(c.Request.Context(), key)
+	if err != nil {
+		s.log(c.Request.Context()).WithFields(logrus.Fields{
+			"method": "PUT",
+			"path":   "/features/metadata/" + key,
+			"key":    key,
+			"error":  err.Error(),
+		}).Error("Failed to find feature toggle")
+
+		c.JSON(http.StatusNotFound, gin.H{"error": "Feature not found"})
+		return
+	}
+
+	if metadata.Description != nil {
+		toggle.Description = *metadata.Description
+	}
+	if metadata.Owner != nil {
+		toggle.Owner = *metadata.Owner
+	}
+	if metadata.Kind != nil {
+		toggle.Kind = *metadata.Kind
+	}
+	if metadata.RemoveAt != nil {
+		toggle.RemoveAt = metadata.RemoveAt
+	}
+
+	if err := validateToggle(&toggle); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := s.store.Save(c.Request.Context(), &toggle); err != nil {
+		s.log(c.Request.Context()).WithFields(logrus.Fields{
+			"method": "PUT",
+			"path":   "/features/metadata/" + key,
+			"key":    key,
+			"error":  err.Error(),
+		}).Error("Failed to update feature toggle metadata")
+
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update feature toggle metadata"})
+		return
+	}
+
+	s.log(c.Request.Context()).WithFields(logrus.Fields{
+		"method": "PUT",
+		"path":   "/features/metadata/" + key,
+		"key":    key,
+		"owner":  toggle.Owner,
+		"kind":   toggle.Kind,
+	}).Info("Successfully updated feature toggle metadata")
+
+	c.JSON(http.StatusOK, toggleResponse(toggle))
+}
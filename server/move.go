@@ -0,0 +1,148 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"tehwolf.de/tehw0lf/yaft/store"
+)
+
+// maxAliasDuration caps how long the old key of a moved toggle keeps resolving
+const maxAliasDuration = 90 * 24 * time.Hour
+
+// resolveAlias looks up the toggle an old key was moved to, it returns the
+// error of the original lookup if there is no alias
+func (s *Server) resolveAlias(ctx context.Context, key string) (*store.ToggleAlias, store.FeatureToggle, error) {
+	alias, err := s.store.ResolveAlias(ctx, key)
+	if err != nil {
+		if !errors.Is(err, store.ErrNotFound) {
+			s.log(ctx).WithFields(logrus.Fields{
+				"key":   key,
+				"error": err.Error(),
+			}).Error("Failed to resolve alias")
+		}
+		return nil, store.FeatureToggle{}, store.ErrNotFound
+	}
+
+	toggle, err := s.store.Get(ctx, alias.Target)
+	if err != nil {
+		return nil, store.FeatureToggle{}, err
+	}
+	return &alias, toggle, nil
+}
+
+// setAliasHeaders tells clients reading an old key where the toggle moved
+// and until when the old key resolves
+func (s *Server) setAliasHeaders(c *gin.Context, alias *store.ToggleAlias) {
+	if alias == nil {
+		return
+	}
+	location := s.options.Prefix + "/features/" + alias.Target
+	if s.isV2(c) {
+		location = s.toggleLocation(alias.Target)
+	}
+	c.Header("Deprecation", "@"+strconv.FormatInt(alias.CreatedAt.Unix(), 10))
+	c.Header("Sunset", alias.ExpiresAt.UTC().Format(http.TimeFormat))
+	c.Header("Content-Location", location)
+}
+
+// moveFeature renames a toggle or moves it into another group. Both secrets
+// are required, the target group must exist.
+func (s *Server) moveFeature(c *gin.Context) {
+	key := c.Param("key")
+	secret := c.Param("secret")
+	target := c.Param("target")
+	targetSecret := c.Param("targetsecret")
+	path := "/features/move/" + key + "/" + target
+
+	if !s.secretsMatch(c.Request.Context(), key, secret) {
+		s.log(c.Request.Context()).WithFields(logrus.Fields{
+			"method": "PUT",
+			"path":   path,
+			"key":    key,
+		}).Error("Invalid secret, returning 401")
+
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid secret"})
+		return
+	}
+
+	group, name, _ := strings.Cut(target, "|")
+	if !startsWithUUID(target) || name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid target, expected UUID|name"})
+		return
+	}
+
+	// The target group gets its own lockout, like every route checking its secret
+	if group != store.Group(key) {
+		if !s.allowGroup(c, group, true) {
+			return
+		}
+	}
+	if !s.secretsMatch(c.Request.Context(), group, targetSecret) {
+		s.log(c.Request.Context()).WithFields(logrus.Fields{
+			"method": "PUT",
+			"path":   path,
+			"key":    key,
+			"target": target,
+		}).Error("Invalid target secret, returning 401")
+
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid target secret"})
+		return
+	}
+
+	if group != store.Group(key) && (!s.allowChange(c, group) || !s.allowToggles(c, group, 1)) {
+		return
+	}
+
+	var aliasUntil *time.Time
+	if value := c.Query("alias"); value != "" {
+		duration, err := time.ParseDuration(value)
+		if err != nil || duration <= 0 || duration > maxAliasDuration {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid alias duration, expected a duration up to 2160h"})
+			return
+		}
+		until := time.Now().Add(duration)
+		aliasUntil = &until
+	}
+
+	toggle, err := s.store.Move(c.Request.Context(), key, target, s.currentSecret(c.Request.Context(), group, targetSecret), aliasUntil)
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Feature not found"})
+		return
+	case errors.Is(err, store.ErrConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": "Target feature already exists"})
+		return
+	case err != nil:
+		s.log(c.Request.Context()).WithFields(logrus.Fields{
+			"method": "PUT",
+			"path":   path,
+			"key":    key,
+			"target": target,
+			"error":  err.Error(),
+		}).Error("Failed to move feature toggle")
+
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to move feature toggle"})
+		return
+	}
+
+	s.log(c.Request.Context()).WithFields(logrus.Fields{
+		"method":     "PUT",
+		"path":       path,
+		"key":        key,
+		"target":     target,
+		"aliasUntil": aliasUntil,
+	}).Info("Successfully moved feature toggle")
+
+	response := toggleResponse(toggle)
+	if aliasUntil != nil {
+		response["aliasUntil"] = aliasUntil.UTC()
+	}
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,267 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// openAPIDocument describes every route of the HTTP API, it is served at
+// /openapi.json and drives the request and response validation
+//
+//go:embed openapi.yaml
+var openAPIDocument []byte
+
+// Validation checks requests and responses against the OpenAPI document,
+// routes that are not documented, like the admin UI, are not checked
+type Validation struct {
+	// Requests answers requests that do not match the document with 400
+	Requests bool
+	// Responses logs responses that do not match the document
+	Responses bool
+	// Strict answers responses that do not match the document with 500
+	// instead of logging them, meant for tests
+	Strict bool
+}
+
+// openAPI is the document as served below the prefix of a Server
+type openAPI struct {
+	document *openapi3.T
+	router   routers.Router
+	prefix   string
+	json     []byte
+}
+
+// parseOpenAPI parses and checks the embedded document once, servers share it
+var parseOpenAPI = sync.OnceValues(func() (*openapi3.T, error) {
+	document, err := openapi3.NewLoader().LoadFromData(openAPIDocument)
+	if err != nil {
+		return nil, err
+	}
+	if err := document.Validate(context.Background()); err != nil {
+		return nil, err
+	}
+	return document, nil
+})
+
+// loadOpenAPI returns the document with its server pointed at the prefix of
+// the routes
+func loadOpenAPI(prefix string) (*openAPI, error) {
+	document, err := parseOpenAPI()
+	if err != nil {
+		return nil, err
+	}
+	router, err := gorillamux.NewRouter(document)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(document)
+	if err != nil {
+		return nil, err
+	}
+	var served map[string]json.RawMessage
+	if err := json.Unmarshal(data, &served); err != nil {
+		return nil, err
+	}
+	served["servers"], err = json.Marshal(openapi3.Servers{{URL: prefix + "/"}})
+	if err != nil {
+		return nil, err
+	}
+	if data, err = json.Marshal(served); err != nil {
+		return nil, err
+	}
+	return &openAPI{document: document, router: router, prefix: prefix, json: data}, nil
+}
+
+// findRoute returns the documented route of a request, the document's
+// paths do not include the prefix
+func (o *openAPI) findRoute(r *http.Request) (*routers.Route, map[string]string, error) {
+	if o.prefix != "" {
+		path, found := strings.CutPrefix(r.URL.Path, o.prefix)
+		if !found {
+			return nil, nil, routers.ErrPathNotFound
+		}
+		unprefixed := *r
+		url := *r.URL
+		url.Path = path
+		url.RawPath = strings.TrimPrefix(r.URL.RawPath, o.prefix)
+		unprefixed.URL = &url
+		r = &unprefixed
+	}
+	return o.router.FindRoute(r)
+}
+
+func (s *Server) getOpenAPI(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json; charset=utf-8", s.openAPI.json)
+}
+
+// validate checks requests and responses of documented routes against the
+// OpenAPI document as configured by Options.Validation
+func (s *Server) validate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Unregistered routes, like the admin API without token, are answered
+		// by the router and undocumented ones are not checked
+		if c.FullPath() == "" {
+			c.Next()
+			return
+		}
+		route, params, err := s.openAPI.findRoute(c.Request)
+		if err != nil {
+			c.Next()
+			return
+		}
+
+		input := &openapi3filter.RequestValidationInput{
+			Request:    c.Request,
+			PathParams: params,
+			Route:      route,
+			Options: &openapi3filter.Options{
+				// Secrets and tokens are checked by the handlers
+				AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+			},
+		}
+		if s.options.Validation.Requests {
+			if err := openapi3filter.ValidateRequest(c.Request.Context(), input); err != nil {
+				s.log(c.Request.Context()).WithFields(logrus.Fields{
+					"method": c.Request.Method,
+					"path":   route.Path,
+					"error":  err.Error(),
+				}).Warn("Request does not match the OpenAPI document, returning 400")
+
+				s.fail(c, http.StatusBadRequest, codeInvalidRequest, requestValidationMessage(err))
+				return
+			}
+		}
+		if !s.options.Validation.Responses {
+			c.Next()
+			return
+		}
+
+		writer := &bufferedWriter{ResponseWriter: c.Writer, status: http.StatusOK}
+		c.Writer = writer
+		c.Next()
+		c.Writer = writer.ResponseWriter
+
+		err = openapi3filter.ValidateResponse(c.Request.Context(), &openapi3filter.ResponseValidationInput{
+			RequestValidationInput: input,
+			Status:                 writer.status,
+			Header:                 writer.Header(),
+			Body:                   io.NopCloser(bytes.NewReader(writer.body.Bytes())),
+			Options:                &openapi3filter.Options{IncludeResponseStatus: true},
+		})
+		if err != nil {
+			s.log(c.Request.Context()).WithFields(logrus.Fields{
+				"method": c.Request.Method,
+				"path":   route.Path,
+				"status": writer.status,
+				"error":  err.Error(),
+			}).Error("Response does not match the OpenAPI document")
+
+			if s.options.Validation.Strict {
+				c.Writer.Header().Del("ETag")
+				s.fail(c, http.StatusInternalServerError, codeInternal, "Response does not match the OpenAPI document")
+				return
+			}
+		}
+		writer.flush()
+	}
+}
+
+// requestValidationMessage describes why a request does not match the
+// document without dumping the schema
+func requestValidationMessage(err error) string {
+	var requestErr *openapi3filter.RequestError
+	if !errors.As(err, &requestErr) {
+		return "Invalid request"
+	}
+
+	reason := requestErr.Reason
+	var schemaErr *openapi3.SchemaError
+	if errors.As(requestErr.Err, &schemaErr) {
+		reason = schemaErr.Reason
+		if pointer := schemaErr.JSONPointer(); len(pointer) > 0 {
+			reason = fmt.Sprintf("field %s: %s", strings.Join(pointer, "."), reason)
+		}
+	} else if requestErr.Err != nil {
+		reason = requestErr.Err.Error()
+	}
+
+	switch {
+	case requestErr.Parameter != nil:
+		return fmt.Sprintf("Invalid %s parameter %s: %s", requestErr.Parameter.In, requestErr.Parameter.Name, reason)
+	case requestErr.RequestBody != nil:
+		return "Invalid request body: " + reason
+	}
+	return "Invalid request: " + reason
+}
+
+// bufferedWriter holds back a response until it was validated
+type bufferedWriter struct {
+	gin.ResponseWriter
+	status  int
+	written bool
+	body    bytes.Buffer
+}
+
+func (w *bufferedWriter) WriteHeader(code int) {
+	if code > 0 && !w.written {
+		w.status = code
+	}
+}
+
+func (w *bufferedWriter) WriteHeaderNow() {
+	w.written = true
+}
+
+func (w *bufferedWriter) Write(data []byte) (int, error) {
+	w.written = true
+	return w.body.Write(data)
+}
+
+func (w *bufferedWriter) WriteString(data string) (int, error) {
+	w.written = true
+	return w.body.WriteString(data)
+}
+
+func (w *bufferedWriter) Status() int {
+	return w.status
+}
+
+func (w *bufferedWriter) Size() int {
+	if !w.written {
+		return -1
+	}
+	return w.body.Len()
+}
+
+func (w *bufferedWriter) Written() bool {
+	return w.written
+}
+
+// Flush is a no-op, the response is sent once it was validated
+func (w *bufferedWriter) Flush() {}
+
+// flush sends the held back response
+func (w *bufferedWriter) flush() {
+	w.ResponseWriter.WriteHeader(w.status)
+	if w.body.Len() == 0 {
+		w.ResponseWriter.WriteHeaderNow()
+		return
+	}
+	_, _ = w.ResponseWriter.Write(w.body.Bytes())
+}
@@ -0,0 +1,167 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"tehwolf.de/tehw0lf/yaft/store"
+)
+
+// applyPatch applies a partial feature toggle document in the style of a JSON
+// merge patch: omitted fields are left unchanged, null clears a field. Field
+// names are matched case-insensitively like on creation.
+func applyPatch(toggle *store.FeatureToggle, patch map[string]json.RawMessage) error {
+	for name, raw := range patch {
+		null := bytes.Equal(bytes.TrimSpace(raw), []byte("null"))
+
+		var err error
+		switch strings.ToLower(name) {
+		case "value":
+			if null {
+				return fmt.Errorf("Field %s cannot be null", name)
+			}
+			err = json.Unmarshal(raw, &toggle.Value)
+		case "tags":
+			var tags []string
+			err = json.Unmarshal(raw, &tags)
+			toggle.Tags = tags
+		case "activeat":
+			err = unmarshalTime(raw, &toggle.ActiveAt)
+		case "disabledat":
+			err = unmarshalTime(raw, &toggle.DisabledAt)
+		case "removeat":
+			err = unmarshalTime(raw, &toggle.RemoveAt)
+		case "description":
+			err = unmarshalString(raw, &toggle.Description)
+		case "owner":
+			err = unmarshalString(raw, &toggle.Owner)
+		case "kind":
+			err = unmarshalString(raw, &toggle.Kind)
+		default:
+			return fmt.Errorf("Unknown field %s", name)
+		}
+		if err != nil {
+			return fmt.Errorf("Invalid value for field %s", name)
+		}
+	}
+
+	return validateToggle(toggle)
+}
+
+func unmarshalTime(raw json.RawMessage, target **time.Time) error {
+	var value *time.Time
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return err
+	}
+	*target = value
+	return nil
+}
+
+func unmarshalString(raw json.RawMessage, target *string) error {
+	var value *string
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return err
+	}
+	*target = ""
+	if value != nil {
+		*target = *value
+	}
+	return nil
+}
+
+func (s *Server) patchFeature(c *gin.Context) {
+	key := c.Param("key")
+
+	var patch map[string]json.RawMessage
+	if err := c.ShouldBindJSON(&patch); err != nil {
+		s.log(c.Request.Context()).WithFields(logrus.Fields{
+			"method": "PATCH",
+			"path":   "/features/" + key,
+			"key":    key,
+			"error":  err.Error(),
+		}).Error("Failed to bind JSON for feature toggle patch")
+
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// The secret authorizes the patch, it is not part of it
+	var secret string
+	for name, raw := range patch {
+		if strings.EqualFold(name, "secret") {
+			_ = json.Unmarshal(raw, &secret)
+			delete(patch, name)
+		}
+	}
+
+	if !s.secretsMatch(c.Request.Context(), key, secret) {
+		s.log(c.Request.Context()).WithFields(logrus.Fields{
+			"method": "PATCH",
+			"path":   "/features/" + key,
+			"key":    key,
+		}).Error("Invalid secret, returning 401")
+
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid secret"})
+		return
+	}
+
+	s.log(c.Request.Context()).WithFields(logrus.Fields{
+		"method": "PATCH",
+		"path":   "/features/" + key,
+		"key":    key,
+	}).Info("Received request to patch feature toggle")
+
+	toggle, err := s.store.Get(c.Request.Context(), key)
+	if err != nil {
+		s.log(c.Request.Context()).WithFields(logrus.Fields{
+			"method": "PATCH",
+			"path":   "/features/" + key,
+			"key":    key,
+			"error":  err.Error(),
+		}).Error("Failed to find feature toggle")
+
+		c.JSON(http.StatusNotFound, gin.H{"error": "Feature not found"})
+		return
+	}
+
+	if err := applyPatch(&toggle, patch); err != nil {
+		s.log(c.Request.Context()).WithFields(logrus.Fields{
+			"method": "PATCH",
+			"path":   "/features/" + key,
+			"key":    key,
+			"error":  err.Error(),
+		}).Error("Invalid feature toggle patch, returning 400")
+
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := s.store.Save(c.Request.Context(), &toggle); err != nil {
+		s.log(c.Request.Context()).WithFields(logrus.Fields{
+			"method": "PATCH",
+			"path":   "/features/" + key,
+			"key":    key,
+			"error":  err.Error(),
+		}).Error("Failed to patch feature toggle")
+
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to patch feature toggle"})
+		return
+	}
+
+	s.log(c.Request.Context()).WithFields(logrus.Fields{
+		"method":     "PATCH",
+		"path":       "/features/" + key,
+		"key":        key,
+		"value":      toggle.Value,
+		"activeAt":   toggle.ActiveAt,
+		"disabledAt": toggle.DisabledAt,
+	}).Info("Successfully patched feature toggle")
+
+	c.JSON(http.StatusOK, toggleResponse(toggle))
+}
@@ -0,0 +1,93 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// problemContentType is the media type of RFC 7807 problem details
+const problemContentType = "application/problem+json"
+
+// Codes identify the errors of the v2 API for programs, the detail of a
+// problem is meant for people and may change
+const (
+	codeInvalidRequest       = "invalid_request"
+	codeInvalidQuery         = "invalid_query"
+	codeInvalidName          = "invalid_name"
+	codeInvalidValue         = "invalid_value"
+	codeInvalidKind          = "invalid_kind"
+	codeInvalidSchedule      = "invalid_schedule"
+	codeUnauthorized         = "unauthorized"
+	codeInvalidSecret        = "invalid_secret"
+	codeOriginNotAllowed     = "origin_not_allowed"
+	codeGroupFrozen          = "group_frozen"
+	codeToggleLimitReached   = "toggle_limit_reached"
+	codeAdminTokenRequired   = "admin_token_required"
+	codeInviteRequired       = "invite_required"
+	codeInvalidInvite        = "invalid_invite"
+	codeGroupQuotaExceeded   = "group_quota_exceeded"
+	codeGroupNotFound        = "group_not_found"
+	codeToggleNotFound       = "toggle_not_found"
+	codeRouteNotFound        = "route_not_found"
+	codeToggleExists         = "toggle_exists"
+	codeRateLimited          = "rate_limited"
+	codeLockedOut            = "locked_out"
+	codeGroupCreationLimited = "group_creation_limited"
+	codeInternal             = "internal_error"
+)
+
+// problem is an RFC 7807 problem details body. The type is always
+// about:blank, so the title is the HTTP status text and the code tells errors
+// with the same status apart.
+type problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail"`
+	Instance string `json:"instance"`
+	Code     string `json:"code"`
+}
+
+// isV2 reports whether the request is for the v2 API, including paths below
+// it that match no route
+func (s *Server) isV2(c *gin.Context) bool {
+	return strings.HasPrefix(c.Request.URL.Path, s.options.Prefix+"/v2/")
+}
+
+// fail answers a request with an error and stops the handler chain. Requests
+// to the v2 API are answered with problem details carrying the code, all
+// others with {"error": message}.
+func (s *Server) fail(c *gin.Context, status int, code string, message string) {
+	if !s.isV2(c) {
+		c.AbortWithStatusJSON(status, gin.H{"error": message})
+		return
+	}
+
+	c.Header("Content-Type", problemContentType)
+	c.AbortWithStatusJSON(status, problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   message,
+		Instance: c.Request.URL.Path,
+		Code:     code,
+	})
+}
+
+// validationCode returns the code of an invalid toggle or patch
+func validationCode(err error) string {
+	switch {
+	case errors.Is(err, errInvalidName):
+		return codeInvalidName
+	case errors.Is(err, errInvalidValue):
+		return codeInvalidValue
+	case errors.Is(err, errInvalidKind):
+		return codeInvalidKind
+	case errors.Is(err, errScheduleOrder):
+		return codeInvalidSchedule
+	}
+	return codeInvalidRequest
+}
@@ -0,0 +1,345 @@
+package server
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
+	"tehwolf.de/tehw0lf/yaft/store"
+)
+
+// RateLimit configures request limits and the lockout after failed secret
+// checks. The zero value disables all limits.
+type RateLimit struct {
+	// RequestsPerSecond limits the requests per client IP
+	RequestsPerSecond float64
+	// Burst is how many requests a client may send at once
+	Burst int
+	// GroupRequestsPerSecond limits the requests to the routes of a single group
+	GroupRequestsPerSecond float64
+	GroupBurst             int
+	// GroupCreationsPerHour limits how many groups a client IP may create
+	GroupCreationsPerHour float64
+	GroupCreationBurst    int
+	// Lockout locks a group after repeated invalid secrets
+	Lockout Lockout
+	// Limiter keeps the counters, defaults to an in-process limiter. Replicas
+	// can share a Limiter to enforce the limits across all instances.
+	Limiter Limiter
+}
+
+// Lockout locks a group for Duration after Failures invalid secrets in a row.
+// Every further failure doubles the lockout, up to MaxDuration. Failures are
+// forgotten after a valid secret or after MaxDuration without failures.
+type Lockout struct {
+	Failures    int
+	Duration    time.Duration
+	MaxDuration time.Duration
+}
+
+// Limiter counts requests and failed secret checks per key
+type Limiter interface {
+	// Allow takes a request of key from a token bucket with the given rate
+	// per second and burst. It returns 0 if the request is allowed, otherwise
+	// how long the client has to wait.
+	Allow(ctx context.Context, key string, perSecond float64, burst int) (time.Duration, error)
+	// LockedOut returns how long key is still locked out
+	LockedOut(ctx context.Context, key string) (time.Duration, error)
+	// Fail records a failure of key and returns the lockout now in effect
+	Fail(ctx context.Context, key string, policy Lockout) (time.Duration, error)
+	// Succeed forgets the failures of key
+	Succeed(ctx context.Context, key string) error
+}
+
+// Counters idle this long are forgotten
+const limiterIdle = 5 * time.Minute
+
+type bucket struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+type failures struct {
+	count       int
+	lastFailure time.Time
+	lockedUntil time.Time
+	// forget is how long failures are remembered
+	forget time.Duration
+}
+
+// memoryLimiter is the in-process Limiter
+type memoryLimiter struct {
+	mu        sync.Mutex
+	now       func() time.Time
+	buckets   map[string]*bucket
+	failures  map[string]*failures
+	lastSweep time.Time
+}
+
+// NewMemoryLimiter returns a Limiter that keeps its counters in process memory
+func NewMemoryLimiter() Limiter {
+	return newMemoryLimiter(time.Now)
+}
+
+func newMemoryLimiter(now func() time.Time) *memoryLimiter {
+	return &memoryLimiter{
+		now:       now,
+		buckets:   make(map[string]*bucket),
+		failures:  make(map[string]*failures),
+		lastSweep: now(),
+	}
+}
+
+// sweep forgets idle counters, the lock must be held
+func (l *memoryLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < limiterIdle {
+		return
+	}
+	for key, b := range l.buckets {
+		if now.Sub(b.lastSeen) > limiterIdle {
+			delete(l.buckets, key)
+		}
+	}
+	for key, f := range l.failures {
+		if now.After(f.lockedUntil) && now.Sub(f.lastFailure) > f.forget {
+			delete(l.failures, key)
+		}
+	}
+	l.lastSweep = now
+}
+
+func (l *memoryLimiter) Allow(ctx context.Context, key string, perSecond float64, burst int) (time.Duration, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{limiter: rate.NewLimiter(rate.Limit(perSecond), burst)}
+		l.buckets[key] = b
+	}
+	b.lastSeen = now
+
+	reservation := b.limiter.ReserveN(now, 1)
+	if !reservation.OK() {
+		return time.Duration(float64(time.Second) / perSecond), nil
+	}
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		reservation.CancelAt(now)
+		return delay, nil
+	}
+	return 0, nil
+}
+
+func (l *memoryLimiter) LockedOut(ctx context.Context, key string) (time.Duration, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	f, ok := l.failures[key]
+	if !ok {
+		return 0, nil
+	}
+	if remaining := f.lockedUntil.Sub(l.now()); remaining > 0 {
+		return remaining, nil
+	}
+	return 0, nil
+}
+
+func (l *memoryLimiter) Fail(ctx context.Context, key string, policy Lockout) (time.Duration, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	f, ok := l.failures[key]
+	if !ok || (now.After(f.lockedUntil) && now.Sub(f.lastFailure) > policy.MaxDuration) {
+		f = &failures{}
+		l.failures[key] = f
+	}
+	f.count++
+	f.lastFailure = now
+	f.forget = policy.MaxDuration
+
+	lockout := lockoutDuration(policy, f.count)
+	if lockout > 0 {
+		f.lockedUntil = now.Add(lockout)
+	}
+	return lockout, nil
+}
+
+func (l *memoryLimiter) Succeed(ctx context.Context, key string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.failures, key)
+	return nil
+}
+
+// lockoutDuration doubles the lockout with every failure beyond the threshold
+func lockoutDuration(policy Lockout, count int) time.Duration {
+	if policy.Failures <= 0 || count < policy.Failures {
+		return 0
+	}
+	lockout := float64(policy.Duration) * math.Pow(2, float64(count-policy.Failures))
+	if lockout > float64(policy.MaxDuration) {
+		return policy.MaxDuration
+	}
+	return time.Duration(lockout)
+}
+
+// tooManyRequests rejects a request with 429 and a Retry-After header in whole seconds
+func (s *Server) tooManyRequests(c *gin.Context, retryAfter time.Duration, code string, message string) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+
+	s.log(c.Request.Context()).WithFields(logrus.Fields{
+		"method":     c.Request.Method,
+		"path":       c.Request.URL.Path,
+		"client":     c.ClientIP(),
+		"retryAfter": seconds,
+	}).Warn(message + ", returning 429")
+
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	s.fail(c, http.StatusTooManyRequests, code, message)
+}
+
+// allow checks a token bucket, limiter errors are logged and let the request pass
+func (s *Server) allow(c *gin.Context, key string, perSecond float64, burst int) time.Duration {
+	return s.allowKey(c.Request.Context(), key, perSecond, burst)
+}
+
+// allowKey checks a token bucket outside of an HTTP request, e.g. for gRPC calls
+func (s *Server) allowKey(ctx context.Context, key string, perSecond float64, burst int) time.Duration {
+	if perSecond <= 0 {
+		return 0
+	}
+	wait, err := s.options.RateLimit.Limiter.Allow(ctx, key, perSecond, burst)
+	if err != nil {
+		s.log(ctx).WithFields(logrus.Fields{
+			"key":   key,
+			"error": err.Error(),
+		}).Error("Failed to check rate limit")
+		return 0
+	}
+	return wait
+}
+
+// allowGroup applies the group rate limit and, for routes checking the
+// secret, the lockout. It responds with 429 and returns false if the request
+// must not proceed.
+func (s *Server) allowGroup(c *gin.Context, group string, checksSecret bool) bool {
+	limits := s.options.RateLimit
+
+	if wait := s.allow(c, "group:"+group, limits.GroupRequestsPerSecond, limits.GroupBurst); wait > 0 {
+		s.tooManyRequests(c, wait, codeRateLimited, "Too many requests")
+		return false
+	}
+
+	if !checksSecret {
+		return true
+	}
+	if locked := s.lockedOut(c.Request.Context(), group); locked > 0 {
+		s.tooManyRequests(c, locked, codeLockedOut, "Too many invalid secrets")
+		return false
+	}
+	return true
+}
+
+// lockedOut returns how long the group stays locked after invalid secrets,
+// limiter errors are logged and do not lock
+func (s *Server) lockedOut(ctx context.Context, group string) time.Duration {
+	limits := s.options.RateLimit
+	if limits.Lockout.Failures <= 0 {
+		return 0
+	}
+	locked, err := limits.Limiter.LockedOut(ctx, "lockout:"+group)
+	if err != nil {
+		s.log(ctx).WithFields(logrus.Fields{
+			"group": group,
+			"error": err.Error(),
+		}).Error("Failed to check lockout")
+		return 0
+	}
+	return locked
+}
+
+// allowGroupCreation limits the groups a client IP may create
+func (s *Server) allowGroupCreation(c *gin.Context) bool {
+	limits := s.options.RateLimit
+	perSecond := limits.GroupCreationsPerHour / time.Hour.Seconds()
+
+	if wait := s.allow(c, "create:"+c.ClientIP(), perSecond, limits.GroupCreationBurst); wait > 0 {
+		s.tooManyRequests(c, wait, codeGroupCreationLimited, "Too many new groups")
+		return false
+	}
+	return true
+}
+
+// recordSecretCheck counts invalid secrets towards the lockout of the group
+func (s *Server) recordSecretCheck(ctx context.Context, group string, match bool) {
+	limits := s.options.RateLimit
+	if limits.Lockout.Failures <= 0 {
+		return
+	}
+
+	var err error
+	if match {
+		err = limits.Limiter.Succeed(ctx, "lockout:"+group)
+	} else {
+		var lockout time.Duration
+		lockout, err = limits.Limiter.Fail(ctx, "lockout:"+group, limits.Lockout)
+		if err == nil && lockout > 0 {
+			s.log(ctx).WithFields(logrus.Fields{
+				"group":   group,
+				"lockout": lockout.String(),
+			}).Warn("Too many invalid secrets, locking group")
+		}
+	}
+	if err != nil {
+		s.log(ctx).WithFields(logrus.Fields{
+			"group": group,
+			"error": err.Error(),
+		}).Error("Failed to record secret check")
+	}
+}
+
+// routeGroup returns the group addressed by the key or uuid parameter of the route
+func routeGroup(c *gin.Context) string {
+	if key := c.Param("key"); startsWithUUID(key) {
+		return store.Group(key)
+	}
+	return c.Param("uuid")
+}
+
+// rateLimit applies the client IP limit to every request and the group
+// limits to requests for routes of a group
+func (s *Server) rateLimit() gin.HandlerFunc {
+	limits := s.options.RateLimit
+
+	return func(c *gin.Context) {
+		if wait := s.allow(c, "ip:"+c.ClientIP(), limits.RequestsPerSecond, limits.Burst); wait > 0 {
+			s.tooManyRequests(c, wait, codeRateLimited, "Too many requests")
+			return
+		}
+
+		group := routeGroup(c)
+
+		// Routes without secret only read, they are never locked. Changes through
+		// the v2 API send the secret in a header.
+		checksSecret := c.Param("secret") != "" || c.Param("oldsecret") != "" || c.Request.Method == http.MethodPatch ||
+			(s.isV2(c) && c.Request.Method != http.MethodGet)
+		if group != "" && !s.allowGroup(c, group, checksSecret) {
+			return
+		}
+		c.Next()
+	}
+}
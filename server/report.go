@@ -0,0 +1,286 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"tehwolf.de/tehw0lf/yaft/store"
+)
+
+// Default staleness thresholds of the stale toggle report
+const (
+	defaultUnchangedDays = 90
+	defaultUnreadDays    = 30
+)
+
+// Reasons a toggle is listed in the stale toggle report
+const (
+	staleUnchanged = "unchanged"
+	staleUnread    = "unread"
+	staleExpired   = "expired"
+	stalePermanent = "permanent"
+)
+
+// staleCriteria selects which toggles are reported as stale, a zero day count
+// or a false flag disables the respective criterion
+type staleCriteria struct {
+	UnchangedDays int
+	UnreadDays    int
+	Expired       bool
+	Permanent     bool
+}
+
+type StaleToggle struct {
+	Key        string     `json:"key"`
+	Value      string     `json:"value"`
+	UpdatedAt  time.Time  `json:"updatedAt"`
+	LastReadAt *time.Time `json:"lastReadAt"`
+	DisabledAt *time.Time `json:"disabledAt"`
+	RemoveAt   *time.Time `json:"removeAt"`
+	Reasons    []string   `json:"reasons"`
+}
+
+func daysBefore(now time.Time, days int) time.Time {
+	return now.Add(-time.Duration(days) * 24 * time.Hour)
+}
+
+// staleReasons lists why a toggle is considered stale, it is empty for toggles in active use
+func staleReasons(toggle store.FeatureToggle, lastReadAt *time.Time, criteria staleCriteria, now time.Time) []string {
+	var reasons []string
+
+	unchanged := criteria.UnchangedDays > 0 && toggle.UpdatedAt.Before(daysBefore(now, criteria.UnchangedDays))
+	if unchanged {
+		reasons = append(reasons, staleUnchanged)
+	}
+
+	if criteria.UnreadDays > 0 && (lastReadAt == nil || lastReadAt.Before(daysBefore(now, criteria.UnreadDays))) {
+		reasons = append(reasons, staleUnread)
+	}
+
+	if criteria.Expired &&
+		((toggle.RemoveAt != nil && !toggle.RemoveAt.After(now)) ||
+			(toggle.DisabledAt != nil && !toggle.DisabledAt.After(now))) {
+		reasons = append(reasons, staleExpired)
+	}
+
+	// Toggles that are on without any pending deactivation no longer toggle anything
+	if criteria.Permanent && toggle.Value == "true" && toggle.DisabledAt == nil &&
+		(criteria.UnchangedDays == 0 || unchanged) {
+		reasons = append(reasons, stalePermanent)
+	}
+
+	return reasons
+}
+
+func parseStaleCriteria(c *gin.Context) (staleCriteria, error) {
+	criteria := staleCriteria{
+		UnchangedDays: defaultUnchangedDays,
+		UnreadDays:    defaultUnreadDays,
+		Expired:       true,
+		Permanent:     true,
+	}
+
+	var err error
+	if value, ok := c.GetQuery("unchangedDays"); ok {
+		if criteria.UnchangedDays, err = strconv.Atoi(value); err != nil || criteria.UnchangedDays < 0 {
+			return criteria, errInvalidQuery("unchangedDays")
+		}
+	}
+	if value, ok := c.GetQuery("unreadDays"); ok {
+		if criteria.UnreadDays, err = strconv.Atoi(value); err != nil || criteria.UnreadDays < 0 {
+			return criteria, errInvalidQuery("unreadDays")
+		}
+	}
+	if value, ok := c.GetQuery("expired"); ok {
+		if criteria.Expired, err = strconv.ParseBool(value); err != nil {
+			return criteria, errInvalidQuery("expired")
+		}
+	}
+	if value, ok := c.GetQuery("permanent"); ok {
+		if criteria.Permanent, err = strconv.ParseBool(value); err != nil {
+			return criteria, errInvalidQuery("permanent")
+		}
+	}
+
+	return criteria, nil
+}
+
+func errInvalidQuery(name string) error {
+	return fmt.Errorf("Invalid value for query parameter %s", name)
+}
+
+// setDeprecationHeaders announces the planned removal of a toggle to reading clients
+func setDeprecationHeaders(c *gin.Context, toggle store.FeatureToggle) {
+	if toggle.RemoveAt == nil {
+		return
+	}
+	c.Header("Deprecation", "@"+strconv.FormatInt(toggle.RemoveAt.Unix(), 10))
+	c.Header("Sunset", toggle.RemoveAt.UTC().Format(http.TimeFormat))
+}
+
+func (s *Server) getStaleReport(c *gin.Context) {
+	uuid := c.Param("uuid")
+	secret := c.Param("secret")
+
+	if !s.secretsMatch(c.Request.Context(), uuid, secret) {
+		s.log(c.Request.Context()).WithFields(logrus.Fields{
+			"method": "GET",
+			"path":   "/report/stale/" + uuid,
+			"uuid":   uuid,
+		}).Error("Invalid secret, returning 401")
+
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid secret"})
+		return
+	}
+
+	criteria, err := parseStaleCriteria(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	s.log(c.Request.Context()).WithFields(logrus.Fields{
+		"method":        "GET",
+		"path":          "/report/stale/" + uuid,
+		"uuid":          uuid,
+		"unchangedDays": criteria.UnchangedDays,
+		"unreadDays":    criteria.UnreadDays,
+		"expired":       criteria.Expired,
+		"permanent":     criteria.Permanent,
+	}).Info("Received GET request for stale toggle report")
+
+	ctx := c.Request.Context()
+	var toggles []store.FeatureToggle
+	var rows []store.ToggleStat
+	err = s.stats.flush(ctx)
+	if err == nil {
+		toggles, err = s.store.List(ctx, store.Filter{Prefix: uuid})
+	}
+	if err == nil {
+		rows, err = s.store.ListStats(ctx, uuid)
+	}
+	if err != nil {
+		s.log(c.Request.Context()).WithFields(logrus.Fields{
+			"method": "GET",
+			"path":   "/report/stale/" + uuid,
+			"uuid":   uuid,
+			"error":  err.Error(),
+		}).Error("Failed to load stale toggle report")
+
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load stale toggle report"})
+		return
+	}
+
+	lastReads := make(map[string]*time.Time, len(rows))
+	for _, row := range rows {
+		lastReads[row.Key] = row.LastReadAt
+	}
+
+	now := time.Now()
+	result := []StaleToggle{}
+	for _, toggle := range toggles {
+		reasons := staleReasons(toggle, lastReads[toggle.Key], criteria, now)
+		if len(reasons) == 0 {
+			continue
+		}
+		result = append(result, StaleToggle{
+			Key:        toggle.Key,
+			Value:      toggle.Value,
+			UpdatedAt:  toggle.UpdatedAt,
+			LastReadAt: lastReads[toggle.Key],
+			DisabledAt: toggle.DisabledAt,
+			RemoveAt:   toggle.RemoveAt,
+			Reasons:    reasons,
+		})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Key < result[j].Key })
+
+	s.log(c.Request.Context()).WithFields(logrus.Fields{
+		"method": "GET",
+		"path":   "/report/stale/" + uuid,
+		"uuid":   uuid,
+		"length": len(result),
+	}).Info("Returning stale toggle report")
+
+	c.JSON(http.StatusOK, gin.H{
+		"toggles": result,
+	})
+}
+
+func (s *Server) setRemoveAt(c *gin.Context) {
+	key := c.Param("key")
+	date := c.Param("date")
+	secret := c.Param("secret")
+
+	if !s.secretsMatch(c.Request.Context(), key, secret) {
+		s.log(c.Request.Context()).WithFields(logrus.Fields{
+			"method": "PUT",
+			"path":   "/features/removeAt/" + key + "/" + date,
+			"key":    key,
+		}).Error("Invalid secret, returning 401")
+
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid secret"})
+		return
+	}
+
+	removeAt, err := parseScheduleDate(date, c.Query("tz"))
+	if err != nil {
+		s.log(c.Request.Context()).WithFields(logrus.Fields{
+			"method": "PUT",
+			"path":   "/features/removeAt/" + key + "/" + date,
+			"key":    key,
+			"error":  err.Error(),
+		}).Error("Invalid removal date, returning 400")
+
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	s.log(c.Request.Context()).WithFields(logrus.Fields{
+		"method": "PUT",
+		"path":   "/features/removeAt/" + key + "/" + date,
+		"key":    key,
+	}).Info("Received request to mark feature toggle for removal")
+
+	toggle, err := s.store.Get(c.Request.Context(), key)
+	if err != nil {
+		s.log(c.Request.Context()).WithFields(logrus.Fields{
+			"method": "PUT",
+			"path":   "/features/removeAt/" + key + "/" + date,
+			"key":    key,
+			"error":  err.Error(),
+		}).Error("Failed to find feature toggle")
+
+		c.JSON(http.StatusNotFound, gin.H{"error": "Feature not found"})
+		return
+	}
+
+	toggle.RemoveAt = &removeAt
+
+	if err := s.store.Save(c.Request.Context(), &toggle); err != nil {
+		s.log(c.Request.Context()).WithFields(logrus.Fields{
+			"method":   "PUT",
+			"path":     "/features/removeAt/" + key + "/" + date,
+			"key":      key,
+			"removeAt": toggle.RemoveAt,
+			"error":    err.Error(),
+		}).Error("Failed to mark feature toggle for removal")
+
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to mark feature toggle for removal"})
+		return
+	}
+
+	s.log(c.Request.Context()).WithFields(logrus.Fields{
+		"method":   "PUT",
+		"path":     "/features/removeAt/" + key + "/" + date,
+		"key":      key,
+		"removeAt": toggle.RemoveAt,
+	}).Info("Successfully set feature toggle removeAt")
+
+	c.JSON(http.StatusOK, toggleResponse(toggle))
+}
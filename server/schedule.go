@@ -0,0 +1,126 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"tehwolf.de/tehw0lf/yaft/store"
+)
+
+// Accepted schedule formats besides RFC3339, they are interpreted in the requested time zone
+const (
+	scheduleDateTimeLayout = "2006-01-02T15:04:05"
+	scheduleDateLayout     = "2006-01-02"
+)
+
+var (
+	errInvalidDate     = errors.New("Invalid date, expected RFC3339 timestamp, date-time without offset or date")
+	errInvalidTimeZone = errors.New("Invalid time zone, expected IANA time zone name")
+	errScheduleOrder   = errors.New("Invalid schedule, activation must be before deactivation")
+)
+
+// parseScheduleDate parses a schedule date. RFC3339 timestamps carry their
+// own offset, date-times without offset and dates are interpreted in the
+// given IANA time zone, which defaults to UTC.
+func parseScheduleDate(date string, timeZone string) (time.Time, error) {
+	location := time.UTC
+	if timeZone != "" {
+		var err error
+		if location, err = time.LoadLocation(timeZone); err != nil {
+			return time.Time{}, errInvalidTimeZone
+		}
+	}
+
+	if parsed, err := time.Parse(time.RFC3339, date); err == nil {
+		return parsed, nil
+	}
+	for _, layout := range []string{scheduleDateTimeLayout, scheduleDateLayout} {
+		if parsed, err := time.ParseInLocation(layout, date, location); err == nil {
+			return parsed, nil
+		}
+	}
+	return time.Time{}, errInvalidDate
+}
+
+// validateSchedule rejects toggles that would be activated after they are deactivated
+func validateSchedule(toggle *store.FeatureToggle) error {
+	if toggle.ActiveAt != nil && toggle.DisabledAt != nil && !toggle.ActiveAt.Before(*toggle.DisabledAt) {
+		return errScheduleOrder
+	}
+	return nil
+}
+
+// effectiveValue evaluates the schedule of a toggle at the given time, so
+// reads do not depend on when the scheduled database update last ran
+func effectiveValue(toggle store.FeatureToggle, now time.Time) string {
+	if toggle.DisabledAt != nil && !now.Before(*toggle.DisabledAt) {
+		return "false"
+	}
+	if toggle.ActiveAt != nil && !now.Before(*toggle.ActiveAt) {
+		return "true"
+	}
+	return toggle.Value
+}
+
+// clearSchedule returns a handler removing one schedule date of a toggle
+func (s *Server) clearSchedule(route string, clear func(toggle *store.FeatureToggle)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.Param("key")
+		secret := c.Param("secret")
+
+		if !s.secretsMatch(c.Request.Context(), key, secret) {
+			s.log(c.Request.Context()).WithFields(logrus.Fields{
+				"method": "DELETE",
+				"path":   "/features/" + route + "/" + key,
+				"key":    key,
+			}).Error("Invalid secret, returning 401")
+
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid secret"})
+			return
+		}
+
+		s.log(c.Request.Context()).WithFields(logrus.Fields{
+			"method": "DELETE",
+			"path":   "/features/" + route + "/" + key,
+			"key":    key,
+		}).Info("Received request to clear feature toggle " + route)
+
+		toggle, err := s.store.Get(c.Request.Context(), key)
+		if err != nil {
+			s.log(c.Request.Context()).WithFields(logrus.Fields{
+				"method": "DELETE",
+				"path":   "/features/" + route + "/" + key,
+				"key":    key,
+				"error":  err.Error(),
+			}).Error("Failed to find feature toggle")
+
+			c.JSON(http.StatusNotFound, gin.H{"error": "Feature not found"})
+			return
+		}
+
+		clear(&toggle)
+
+		if err := s.store.Save(c.Request.Context(), &toggle); err != nil {
+			s.log(c.Request.Context()).WithFields(logrus.Fields{
+				"method": "DELETE",
+				"path":   "/features/" + route + "/" + key,
+				"key":    key,
+				"error":  err.Error(),
+			}).Error("Failed to clear feature toggle " + route)
+
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clear feature toggle " + route})
+			return
+		}
+
+		s.log(c.Request.Context()).WithFields(logrus.Fields{
+			"method": "DELETE",
+			"path":   "/features/" + route + "/" + key,
+			"key":    key,
+		}).Info("Successfully cleared feature toggle " + route)
+
+		c.JSON(http.StatusOK, toggleResponse(toggle))
+	}
+}
@@ -0,0 +1,208 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"tehwolf.de/tehw0lf/yaft/store"
+)
+
+const (
+	// minSecretLength and minSecretCharacters reject weak client-chosen secrets,
+	// generated secrets have 108 characters of 17 different ones
+	minSecretLength     = 32
+	minSecretCharacters = 10
+	// defaultMaxRotationOverlap caps the overlap if SecretRotation.MaxOverlap is not set
+	defaultMaxRotationOverlap = 30 * 24 * time.Hour
+)
+
+var errWeakSecret = errors.New("New secret is too weak, it needs at least 32 characters of 10 different ones")
+
+// SecretRotation controls how long the previous secret of a group stays
+// valid after a rotation. The zero value invalidates it at once unless the
+// rotation asks for an overlap.
+type SecretRotation struct {
+	// Overlap is used for rotations that do not ask for an overlap
+	Overlap time.Duration
+	// MaxOverlap is the longest overlap a rotation may ask for, defaults to 30 days
+	MaxOverlap time.Duration
+}
+
+// checkNewSecret rejects client-chosen secrets that are weak, reused or cannot be part of a URL
+func checkNewSecret(secret string, oldSecret string) error {
+	if !isURLParseable(secret) {
+		return errors.New("New secret is not URL parseable, aborting operation")
+	}
+	if secret == oldSecret {
+		return errors.New("New secret must differ from the old secret")
+	}
+	characters := map[rune]bool{}
+	for _, character := range secret {
+		characters[character] = true
+	}
+	if len(secret) < minSecretLength || len(characters) < minSecretCharacters {
+		return errWeakSecret
+	}
+	return nil
+}
+
+// currentSecret returns the current secret of the group for new or moved
+// toggles, so a previous secret accepted during a rotation is not stored
+func (s *Server) currentSecret(ctx context.Context, group string, secret string) string {
+	current, err := s.store.GroupSecret(ctx, group)
+	if err != nil {
+		return secret
+	}
+	return current
+}
+
+// currentSecretMatches is secretsMatch without the previous secret. The routes
+// managing the secret only accept the current one, otherwise a leaked
+// previous secret could take the group over during the overlap.
+func (s *Server) currentSecretMatches(ctx context.Context, key string, secret string) bool {
+	current, err := s.store.GroupSecret(ctx, store.Group(key))
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		s.log(ctx).WithFields(logrus.Fields{
+			"key":   key,
+			"error": err.Error(),
+		}).Error("Failed to check secret")
+		return false
+	}
+	match := err == nil && subtle.ConstantTimeCompare([]byte(secret), []byte(current)) == 1
+	s.recordSecretCheck(ctx, store.Group(key), match)
+	return match
+}
+
+// rotateSecret replaces the secret of a group while the old secret stays
+// valid for the overlap given by "overlap" in the query. Without newsecret
+// the server generates the secret.
+func (s *Server) rotateSecret(c *gin.Context) {
+	uuid := c.Param("uuid")
+	secret := c.Param("secret")
+	newSecret := c.Param("newsecret")
+	path := "/secret/rotate/" + uuid
+
+	if !startsWithUUID(uuid) || !s.currentSecretMatches(c.Request.Context(), uuid, secret) {
+		s.log(c.Request.Context()).WithFields(logrus.Fields{
+			"method": "PUT",
+			"path":   path,
+			"uuid":   uuid,
+		}).Error("Invalid secret, returning 401")
+
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid secret"})
+		return
+	}
+
+	generated := newSecret == ""
+	if generated {
+		newSecret = generateSecret()
+	} else if err := checkNewSecret(newSecret, secret); err != nil {
+		s.log(c.Request.Context()).WithFields(logrus.Fields{
+			"method": "PUT",
+			"path":   path,
+			"uuid":   uuid,
+			"error":  err.Error(),
+		}).Error("New secret rejected, aborting operation")
+
+		c.JSON(http.StatusNotAcceptable, gin.H{"error": err.Error()})
+		return
+	}
+
+	overlap := s.options.SecretRotation.Overlap
+	if value := c.Query("overlap"); value != "" {
+		var err error
+		overlap, err = time.ParseDuration(value)
+		if err != nil || overlap < 0 || overlap > s.options.SecretRotation.MaxOverlap {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid overlap, expected a duration up to " + s.options.SecretRotation.MaxOverlap.String()})
+			return
+		}
+	}
+
+	s.log(c.Request.Context()).WithFields(logrus.Fields{
+		"method":  "PUT",
+		"path":    path,
+		"uuid":    uuid,
+		"overlap": overlap.String(),
+	}).Info("Received request to rotate secret")
+
+	rotation, err := s.store.RotateSecret(c.Request.Context(), uuid, newSecret, time.Now().Add(overlap))
+	if err != nil {
+		s.log(c.Request.Context()).WithFields(logrus.Fields{
+			"method": "PUT",
+			"path":   path,
+			"uuid":   uuid,
+			"error":  err.Error(),
+		}).Error("Failed to rotate secret")
+
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to rotate secret"})
+		return
+	}
+
+	s.log(c.Request.Context()).WithFields(logrus.Fields{
+		"method": "PUT",
+		"path":   path,
+		"uuid":   uuid,
+	}).Info("Successfully rotated secret")
+
+	response := gin.H{
+		"key":                     uuid,
+		"previousSecretExpiresAt": rotation.ExpiresAt.UTC(),
+	}
+	if generated {
+		response["secret"] = newSecret
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// getSecretStatus reports the last rotation of a group and when the
+// previous secret was last used
+func (s *Server) getSecretStatus(c *gin.Context) {
+	uuid := c.Param("uuid")
+	secret := c.Param("secret")
+
+	if !startsWithUUID(uuid) || !s.currentSecretMatches(c.Request.Context(), uuid, secret) {
+		s.log(c.Request.Context()).WithFields(logrus.Fields{
+			"method": "GET",
+			"path":   "/secret/status/" + uuid,
+			"uuid":   uuid,
+		}).Error("Invalid secret, returning 401")
+
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid secret"})
+		return
+	}
+
+	rotation, err := s.store.GetSecretRotation(c.Request.Context(), uuid)
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "The secret was not rotated"})
+		return
+	}
+	if err != nil {
+		s.log(c.Request.Context()).WithFields(logrus.Fields{
+			"method": "GET",
+			"path":   "/secret/status/" + uuid,
+			"uuid":   uuid,
+			"error":  err.Error(),
+		}).Error("Failed to get secret rotation")
+
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get secret rotation"})
+		return
+	}
+
+	var lastUsedAt *time.Time
+	if rotation.LastUsedAt != nil {
+		utc := rotation.LastUsedAt.UTC()
+		lastUsedAt = &utc
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"key":                      uuid,
+		"rotatedAt":                rotation.RotatedAt.UTC(),
+		"previousSecretExpiresAt":  rotation.ExpiresAt.UTC(),
+		"previousSecretValid":      rotation.ExpiresAt.After(time.Now()),
+		"previousSecretLastUsedAt": lastUsedAt,
+	})
+}
@@ -0,0 +1,923 @@
+// Package server implements the YaFT HTTP API on top of a store, so it can be
+// served standalone or mounted inside another application.
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"tehwolf.de/tehw0lf/yaft/store"
+)
+
+type FeatureToggleDTO struct {
+	Key         string
+	Value       string
+	ActiveAt    *time.Time
+	DisabledAt  *time.Time
+	RemoveAt    *time.Time
+	Tags        pq.StringArray
+	Description string
+	Owner       string
+	Kind        string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// toggleResponse is the JSON representation of a single feature toggle, without its secret
+func toggleResponse(toggle store.FeatureToggle) gin.H {
+	return gin.H{
+		"key":         toggle.Key,
+		"value":       toggle.Value,
+		"activeAt":    toggle.ActiveAt,
+		"disabledAt":  toggle.DisabledAt,
+		"removeAt":    toggle.RemoveAt,
+		"tags":        toggle.Tags,
+		"description": toggle.Description,
+		"owner":       toggle.Owner,
+		"kind":        toggle.Kind,
+		"createdAt":   toggle.CreatedAt,
+		"updatedAt":   toggle.UpdatedAt,
+	}
+}
+
+// Options configure a Server, the zero value serves all routes at the root
+// with the default logger and CORS policy
+type Options struct {
+	// Prefix is prepended to every route, e.g. "/toggles"
+	Prefix string
+	// Logger receives the request logs, defaults to the standard logrus logger
+	Logger logrus.FieldLogger
+	// CORS decides which browser origins may call the API, defaults to
+	// same-origin requests only
+	CORS *CORSPolicy
+	// Auth is called before every request, returning an error rejects the request
+	Auth Authenticator
+	// RateLimit limits the requests per client IP, disabled by default
+	RateLimit RateLimit
+	// TrustedProxies are the IPs or CIDR ranges of reverse proxies whose
+	// X-Forwarded-For header is used as client IP. None by default, so the
+	// client IP is the address of the connection.
+	TrustedProxies []string
+	// StatsFlushInterval is how often read statistics are written to the store
+	StatsFlushInterval time.Duration
+	// SecretRotation controls how long rotated secrets stay valid
+	SecretRotation SecretRotation
+	// AdminToken enables the admin API, requests authenticate with it as bearer token
+	AdminToken string
+	// UI serves the web admin UI at /ui
+	UI bool
+	// Validation checks requests and responses against the OpenAPI document,
+	// disabled by default
+	Validation Validation
+	// Admission decides who may create new groups, defaults to the open policy
+	Admission Admission
+	// TracerProvider creates the request spans, defaults to the global provider
+	TracerProvider trace.TracerProvider
+	// Propagator reads the trace context of incoming requests, defaults to
+	// W3C trace context and baggage
+	Propagator propagation.TextMapPropagator
+}
+
+// Authenticator guards the API in addition to the per group secrets
+type Authenticator func(r *http.Request) error
+
+// Server serves the HTTP API from whichever store it was built with
+type Server struct {
+	store   store.Store
+	stats   *statsRecorder
+	logger  logrus.FieldLogger
+	options Options
+	handler http.Handler
+	// routes are the registered routes, used to answer CORS preflight requests
+	routes  []route
+	openAPI *openAPI
+	done    chan struct{}
+	stopped chan struct{}
+	// draining is set once shutdown started, warmed once the toggle cache is loaded
+	draining atomic.Bool
+	warmed   atomic.Bool
+}
+
+// NewServer registers all routes and starts flushing read statistics in the
+// background. Close stops flushing, the store is left open.
+func NewServer(st store.Store, options Options) *Server {
+	if options.Logger == nil {
+		options.Logger = logrus.StandardLogger()
+	}
+	if options.CORS == nil {
+		options.CORS = &CORSPolicy{}
+	}
+	if options.CORS.AllowCredentials && slices.Contains(options.CORS.AllowedOrigins, "*") {
+		panic("CORS credentials cannot be allowed for every origin")
+	}
+	if options.StatsFlushInterval <= 0 {
+		options.StatsFlushInterval = statsFlushInterval
+	}
+	if options.RateLimit.Limiter == nil {
+		options.RateLimit.Limiter = NewMemoryLimiter()
+	}
+	if options.RateLimit.Burst < 1 {
+		options.RateLimit.Burst = 1
+	}
+	if options.RateLimit.GroupBurst < 1 {
+		options.RateLimit.GroupBurst = 1
+	}
+	if options.RateLimit.GroupCreationBurst < 1 {
+		options.RateLimit.GroupCreationBurst = 1
+	}
+	if options.SecretRotation.MaxOverlap <= 0 {
+		options.SecretRotation.MaxOverlap = defaultMaxRotationOverlap
+	}
+	if options.Admission.Policy == "" {
+		options.Admission.Policy = AdmissionOpen
+	}
+	if options.TracerProvider == nil {
+		options.TracerProvider = otel.GetTracerProvider()
+	}
+	if options.Propagator == nil {
+		options.Propagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+	}
+	options.Prefix = strings.TrimSuffix(options.Prefix, "/")
+
+	// The document is embedded, it can only fail to load after a broken edit
+	document, err := loadOpenAPI(options.Prefix)
+	if err != nil {
+		panic("invalid OpenAPI document: " + err.Error())
+	}
+
+	s := &Server{
+		store:   st,
+		stats:   newStatsRecorder(st),
+		logger:  options.Logger,
+		options: options,
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+		openAPI: document,
+	}
+	s.handler = s.router()
+
+	go s.flushStats(options.StatsFlushInterval)
+
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.handler.ServeHTTP(w, r)
+}
+
+// Close stops the background flushing and writes pending read statistics
+func (s *Server) Close() error {
+	select {
+	case <-s.done:
+		return nil
+	default:
+	}
+	close(s.done)
+	<-s.stopped
+
+	return s.stats.flush(context.Background())
+}
+
+// authenticate rejects requests the configured Authenticator does not accept
+func (s *Server) authenticate(c *gin.Context) {
+	if err := s.options.Auth(c.Request); err != nil {
+		s.log(c.Request.Context()).WithFields(logrus.Fields{
+			"method": c.Request.Method,
+			"path":   c.Request.URL.Path,
+			"error":  err.Error(),
+		}).Error("Request not authenticated, returning 401")
+
+		s.fail(c, http.StatusUnauthorized, codeUnauthorized, "Unauthorized")
+		return
+	}
+	c.Next()
+}
+
+func (s *Server) prependUUID(ctx context.Context, key string) (string, error) {
+	for {
+		newUUID := uuid.New().String()
+		exists, err := s.store.GroupExists(ctx, newUUID)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return newUUID + "|" + key, nil
+		}
+	}
+}
+
+func startsWithUUID(key string) bool {
+	firstPart := strings.Split(key, "|")[0]
+	_, err := uuid.Parse(firstPart)
+	return err == nil
+}
+
+func isURLParseable(secret string) bool {
+	_, err := url.ParseRequestURI("https://example.com/" + secret)
+	return err == nil
+}
+
+func generateSecret() string {
+	return uuid.New().String() + uuid.New().String() + uuid.New().String()
+}
+
+func (s *Server) secretsMatch(ctx context.Context, key string, secret string) bool {
+	match, err := s.store.SecretsMatch(ctx, store.Group(key), secret)
+	if err != nil {
+		s.log(ctx).WithFields(logrus.Fields{
+			"key":   key,
+			"error": err.Error(),
+		}).Error("Failed to check secret")
+		return false
+	}
+	s.recordSecretCheck(ctx, store.Group(key), match)
+	return match
+}
+
+func (s *Server) router() *gin.Engine {
+	router := gin.New()
+	// Rate limits and admission are keyed by client IP, a forwarded header
+	// from anyone but a trusted proxy would let clients pick their own
+	if err := router.SetTrustedProxies(s.options.TrustedProxies); err != nil {
+		panic("invalid trusted proxies: " + err.Error())
+	}
+	router.Use(gin.Recovery())
+	router.Use(s.requestID(), s.trace(), s.accessLog())
+	router.Use(s.cors())
+	if s.options.Validation.Requests || s.options.Validation.Responses {
+		router.Use(s.validate())
+	}
+
+	// Probes are neither rate limited nor authenticated
+	router.GET(s.options.Prefix+"/healthz", s.getHealth)
+	router.GET(s.options.Prefix+"/readyz", s.getReady)
+	router.GET(s.options.Prefix+"/openapi.json", s.getOpenAPI)
+
+	// The UI files are static, its API calls are limited like those of any other client
+	if s.options.UI {
+		s.uiRoutes(router)
+	}
+
+	routes := router.Group(s.options.Prefix)
+	routes.Use(s.rateLimit())
+	if s.options.Auth != nil {
+		routes.Use(s.authenticate)
+	}
+	routes.Use(s.rejectFrozen)
+
+	routes.GET("/collectionHash/:key", func(c *gin.Context) {
+		key := c.Param("key")
+		s.log(c.Request.Context()).WithFields(logrus.Fields{
+			"method": "GET",
+			"path":   "/collectionHash/" + key,
+			"key":    key,
+		}).Info("Received GET request for collectionHash")
+
+		if !startsWithUUID(key) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Feature not found"})
+			return
+		}
+
+		// The hash covers exactly the toggles a listing with the same filters returns
+		query, err := parseListQuery(c, key)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		toggles, err := s.store.List(c.Request.Context(), query.filter)
+		if err == nil && len(toggles) == 0 {
+			err = store.ErrNotFound
+		}
+		if err != nil {
+			s.log(c.Request.Context()).WithFields(logrus.Fields{
+				"method": "GET",
+				"path":   "/collectionHash/" + key,
+				"key":    key,
+				"error":  err.Error(),
+			}).Error("Failed to calculate collection hash")
+
+			c.JSON(http.StatusNotFound, gin.H{"error": "Failed to calculate collection hash for provided UUID"})
+			return
+		}
+		collectionHash := servedHash(toggles, time.Now())
+
+		s.log(c.Request.Context()).WithFields(logrus.Fields{
+			"method":         "GET",
+			"path":           "/collectionHash/" + key,
+			"collectionHash": collectionHash,
+		}).Info("Returning collectionHash")
+
+		c.JSON(http.StatusOK, gin.H{
+			"collectionHash": collectionHash,
+		})
+	})
+
+	routes.GET("/features/:key", func(c *gin.Context) {
+		key := c.Param("key")
+		s.log(c.Request.Context()).WithFields(logrus.Fields{
+			"method": "GET",
+			"path":   "/features/" + key,
+			"key":    key,
+		}).Info("Received GET request for feature toggle")
+
+		toggle, err := s.store.Get(c.Request.Context(), key)
+		var alias *store.ToggleAlias
+		if errors.Is(err, store.ErrNotFound) && strings.Contains(key, "|") {
+			alias, toggle, err = s.resolveAlias(c.Request.Context(), key)
+		}
+		if err != nil {
+			if !startsWithUUID(key) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Feature not found"})
+				return
+			}
+
+			s.listGroup(c, key)
+		} else {
+			toggle.Value = effectiveValue(toggle, time.Now())
+
+			s.log(c.Request.Context()).WithFields(logrus.Fields{
+				"method":     "GET",
+				"path":       "/features/" + key,
+				"key":        key,
+				"value":      toggle.Value,
+				"activeAt":   toggle.ActiveAt,
+				"disabledAt": toggle.DisabledAt,
+			}).Info("Returning feature toggle value without secret")
+
+			s.stats.recordRead(toggle.Key, clientID(c), toggle.Value, true)
+			setDeprecationHeaders(c, toggle)
+			s.setAliasHeaders(c, alias)
+			if notModified(c, store.HashToggles([]store.FeatureToggle{toggle})) {
+				return
+			}
+
+			c.JSON(http.StatusOK, toggleResponse(toggle))
+		}
+	})
+
+	routes.POST("/features", func(c *gin.Context) {
+		var newToggle store.FeatureToggle
+		var secret string = ""
+		if err := c.ShouldBindJSON(&newToggle); err != nil {
+			s.log(c.Request.Context()).WithFields(logrus.Fields{
+				"method": "POST",
+				"path":   "/features",
+				"error":  err.Error(),
+			}).Error("Failed to bind JSON for new feature toggle")
+
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := validateToggle(&newToggle); err != nil {
+			s.log(c.Request.Context()).WithFields(logrus.Fields{
+				"method": "POST",
+				"path":   "/features",
+				"key":    newToggle.Key,
+				"error":  err.Error(),
+			}).Error("Invalid feature toggle, returning 400")
+
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// IDs and timestamps are maintained by the store
+		newToggle.ID = 0
+		newToggle.CreatedAt = time.Time{}
+		newToggle.UpdatedAt = time.Time{}
+
+		var admission *admittedGroup
+		if !startsWithUUID(newToggle.Key) {
+			admitted, ok := s.admitGroup(c)
+			if !ok {
+				return
+			}
+			admission = &admitted
+
+			key, err := s.prependUUID(c.Request.Context(), newToggle.Key)
+			if err != nil {
+				s.releaseAdmission(c, admitted)
+				s.log(c.Request.Context()).WithFields(logrus.Fields{
+					"method": "POST",
+					"path":   "/features",
+					"key":    newToggle.Key,
+					"error":  err.Error(),
+				}).Error("Failed to create feature toggle group")
+
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create feature toggle"})
+				return
+			}
+			newToggle.Key = key
+			secret = generateSecret()
+			newToggle.Secret = secret
+		} else {
+			if !s.allowGroup(c, store.Group(newToggle.Key), true) {
+				return
+			}
+
+			if !s.secretsMatch(c.Request.Context(), newToggle.Key, newToggle.Secret) {
+				s.log(c.Request.Context()).WithFields(logrus.Fields{
+					"method": "POST",
+					"path":   "/features",
+					"key":    newToggle.Key,
+				}).Error("Invalid secret, returning 401")
+
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid secret"})
+				return
+			}
+			if !s.allowChange(c, store.Group(newToggle.Key)) || !s.allowToggles(c, store.Group(newToggle.Key), 1) {
+				return
+			}
+			newToggle.Secret = s.currentSecret(c.Request.Context(), store.Group(newToggle.Key), newToggle.Secret)
+		}
+
+		if err := s.store.Create(c.Request.Context(), &newToggle); err != nil {
+			s.log(c.Request.Context()).WithFields(logrus.Fields{
+				"method": "POST",
+				"path":   "/features",
+				"key":    newToggle.Key,
+				"value":  newToggle.Value,
+				"error":  err.Error(),
+			}).Error("Failed to create feature toggle")
+
+			if admission != nil {
+				s.releaseAdmission(c, *admission)
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create feature toggle"})
+			return
+		}
+
+		if admission != nil {
+			s.saveAdmission(c, store.Group(newToggle.Key), *admission)
+		}
+
+		s.log(c.Request.Context()).WithFields(logrus.Fields{
+			"method":   "POST",
+			"path":     "/features",
+			"key":      newToggle.Key,
+			"value":    newToggle.Value,
+			"activeAt": newToggle.ActiveAt,
+		}).Info("Successfully created feature toggle")
+
+		response := toggleResponse(newToggle)
+		if secret != "" {
+			response["secret"] = secret
+		}
+		c.JSON(http.StatusCreated, response)
+	})
+
+	routes.PUT("/features/activate/:key/:secret", func(c *gin.Context) {
+		key := c.Param("key")
+		secret := c.Param("secret")
+
+		if !s.secretsMatch(c.Request.Context(), key, secret) {
+			s.log(c.Request.Context()).WithFields(logrus.Fields{
+				"method": "PUT",
+				"path":   "/features/activate/" + key,
+				"key":    key,
+			}).Error("Invalid secret, returning 401")
+
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid secret"})
+			return
+		}
+
+		s.log(c.Request.Context()).WithFields(logrus.Fields{
+			"method": "PUT",
+			"path":   "/features/activate/" + key,
+			"key":    key,
+		}).Info("Received request to activate feature toggle")
+
+		toggle, err := s.store.Get(c.Request.Context(), key)
+		if err != nil {
+			s.log(c.Request.Context()).WithFields(logrus.Fields{
+				"method": "PUT",
+				"path":   "/features/activate/" + key,
+				"key":    key,
+				"error":  err.Error(),
+			}).Error("Failed to find feature toggle")
+
+			c.JSON(http.StatusNotFound, gin.H{"error": "Feature not found"})
+			return
+		}
+
+		toggle.Value = "true"
+
+		if err := s.store.Save(c.Request.Context(), &toggle); err != nil {
+			s.log(c.Request.Context()).WithFields(logrus.Fields{
+				"method":   "PUT",
+				"path":     "/features/activate/" + key,
+				"key":      key,
+				"activeAt": toggle.ActiveAt,
+				"error":    err.Error(),
+			}).Error("Failed to activate feature toggle")
+
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to activate feature toggle"})
+			return
+		}
+
+		s.log(c.Request.Context()).WithFields(logrus.Fields{
+			"method":   "PUT",
+			"path":     "/features/activate/" + key,
+			"key":      key,
+			"activeAt": toggle.ActiveAt,
+		}).Info("Successfully activated feature toggle")
+
+		c.JSON(http.StatusOK, toggleResponse(toggle))
+	})
+
+	routes.PUT("/features/activateAt/:key/:date/:secret", func(c *gin.Context) {
+		key := c.Param("key")
+		date := c.Param("date")
+		secret := c.Param("secret")
+
+		if !s.secretsMatch(c.Request.Context(), key, secret) {
+			s.log(c.Request.Context()).WithFields(logrus.Fields{
+				"method": "PUT",
+				"path":   "/features/activateAt/" + key + "/" + date,
+				"key":    key,
+			}).Error("Invalid secret, returning 401")
+
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid secret"})
+			return
+		}
+
+		activeAt, err := parseScheduleDate(date, c.Query("tz"))
+		if err != nil {
+			s.log(c.Request.Context()).WithFields(logrus.Fields{
+				"method": "PUT",
+				"path":   "/features/activateAt/" + key + "/" + date,
+				"key":    key,
+				"error":  err.Error(),
+			}).Error("Invalid schedule date, returning 400")
+
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		s.log(c.Request.Context()).WithFields(logrus.Fields{
+			"method": "PUT",
+			"path":   "/features/activateAt/" + key + "/" + date,
+			"key":    key,
+		}).Info("Received request to activate feature toggle at")
+
+		toggle, err := s.store.Get(c.Request.Context(), key)
+		if err != nil {
+			s.log(c.Request.Context()).WithFields(logrus.Fields{
+				"method": "PUT",
+				"path":   "/features/activateAt/" + key + "/" + date,
+				"key":    key,
+				"error":  err.Error(),
+			}).Error("Failed to find feature toggle")
+
+			c.JSON(http.StatusNotFound, gin.H{"error": "Feature not found"})
+			return
+		}
+
+		toggle.ActiveAt = &activeAt
+
+		if err := validateSchedule(&toggle); err != nil {
+			s.log(c.Request.Context()).WithFields(logrus.Fields{
+				"method": "PUT",
+				"path":   "/features/activateAt/" + key + "/" + date,
+				"key":    key,
+				"error":  err.Error(),
+			}).Error("Invalid schedule, returning 400")
+
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := s.store.Save(c.Request.Context(), &toggle); err != nil {
+			s.log(c.Request.Context()).WithFields(logrus.Fields{
+				"method":   "PUT",
+				"path":     "/features/activateAt/" + key + "/" + date,
+				"key":      key,
+				"activeAt": toggle.ActiveAt,
+				"error":    err.Error(),
+			}).Error("Failed to activate feature toggle at")
+
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to activate feature toggle at"})
+			return
+		}
+
+		s.log(c.Request.Context()).WithFields(logrus.Fields{
+			"method":   "PUT",
+			"path":     "/features/activateAt/" + key + "/" + date,
+			"key":      key,
+			"activeAt": toggle.ActiveAt,
+		}).Info("Successfully set feature toggle activeAt")
+
+		c.JSON(http.StatusOK, toggleResponse(toggle))
+	})
+
+	routes.PUT("/features/deactivate/:key/:secret", func(c *gin.Context) {
+		key := c.Param("key")
+		secret := c.Param("secret")
+
+		if !s.secretsMatch(c.Request.Context(), key, secret) {
+			s.log(c.Request.Context()).WithFields(logrus.Fields{
+				"method": "PUT",
+				"path":   "/features/deactivate/" + key,
+				"key":    key,
+			}).Error("Invalid secret, returning 401")
+
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid secret"})
+			return
+		}
+
+		s.log(c.Request.Context()).WithFields(logrus.Fields{
+			"method": "PUT",
+			"path":   "/features/deactivate/" + key,
+			"key":    key,
+		}).Info("Received request to deactivate feature toggle")
+
+		toggle, err := s.store.Get(c.Request.Context(), key)
+		if err != nil {
+			s.log(c.Request.Context()).WithFields(logrus.Fields{
+				"method": "PUT",
+				"path":   "/features/deactivate/" + key,
+				"key":    key,
+				"error":  err.Error(),
+			}).Error("Failed to find feature toggle")
+
+			c.JSON(http.StatusNotFound, gin.H{"error": "Feature not found"})
+			return
+		}
+
+		toggle.Value = "false"
+
+		if err := s.store.Save(c.Request.Context(), &toggle); err != nil {
+			s.log(c.Request.Context()).WithFields(logrus.Fields{
+				"method":     "PUT",
+				"path":       "/features/deactivate/" + key,
+				"key":        key,
+				"disabledAt": toggle.DisabledAt,
+				"error":      err.Error(),
+			}).Error("Failed to deactivate feature toggle")
+
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to deactivate feature toggle"})
+			return
+		}
+
+		s.log(c.Request.Context()).WithFields(logrus.Fields{
+			"method":     "PUT",
+			"path":       "/features/deactivate/" + key,
+			"key":        key,
+			"disabledAt": toggle.DisabledAt,
+		}).Info("Successfully deactivated feature toggle")
+
+		c.JSON(http.StatusOK, toggleResponse(toggle))
+	})
+
+	routes.PUT("/features/deactivateAt/:key/:date/:secret", func(c *gin.Context) {
+		key := c.Param("key")
+		date := c.Param("date")
+		secret := c.Param("secret")
+
+		if !s.secretsMatch(c.Request.Context(), key, secret) {
+			s.log(c.Request.Context()).WithFields(logrus.Fields{
+				"method": "PUT",
+				"path":   "/features/deactivateAt/" + key + "/" + date,
+				"key":    key,
+			}).Error("Invalid secret, returning 401")
+
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid secret"})
+			return
+		}
+
+		disabledAt, err := parseScheduleDate(date, c.Query("tz"))
+		if err != nil {
+			s.log(c.Request.Context()).WithFields(logrus.Fields{
+				"method": "PUT",
+				"path":   "/features/deactivateAt/" + key + "/" + date,
+				"key":    key,
+				"error":  err.Error(),
+			}).Error("Invalid schedule date, returning 400")
+
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		s.log(c.Request.Context()).WithFields(logrus.Fields{
+			"method": "PUT",
+			"path":   "/features/deactivateAt/" + key + "/" + date,
+			"key":    key,
+		}).Info("Received request to activate feature toggle at")
+
+		toggle, err := s.store.Get(c.Request.Context(), key)
+		if err != nil {
+			s.log(c.Request.Context()).WithFields(logrus.Fields{
+				"method": "PUT",
+				"path":   "/features/deactivateAt/" + key + "/" + date,
+				"key":    key,
+				"error":  err.Error(),
+			}).Error("Failed to find feature toggle")
+
+			c.JSON(http.StatusNotFound, gin.H{"error": "Feature not found"})
+			return
+		}
+
+		toggle.DisabledAt = &disabledAt
+
+		if err := validateSchedule(&toggle); err != nil {
+			s.log(c.Request.Context()).WithFields(logrus.Fields{
+				"method": "PUT",
+				"path":   "/features/deactivateAt/" + key + "/" + date,
+				"key":    key,
+				"error":  err.Error(),
+			}).Error("Invalid schedule, returning 400")
+
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := s.store.Save(c.Request.Context(), &toggle); err != nil {
+			s.log(c.Request.Context()).WithFields(logrus.Fields{
+				"method":     "PUT",
+				"path":       "/features/deactivateAt/" + key + "/" + date,
+				"key":        key,
+				"disabledAt": toggle.DisabledAt,
+				"error":      err.Error(),
+			}).Error("Failed to deactivate feature toggle at")
+
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to deactivate feature toggle at"})
+			return
+		}
+
+		s.log(c.Request.Context()).WithFields(logrus.Fields{
+			"method":     "PUT",
+			"path":       "/features/deactivateAt/" + key + "/" + date,
+			"key":        key,
+			"disabledAt": toggle.DisabledAt,
+		}).Info("Successfully set feature toggle disabledAt")
+
+		c.JSON(http.StatusOK, toggleResponse(toggle))
+	})
+
+	routes.DELETE("/features/:key/:secret", func(c *gin.Context) {
+		key := c.Param("key")
+		secret := c.Param("secret")
+
+		if !s.secretsMatch(c.Request.Context(), key, secret) {
+			s.log(c.Request.Context()).WithFields(logrus.Fields{
+				"method": "DELETE",
+				"path":   "/features/" + key,
+				"key":    key,
+			}).Error("Invalid secret, returning 401")
+
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid secret"})
+			return
+		}
+
+		s.log(c.Request.Context()).WithFields(logrus.Fields{
+			"method": "DELETE",
+			"path":   "/features/" + key,
+			"key":    key,
+		}).Info("Received request to delete feature toggle")
+
+		if err := s.store.Delete(c.Request.Context(), key); err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				s.log(c.Request.Context()).WithFields(logrus.Fields{
+					"method": "DELETE",
+					"path":   "/features/" + key,
+					"key":    key,
+					"error":  err.Error(),
+				}).Error("Failed to find feature toggle")
+
+				c.JSON(http.StatusNotFound, gin.H{"error": "Feature not found"})
+				return
+			}
+
+			s.log(c.Request.Context()).WithFields(logrus.Fields{
+				"method": "DELETE",
+				"path":   "/features/" + key,
+				"key":    key,
+				"error":  err.Error(),
+			}).Error("Failed to delete feature toggle")
+
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete feature toggle"})
+			return
+		}
+
+		s.log(c.Request.Context()).WithFields(logrus.Fields{
+			"method": "DELETE",
+			"path":   "/features/" + key,
+			"key":    key,
+		}).Info("Successfully deleted feature toggle")
+
+		c.JSON(http.StatusOK, gin.H{"message": "Feature toggle deleted"})
+	})
+
+	routes.PUT("/secret/update/:uuid/:oldsecret/:newsecret", func(c *gin.Context) {
+		uuid := c.Param("uuid")
+		oldSecret := c.Param("oldsecret")
+		newSecret := c.Param("newsecret")
+
+		if !s.currentSecretMatches(c.Request.Context(), uuid, oldSecret) {
+			s.log(c.Request.Context()).WithFields(logrus.Fields{
+				"method": "PUT",
+				"path":   "/secret/update/" + uuid,
+				"uuid":   uuid,
+			}).Error("Invalid secret, returning 401")
+
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid secret"})
+			return
+		}
+
+		if err := checkNewSecret(newSecret, oldSecret); err != nil {
+			s.log(c.Request.Context()).WithFields(logrus.Fields{
+				"method": "PUT",
+				"path":   "/secret/update/" + uuid,
+				"uuid":   uuid,
+				"error":  err.Error(),
+			}).Error("New secret rejected, aborting operation")
+
+			c.JSON(http.StatusNotAcceptable, gin.H{"error": err.Error()})
+			return
+		}
+
+		s.log(c.Request.Context()).WithFields(logrus.Fields{
+			"method": "PUT",
+			"path":   "/secret/update/" + uuid,
+			"uuid":   uuid,
+		}).Info("Received request to update secret")
+
+		if err := s.store.UpdateSecret(c.Request.Context(), uuid, newSecret); err != nil {
+			s.log(c.Request.Context()).WithFields(logrus.Fields{
+				"method": "PUT",
+				"path":   "/secret/update/" + uuid,
+				"error":  err.Error(),
+			}).Error("Failed to update secret")
+
+			c.JSON(http.StatusNotFound, gin.H{"error": "Failed to update secret"})
+			return
+		}
+
+		s.log(c.Request.Context()).WithFields(logrus.Fields{
+			"method": "PUT",
+			"path":   "/secret/update/" + uuid,
+			"key":    uuid,
+		}).Info("Successfully updated secret")
+
+		c.JSON(http.StatusOK, gin.H{
+			"key": uuid,
+		})
+	})
+
+	routes.PUT("/secret/rotate/:uuid/:secret", s.rotateSecret)
+	routes.PUT("/secret/rotate/:uuid/:secret/:newsecret", s.rotateSecret)
+	routes.GET("/secret/status/:uuid/:secret", s.getSecretStatus)
+
+	routes.DELETE("/features/activateAt/:key/:secret", s.clearSchedule("activateAt", func(toggle *store.FeatureToggle) {
+		toggle.ActiveAt = nil
+	}))
+	routes.DELETE("/features/deactivateAt/:key/:secret", s.clearSchedule("deactivateAt", func(toggle *store.FeatureToggle) {
+		toggle.DisabledAt = nil
+	}))
+
+	routes.PUT("/features/removeAt/:key/:date/:secret", s.setRemoveAt)
+	routes.PUT("/features/metadata/:key/:secret", s.updateMetadata)
+	routes.PATCH("/features/:key", s.patchFeature)
+	routes.PUT("/features/move/:key/:secret/:target/:targetsecret", s.moveFeature)
+
+	routes.PUT("/bulk/activate/:uuid/:secret", s.bulkUpdate("activate", setValue("true")))
+	routes.PUT("/bulk/deactivate/:uuid/:secret", s.bulkUpdate("deactivate", setValue("false")))
+	routes.PUT("/bulk/activateAt/:uuid/:date/:secret", s.bulkUpdate("activateAt", setSchedule(func(toggle *store.FeatureToggle, date time.Time) {
+		toggle.ActiveAt = &date
+	})))
+	routes.PUT("/bulk/deactivateAt/:uuid/:date/:secret", s.bulkUpdate("deactivateAt", setSchedule(func(toggle *store.FeatureToggle, date time.Time) {
+		toggle.DisabledAt = &date
+	})))
+	routes.PUT("/bulk/tags/:uuid/:secret", s.bulkUpdate("tags", updateTags))
+	routes.POST("/clone/:uuid/:secret", s.cloneGroup)
+
+	routes.GET("/stats/:uuid/:secret", s.getStats)
+	routes.GET("/report/stale/:uuid/:secret", s.getStaleReport)
+
+	routes.GET("/cors/:uuid/:secret", s.getGroupCORS)
+	routes.PUT("/cors/:uuid/:secret", s.updateGroupCORS)
+
+	s.v2Routes(routes)
+	router.NoRoute(s.notFoundV2)
+
+	if s.options.AdminToken != "" {
+		s.adminRoutes(router)
+	}
+
+	s.routes = collectRoutes(router.Routes())
+
+	return router
+}
@@ -0,0 +1,2872 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	yaftv1 "tehwolf.de/tehw0lf/yaft/proto/yaft/v1"
+	"tehwolf.de/tehw0lf/yaft/store"
+)
+
+// Test server setup with an in-memory SQLite store
+// strictValidation fails responses that drift from the OpenAPI document with 500
+var strictValidation = Validation{Responses: true, Strict: true}
+
+func setupTestServer(t testing.TB) *Server {
+	gin.SetMode(gin.TestMode)
+
+	testStore, err := store.NewSQLite(":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { testStore.Close() })
+
+	srv := NewServer(testStore, Options{Validation: strictValidation})
+	t.Cleanup(func() { srv.Close() })
+
+	return srv
+}
+
+// Test router setup, serving the same routes as NewServer
+func setupTestRouter(srv *Server) http.Handler {
+	return srv
+}
+
+// Unit Tests for Utility Functions
+func TestPrependUUID(t *testing.T) {
+	srv := setupTestServer(t)
+	
+	tests := []struct {
+		name string
+		key  string
+	}{
+		{"simple key", "myfeature"},
+		{"key with spaces", "my feature"},
+		{"key with special chars", "my-feature_123"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := srv.prependUUID(context.Background(), tt.key)
+			require.NoError(t, err)
+			
+			parts := strings.Split(result, "|")
+			assert.Len(t, parts, 2)
+			assert.Equal(t, tt.key, parts[1])
+			
+			// Verify UUID is valid
+			_, err = uuid.Parse(parts[0])
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestStartsWithUUID(t *testing.T) {
+	tests := []struct {
+		name     string
+		key      string
+		expected bool
+	}{
+		{"valid UUID prefix", "550e8400-e29b-41d4-a716-446655440000|myfeature", true},
+		{"invalid UUID prefix", "not-a-uuid|myfeature", false},
+		{"no UUID prefix", "myfeature", false},
+		{"empty string", "", false},
+		{"only UUID", "550e8400-e29b-41d4-a716-446655440000", true}, // This actually returns true because it's a valid UUID
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := startsWithUUID(tt.key)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestIsURLParseable(t *testing.T) {
+	tests := []struct {
+		name     string
+		secret   string
+		expected bool
+	}{
+		{"valid secret", "abc123-def456-ghi789", true},
+		{"valid UUID secret", "550e8400-e29b-41d4-a716-446655440000", true},
+		{"invalid chars", "secret with spaces", true}, // URL parsing is more permissive than expected
+		{"invalid chars special", "secret%with%percent", false},
+		{"empty string", "", true},
+		{"only alphanumeric", "abc123", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := isURLParseable(tt.secret)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret1 := generateSecret()
+	secret2 := generateSecret()
+	
+	// Should be different each time
+	assert.NotEqual(t, secret1, secret2)
+	
+	// Should be 3 UUIDs concatenated (3 * 36 = 108 chars)
+	assert.Len(t, secret1, 108)
+	assert.Len(t, secret2, 108)
+	
+	// Should be URL parseable
+	assert.True(t, isURLParseable(secret1))
+	assert.True(t, isURLParseable(secret2))
+}
+
+func TestSecretsMatch(t *testing.T) {
+	srv := setupTestServer(t)
+	
+	// Create test data
+	testUUID := uuid.New().String()
+	testSecret := "test-secret-123"
+	
+	toggle1 := store.FeatureToggle{
+		Key:    testUUID + "|feature1",
+		Value:  "true",
+		Secret: testSecret,
+	}
+	toggle2 := store.FeatureToggle{
+		Key:    testUUID + "|feature2", 
+		Value:  "false",
+		Secret: testSecret,
+	}
+	
+	err := srv.store.Create(context.Background(), &toggle1)
+	require.NoError(t, err)
+	err = srv.store.Create(context.Background(), &toggle2)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		key      string
+		secret   string
+		expected bool
+	}{
+		{"valid secret for feature1", toggle1.Key, testSecret, true},
+		{"valid secret for feature2", toggle2.Key, testSecret, true},
+		{"invalid secret", toggle1.Key, "wrong-secret", false},
+		{"nonexistent key", "nonexistent|key", testSecret, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := srv.secretsMatch(context.Background(), tt.key, tt.secret)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+// Integration Tests for API Endpoints
+func TestCreateFeatureToggle(t *testing.T) {
+	srv := setupTestServer(t)
+	
+	router := setupTestRouter(srv)
+
+	tests := []struct {
+		name           string
+		payload        map[string]interface{}
+		expectedStatus int
+		checkResponse  func(t *testing.T, resp map[string]interface{})
+	}{
+		{
+			name: "create new feature without UUID",
+			payload: map[string]interface{}{
+				"Key":   "newfeature",
+				"Value": "true",
+			},
+			expectedStatus: http.StatusCreated,
+			checkResponse: func(t *testing.T, resp map[string]interface{}) {
+				assert.Contains(t, resp, "secret")
+				assert.True(t, startsWithUUID(resp["key"].(string)))
+				assert.Equal(t, "true", resp["value"])
+			},
+		},
+		{
+			name: "create feature with missing required fields",
+			payload: map[string]interface{}{
+				"Key": "",  // Empty key should still work with UUID prefix
+			},
+			expectedStatus: http.StatusCreated,
+			checkResponse: func(t *testing.T, resp map[string]interface{}) {
+				assert.Contains(t, resp, "secret")
+				assert.True(t, startsWithUUID(resp["key"].(string)))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jsonBytes, _ := json.Marshal(tt.payload)
+			req, _ := http.NewRequest("POST", "/features", bytes.NewBuffer(jsonBytes))
+			req.Header.Set("Content-Type", "application/json")
+			
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			
+			var response map[string]interface{}
+			err := json.Unmarshal(w.Body.Bytes(), &response)
+			require.NoError(t, err)
+			
+			tt.checkResponse(t, response)
+		})
+	}
+}
+
+func TestGetFeatureToggle(t *testing.T) {
+	srv := setupTestServer(t)
+	
+	router := setupTestRouter(srv)
+	
+	// Setup test data
+	testUUID := uuid.New().String()
+	toggle := store.FeatureToggle{
+		Key:    testUUID + "|testfeature",
+		Value:  "true",
+		Secret: "test-secret",
+	}
+	err := srv.store.Create(context.Background(), &toggle)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		key            string
+		expectedStatus int
+		checkResponse  func(t *testing.T, resp map[string]interface{})
+	}{
+		{
+			name:           "get existing feature",
+			key:            toggle.Key,
+			expectedStatus: http.StatusOK,
+			checkResponse: func(t *testing.T, resp map[string]interface{}) {
+				assert.Equal(t, toggle.Key, resp["key"])
+				assert.Equal(t, toggle.Value, resp["value"])
+				assert.NotContains(t, resp, "secret") // Secret should not be returned
+			},
+		},
+		{
+			name:           "get nonexistent feature",
+			key:            "nonexistent",
+			expectedStatus: http.StatusNotFound,
+			checkResponse: func(t *testing.T, resp map[string]interface{}) {
+				assert.Contains(t, resp, "error")
+			},
+		},
+		{
+			name:           "get features by UUID",
+			key:            testUUID,
+			expectedStatus: http.StatusOK,
+			checkResponse: func(t *testing.T, resp map[string]interface{}) {
+				assert.Contains(t, resp, "toggles")
+				toggles := resp["toggles"].([]interface{})
+				assert.Len(t, toggles, 1)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "/features/"+tt.key, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			
+			var response map[string]interface{}
+			err := json.Unmarshal(w.Body.Bytes(), &response)
+			require.NoError(t, err)
+			
+			tt.checkResponse(t, response)
+		})
+	}
+}
+
+func TestActivateFeatureToggle(t *testing.T) {
+	srv := setupTestServer(t)
+	
+	router := setupTestRouter(srv)
+	
+	// Setup test data
+	testUUID := uuid.New().String()
+	testSecret := "test-secret-123"
+	toggle := store.FeatureToggle{
+		Key:    testUUID + "|testfeature",
+		Value:  "false",
+		Secret: testSecret,
+	}
+	err := srv.store.Create(context.Background(), &toggle)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		key            string
+		secret         string
+		expectedStatus int
+		checkResponse  func(t *testing.T, resp map[string]interface{})
+	}{
+		{
+			name:           "activate with valid secret",
+			key:            toggle.Key,
+			secret:         testSecret,
+			expectedStatus: http.StatusOK,
+			checkResponse: func(t *testing.T, resp map[string]interface{}) {
+				assert.Equal(t, "true", resp["value"])
+				assert.Equal(t, toggle.Key, resp["key"])
+			},
+		},
+		{
+			name:           "activate with invalid secret",
+			key:            toggle.Key,
+			secret:         "wrong-secret",
+			expectedStatus: http.StatusUnauthorized,
+			checkResponse: func(t *testing.T, resp map[string]interface{}) {
+				assert.Contains(t, resp, "error")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			url := fmt.Sprintf("/features/activate/%s/%s", tt.key, tt.secret)
+			req, _ := http.NewRequest("PUT", url, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			
+			var response map[string]interface{}
+			err := json.Unmarshal(w.Body.Bytes(), &response)
+			require.NoError(t, err)
+			
+			tt.checkResponse(t, response)
+		})
+	}
+}
+
+func TestDeactivateAndDeleteFeatureToggle(t *testing.T) {
+	srv := setupTestServer(t)
+	
+	router := setupTestRouter(srv)
+	
+	// Setup test data
+	testUUID := uuid.New().String()
+	testSecret := "test-secret-123"
+	toggle := store.FeatureToggle{
+		Key:    testUUID + "|testfeature",
+		Value:  "true",
+		Secret: testSecret,
+	}
+	err := srv.store.Create(context.Background(), &toggle)
+	require.NoError(t, err)
+
+	t.Run("deactivate with valid secret", func(t *testing.T) {
+		url := fmt.Sprintf("/features/deactivate/%s/%s", toggle.Key, testSecret)
+		req, _ := http.NewRequest("PUT", url, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		
+		assert.Equal(t, http.StatusOK, w.Code)
+		
+		var response map[string]interface{}
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		require.NoError(t, err)
+		
+		assert.Equal(t, "false", response["value"])
+		assert.Equal(t, toggle.Key, response["key"])
+	})
+
+	t.Run("delete with valid secret", func(t *testing.T) {
+		url := fmt.Sprintf("/features/%s/%s", toggle.Key, testSecret)
+		req, _ := http.NewRequest("DELETE", url, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		
+		assert.Equal(t, http.StatusOK, w.Code)
+		
+		var response map[string]interface{}
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		require.NoError(t, err)
+		
+		assert.Equal(t, "Feature toggle deleted", response["message"])
+		
+		// Verify it's actually deleted
+		_, err = srv.store.Get(context.Background(), toggle.Key)
+		assert.ErrorIs(t, err, store.ErrNotFound) // Should not be found
+	})
+}
+
+func TestCollectionHash(t *testing.T) {
+	srv := setupTestServer(t)
+	
+	router := setupTestRouter(srv)
+	
+	// Setup test data
+	testUUID := uuid.New().String()
+	toggle1 := store.FeatureToggle{
+		Key:    testUUID + "|feature1",
+		Value:  "true",
+		Secret: "test-secret",
+	}
+	toggle2 := store.FeatureToggle{
+		Key:    testUUID + "|feature2",
+		Value:  "false",
+		Secret: "test-secret",
+	}
+	
+	err := srv.store.Create(context.Background(), &toggle1)
+	require.NoError(t, err)
+	err = srv.store.Create(context.Background(), &toggle2)
+	require.NoError(t, err)
+
+	t.Run("get collection hash for UUID with features", func(t *testing.T) {
+		url := fmt.Sprintf("/collectionHash/%s", testUUID)
+		req, _ := http.NewRequest("GET", url, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		
+		assert.Equal(t, http.StatusOK, w.Code)
+		
+		var response map[string]interface{}
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		require.NoError(t, err)
+		
+		assert.Contains(t, response, "collectionHash")
+		assert.NotEmpty(t, response["collectionHash"])
+	})
+}
+
+func TestStatsRecorder(t *testing.T) {
+	srv := setupTestServer(t)
+
+	recorder := newStatsRecorder(srv.store)
+	recorder.recordRead("a|feature1", "client-1", "true", true)
+	recorder.recordRead("a|feature1", "client-2", "false", false)
+	require.NoError(t, recorder.flush(context.Background()))
+
+	// A second flush must add to the stored counts
+	recorder.recordRead("a|feature1", "client-3", "true", true)
+	require.NoError(t, recorder.flush(context.Background()))
+
+	stored, err := srv.store.ListStats(context.Background(), "a|feature1")
+	require.NoError(t, err)
+	require.Len(t, stored, 1)
+	stat := stored[0]
+	assert.Equal(t, int64(2), stat.Evaluations)
+	assert.Equal(t, int64(3), stat.Reads)
+	assert.Equal(t, "client-3", stat.LastClient)
+	assert.Equal(t, "true", stat.LastValue)
+	assert.NotNil(t, stat.LastReadAt)
+
+	// Nothing pending, nothing written
+	assert.NoError(t, recorder.flush(context.Background()))
+}
+
+func TestStatsRequeue(t *testing.T) {
+	recorder := newStatsRecorder(store.NewMemory())
+	earlier := time.Now().Add(-time.Minute)
+	later := time.Now()
+	recorder.pending["a|newer"] = &store.ToggleStat{Key: "a|newer", Reads: 1, LastReadAt: &later, LastClient: "client-2", LastValue: "true"}
+	recorder.pending["a|older"] = &store.ToggleStat{Key: "a|older", Reads: 1, LastReadAt: &earlier, LastClient: "client-1", LastValue: "false"}
+
+	recorder.requeue(map[string]*store.ToggleStat{
+		"a|newer":  {Key: "a|newer", Evaluations: 1, Reads: 2, LastReadAt: &earlier, LastClient: "client-1", LastValue: "false"},
+		"a|older":  {Key: "a|older", Evaluations: 1, Reads: 2, LastReadAt: &later, LastClient: "client-2", LastValue: "true"},
+		"a|failed": {Key: "a|failed", Evaluations: 1, Reads: 1, LastReadAt: &earlier, LastClient: "client-1", LastValue: "false"},
+	})
+
+	// Counts add up, the details of the last read win
+	for _, key := range []string{"a|newer", "a|older"} {
+		stat := recorder.pending[key]
+		assert.Equal(t, int64(1), stat.Evaluations)
+		assert.Equal(t, int64(3), stat.Reads)
+		assert.Equal(t, &later, stat.LastReadAt)
+		assert.Equal(t, "client-2", stat.LastClient)
+		assert.Equal(t, "true", stat.LastValue)
+	}
+	assert.Equal(t, &store.ToggleStat{Key: "a|failed", Evaluations: 1, Reads: 1, LastReadAt: &earlier, LastClient: "client-1", LastValue: "false"}, recorder.pending["a|failed"])
+}
+
+func TestGetStats(t *testing.T) {
+	srv := setupTestServer(t)
+
+	router := setupTestRouter(srv)
+
+	testUUID := uuid.New().String()
+	testSecret := "test-secret-123"
+	for _, name := range []string{"read", "unread"} {
+		err := srv.store.Create(context.Background(), &store.FeatureToggle{
+			Key:    testUUID + "|" + name,
+			Value:  "true",
+			Secret: testSecret,
+		})
+		require.NoError(t, err)
+	}
+	srv.stats.recordRead(testUUID+"|read", "client-1", "true", true)
+
+	t.Run("stats with valid secret", func(t *testing.T) {
+		url := fmt.Sprintf("/stats/%s/%s", testUUID, testSecret)
+		req, _ := http.NewRequest("GET", url, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response struct {
+			Stats []store.ToggleStat `json:"stats"`
+		}
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		require.NoError(t, err)
+
+		require.Len(t, response.Stats, 2)
+		assert.Equal(t, testUUID+"|read", response.Stats[0].Key)
+		assert.Equal(t, int64(1), response.Stats[0].Evaluations)
+		assert.Equal(t, "client-1", response.Stats[0].LastClient)
+		assert.Equal(t, testUUID+"|unread", response.Stats[1].Key)
+		assert.Equal(t, int64(0), response.Stats[1].Reads)
+		assert.Nil(t, response.Stats[1].LastReadAt)
+	})
+
+	t.Run("stats with invalid secret", func(t *testing.T) {
+		url := fmt.Sprintf("/stats/%s/%s", testUUID, "wrong-secret")
+		req, _ := http.NewRequest("GET", url, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
+
+func TestStaleReasons(t *testing.T) {
+	now := time.Date(2026, 10, 10, 12, 0, 0, 0, time.UTC)
+	longAgo := now.Add(-200 * 24 * time.Hour)
+	yesterday := now.Add(-24 * time.Hour)
+	tomorrow := now.Add(24 * time.Hour)
+	all := staleCriteria{UnchangedDays: 90, UnreadDays: 30, Expired: true, Permanent: true}
+
+	tests := []struct {
+		name       string
+		toggle     store.FeatureToggle
+		lastReadAt *time.Time
+		criteria   staleCriteria
+		expected   []string
+	}{
+		{"recently changed and read", store.FeatureToggle{Value: "false", UpdatedAt: yesterday}, &yesterday, all, nil},
+		{"never read", store.FeatureToggle{Value: "false", UpdatedAt: yesterday}, nil, all, []string{staleUnread}},
+		{"permanently on", store.FeatureToggle{Value: "true", UpdatedAt: longAgo}, &yesterday, all, []string{staleUnchanged, stalePermanent}},
+		{"on with pending deactivation", store.FeatureToggle{Value: "true", UpdatedAt: longAgo, DisabledAt: &tomorrow}, &yesterday, all, []string{staleUnchanged}},
+		{"past removal date", store.FeatureToggle{Value: "false", UpdatedAt: yesterday, RemoveAt: &yesterday}, &yesterday, all, []string{staleExpired}},
+		{"past deactivation", store.FeatureToggle{Value: "false", UpdatedAt: yesterday, DisabledAt: &yesterday}, &yesterday, all, []string{staleExpired}},
+		{"criteria disabled", store.FeatureToggle{Value: "true", UpdatedAt: longAgo, RemoveAt: &yesterday}, nil, staleCriteria{}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := staleReasons(tt.toggle, tt.lastReadAt, tt.criteria, now)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestStaleReport(t *testing.T) {
+	srv := setupTestServer(t)
+
+	router := setupTestRouter(srv)
+
+	testUUID := uuid.New().String()
+	testSecret := "test-secret-123"
+	for _, name := range []string{"fresh", "doomed"} {
+		err := srv.store.Create(context.Background(), &store.FeatureToggle{
+			Key:    testUUID + "|" + name,
+			Value:  "false",
+			Secret: testSecret,
+		})
+		require.NoError(t, err)
+	}
+
+	t.Run("mark feature for removal", func(t *testing.T) {
+		url := fmt.Sprintf("/features/removeAt/%s/%s/%s", testUUID+"|doomed", "2020-01-01T00:00:00Z", testSecret)
+		req, _ := http.NewRequest("PUT", url, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("reject invalid removal date", func(t *testing.T) {
+		url := fmt.Sprintf("/features/removeAt/%s/%s/%s", testUUID+"|doomed", "someday", testSecret)
+		req, _ := http.NewRequest("PUT", url, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("report expired features only", func(t *testing.T) {
+		url := fmt.Sprintf("/report/stale/%s/%s?unreadDays=0", testUUID, testSecret)
+		req, _ := http.NewRequest("GET", url, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response struct {
+			Toggles []StaleToggle `json:"toggles"`
+		}
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		require.NoError(t, err)
+
+		require.Len(t, response.Toggles, 1)
+		assert.Equal(t, testUUID+"|doomed", response.Toggles[0].Key)
+		assert.Equal(t, []string{staleExpired}, response.Toggles[0].Reasons)
+	})
+
+	t.Run("reject invalid criteria", func(t *testing.T) {
+		url := fmt.Sprintf("/report/stale/%s/%s?unreadDays=never", testUUID, testSecret)
+		req, _ := http.NewRequest("GET", url, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestSetDeprecationHeaders(t *testing.T) {
+	removeAt := time.Date(2026, 10, 10, 0, 0, 0, 0, time.UTC)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	setDeprecationHeaders(c, store.FeatureToggle{RemoveAt: &removeAt})
+
+	assert.Equal(t, "@1791590400", w.Header().Get("Deprecation"))
+	assert.Equal(t, "Sat, 10 Oct 2026 00:00:00 GMT", w.Header().Get("Sunset"))
+
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	setDeprecationHeaders(c, store.FeatureToggle{})
+
+	assert.Empty(t, w.Header().Get("Deprecation"))
+	assert.Empty(t, w.Header().Get("Sunset"))
+}
+
+func TestValidateToggle(t *testing.T) {
+	tests := []struct {
+		name    string
+		toggle  store.FeatureToggle
+		wantErr bool
+	}{
+		{"no kind", store.FeatureToggle{Key: "a"}, false},
+		{"release kind", store.FeatureToggle{Key: "a", Kind: kindRelease}, false},
+		{"permission kind", store.FeatureToggle{Key: "a", Kind: kindPermission}, false},
+		{"unknown kind", store.FeatureToggle{Key: "a", Kind: "forever"}, true},
+		{"true value", store.FeatureToggle{Key: "a", Value: "true"}, false},
+		{"non boolean value", store.FeatureToggle{Key: "a", Value: "maybe"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateToggle(&tt.toggle)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+
+	toggle := store.FeatureToggle{Key: "a"}
+	require.NoError(t, validateToggle(&toggle))
+	assert.Equal(t, "false", toggle.Value)
+}
+
+func TestUpdateMetadata(t *testing.T) {
+	srv := setupTestServer(t)
+
+	router := setupTestRouter(srv)
+
+	testUUID := uuid.New().String()
+	testSecret := "test-secret-123"
+	toggle := store.FeatureToggle{
+		Key:         testUUID + "|testfeature",
+		Value:       "true",
+		Secret:      testSecret,
+		Description: "Enables the new checkout",
+	}
+	err := srv.store.Create(context.Background(), &toggle)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		payload        map[string]interface{}
+		expectedStatus int
+		checkResponse  func(t *testing.T, resp map[string]interface{})
+	}{
+		{
+			name:           "update owner and kind",
+			payload:        map[string]interface{}{"owner": "team-checkout", "kind": "release"},
+			expectedStatus: http.StatusOK,
+			checkResponse: func(t *testing.T, resp map[string]interface{}) {
+				assert.Equal(t, "team-checkout", resp["owner"])
+				assert.Equal(t, "release", resp["kind"])
+				assert.Equal(t, "Enables the new checkout", resp["description"])
+				assert.NotContains(t, resp, "secret")
+			},
+		},
+		{
+			name:           "reject unknown kind",
+			payload:        map[string]interface{}{"kind": "forever"},
+			expectedStatus: http.StatusBadRequest,
+			checkResponse: func(t *testing.T, resp map[string]interface{}) {
+				assert.Contains(t, resp, "error")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jsonBytes, _ := json.Marshal(tt.payload)
+			url := fmt.Sprintf("/features/metadata/%s/%s", toggle.Key, testSecret)
+			req, _ := http.NewRequest("PUT", url, bytes.NewBuffer(jsonBytes))
+			req.Header.Set("Content-Type", "application/json")
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			var response map[string]interface{}
+			err := json.Unmarshal(w.Body.Bytes(), &response)
+			require.NoError(t, err)
+
+			tt.checkResponse(t, response)
+		})
+	}
+}
+
+func TestApplyPatch(t *testing.T) {
+	activeAt := time.Date(2026, 10, 10, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		patch   string
+		wantErr bool
+		check   func(t *testing.T, toggle store.FeatureToggle)
+	}{
+		{
+			name:  "empty patch keeps toggle",
+			patch: `{}`,
+			check: func(t *testing.T, toggle store.FeatureToggle) {
+				assert.Equal(t, "true", toggle.Value)
+				assert.Equal(t, "team-a", toggle.Owner)
+			},
+		},
+		{
+			name:  "update value and tags",
+			patch: `{"value":"false","tags":["sprint-42"]}`,
+			check: func(t *testing.T, toggle store.FeatureToggle) {
+				assert.Equal(t, "false", toggle.Value)
+				assert.Equal(t, []string{"sprint-42"}, []string(toggle.Tags))
+			},
+		},
+		{
+			name:  "null clears fields",
+			patch: `{"activeAt":null,"owner":null,"tags":null}`,
+			check: func(t *testing.T, toggle store.FeatureToggle) {
+				assert.Nil(t, toggle.ActiveAt)
+				assert.Empty(t, toggle.Owner)
+				assert.Nil(t, toggle.Tags)
+			},
+		},
+		{
+			name:  "field names are case insensitive",
+			patch: `{"Description":"new flow"}`,
+			check: func(t *testing.T, toggle store.FeatureToggle) {
+				assert.Equal(t, "new flow", toggle.Description)
+			},
+		},
+		{name: "null value", patch: `{"value":null}`, wantErr: true},
+		{name: "invalid value", patch: `{"value":"maybe"}`, wantErr: true},
+		{name: "invalid kind", patch: `{"kind":"forever"}`, wantErr: true},
+		{name: "invalid date", patch: `{"disabledAt":"tomorrow"}`, wantErr: true},
+		{name: "unknown field", patch: `{"key":"other"}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			toggle := store.FeatureToggle{
+				Key:      "a|feature",
+				Value:    "true",
+				ActiveAt: &activeAt,
+				Owner:    "team-a",
+				Tags:     []string{"sprint-41"},
+			}
+
+			var patch map[string]json.RawMessage
+			require.NoError(t, json.Unmarshal([]byte(tt.patch), &patch))
+
+			err := applyPatch(&toggle, patch)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			tt.check(t, toggle)
+		})
+	}
+}
+
+func TestPatchFeatureToggle(t *testing.T) {
+	srv := setupTestServer(t)
+
+	router := setupTestRouter(srv)
+
+	testUUID := uuid.New().String()
+	testSecret := "test-secret-123"
+	toggle := store.FeatureToggle{
+		Key:    testUUID + "|testfeature",
+		Value:  "false",
+		Secret: testSecret,
+	}
+	err := srv.store.Create(context.Background(), &toggle)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		payload        string
+		expectedStatus int
+	}{
+		{"patch with valid secret", `{"secret":"` + testSecret + `","value":"true","tags":["a","b"]}`, http.StatusOK},
+		{"patch with invalid secret", `{"secret":"wrong-secret","value":"true"}`, http.StatusUnauthorized},
+		{"patch without secret", `{"value":"true"}`, http.StatusUnauthorized},
+		{"patch with invalid document", `{"secret":"` + testSecret + `","value":"maybe"}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("PATCH", "/features/"+toggle.Key, strings.NewReader(tt.payload))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+		})
+	}
+
+	patched, err := srv.store.Get(context.Background(), toggle.Key)
+	require.NoError(t, err)
+	assert.Equal(t, "true", patched.Value)
+	assert.Equal(t, []string{"a", "b"}, []string(patched.Tags))
+	assert.Equal(t, testSecret, patched.Secret)
+}
+
+func TestParseScheduleDate(t *testing.T) {
+	tests := []struct {
+		name     string
+		date     string
+		timeZone string
+		expected time.Time
+		wantErr  bool
+	}{
+		{"RFC3339 UTC", "2026-10-10T08:30:00Z", "", time.Date(2026, 10, 10, 8, 30, 0, 0, time.UTC), false},
+		{"RFC3339 offset ignores time zone", "2026-10-10T08:30:00+02:00", "America/New_York", time.Date(2026, 10, 10, 6, 30, 0, 0, time.UTC), false},
+		{"date defaults to UTC", "2026-10-10", "", time.Date(2026, 10, 10, 0, 0, 0, 0, time.UTC), false},
+		{"date in time zone", "2026-10-10", "Europe/Berlin", time.Date(2026, 10, 9, 22, 0, 0, 0, time.UTC), false},
+		{"date-time in time zone", "2026-10-10T08:30:00", "Europe/Berlin", time.Date(2026, 10, 10, 6, 30, 0, 0, time.UTC), false},
+		{"invalid date", "10/10/2026", "", time.Time{}, true},
+		{"invalid time zone", "2026-10-10", "Mars/Olympus", time.Time{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := parseScheduleDate(tt.date, tt.timeZone)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, tt.expected.Equal(result), "expected %s, got %s", tt.expected, result)
+		})
+	}
+}
+
+func TestValidateSchedule(t *testing.T) {
+	earlier := time.Date(2026, 10, 10, 0, 0, 0, 0, time.UTC)
+	later := earlier.Add(time.Hour)
+
+	assert.NoError(t, validateSchedule(&store.FeatureToggle{}))
+	assert.NoError(t, validateSchedule(&store.FeatureToggle{ActiveAt: &earlier}))
+	assert.NoError(t, validateSchedule(&store.FeatureToggle{ActiveAt: &earlier, DisabledAt: &later}))
+	assert.Error(t, validateSchedule(&store.FeatureToggle{ActiveAt: &later, DisabledAt: &earlier}))
+	assert.Error(t, validateSchedule(&store.FeatureToggle{ActiveAt: &earlier, DisabledAt: &earlier}))
+	assert.Error(t, validateToggle(&store.FeatureToggle{ActiveAt: &later, DisabledAt: &earlier}))
+}
+
+func TestEffectiveValue(t *testing.T) {
+	now := time.Date(2026, 10, 10, 12, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Minute)
+	future := now.Add(time.Minute)
+
+	tests := []struct {
+		name     string
+		toggle   store.FeatureToggle
+		expected string
+	}{
+		{"no schedule", store.FeatureToggle{Value: "true"}, "true"},
+		{"activation pending", store.FeatureToggle{Value: "false", ActiveAt: &future}, "false"},
+		{"activation passed", store.FeatureToggle{Value: "false", ActiveAt: &past}, "true"},
+		{"activation at now", store.FeatureToggle{Value: "false", ActiveAt: &now}, "true"},
+		{"deactivation pending", store.FeatureToggle{Value: "true", DisabledAt: &future}, "true"},
+		{"deactivation passed", store.FeatureToggle{Value: "true", ActiveAt: &past, DisabledAt: &now}, "false"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, effectiveValue(tt.toggle, now))
+		})
+	}
+}
+
+func TestClearSchedule(t *testing.T) {
+	srv := setupTestServer(t)
+
+	router := setupTestRouter(srv)
+
+	testUUID := uuid.New().String()
+	testSecret := "test-secret-123"
+	activeAt := time.Date(2026, 10, 10, 0, 0, 0, 0, time.UTC)
+	toggle := store.FeatureToggle{
+		Key:      testUUID + "|testfeature",
+		Value:    "false",
+		Secret:   testSecret,
+		ActiveAt: &activeAt,
+	}
+	err := srv.store.Create(context.Background(), &toggle)
+	require.NoError(t, err)
+
+	url := fmt.Sprintf("/features/activateAt/%s/%s", toggle.Key, testSecret)
+	req, _ := http.NewRequest("DELETE", url, nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response map[string]interface{}
+	err = json.Unmarshal(w.Body.Bytes(), &response)
+	require.NoError(t, err)
+	assert.Nil(t, response["activeAt"])
+
+	cleared, err := srv.store.Get(context.Background(), toggle.Key)
+	require.NoError(t, err)
+	assert.Nil(t, cleared.ActiveAt)
+}
+
+func TestServerOptions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	testStore := store.NewMemory()
+	testUUID := uuid.New().String()
+	require.NoError(t, testStore.Create(context.Background(), &store.FeatureToggle{
+		Key:    testUUID + "|feature",
+		Value:  "true",
+		Secret: "test-secret",
+	}))
+
+	srv := NewServer(testStore, Options{
+		Validation: strictValidation,
+		Prefix:     "/toggles/",
+		CORS:       &CORSPolicy{AllowedOrigins: []string{"https://app.example.com"}},
+		Auth: func(r *http.Request) error {
+			if r.Header.Get("Authorization") != "Bearer token" {
+				return fmt.Errorf("missing token")
+			}
+			return nil
+		},
+	})
+	defer srv.Close()
+
+	// Mounted inside another application
+	mux := http.NewServeMux()
+	mux.Handle("/toggles/", srv)
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		headers        map[string]string
+		expectedStatus int
+		expectedOrigin string
+	}{
+		{"prefixed route", "GET", "/toggles/features/" + testUUID + "|feature", map[string]string{"Authorization": "Bearer token"}, http.StatusOK, ""},
+		{"unauthenticated", "GET", "/toggles/features/" + testUUID + "|feature", nil, http.StatusUnauthorized, ""},
+		{"allowed origin", "GET", "/toggles/features/" + testUUID + "|feature", map[string]string{"Authorization": "Bearer token", "Origin": "https://app.example.com"}, http.StatusOK, "https://app.example.com"},
+		{"other origin", "GET", "/toggles/features/" + testUUID + "|feature", map[string]string{"Authorization": "Bearer token", "Origin": "https://evil.example.com"}, http.StatusForbidden, ""},
+		{"preflight without credentials", "OPTIONS", "/toggles/features", map[string]string{"Origin": "https://app.example.com"}, http.StatusNoContent, "https://app.example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, tt.path, nil)
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Equal(t, tt.expectedOrigin, w.Header().Get("Access-Control-Allow-Origin"))
+		})
+	}
+}
+
+func TestOriginAllowed(t *testing.T) {
+	patterns := []string{"https://app.example.com", "https://*.example.org", "http://localhost:3000"}
+
+	tests := []struct {
+		origin   string
+		expected bool
+	}{
+		{"https://app.example.com", true},
+		{"https://APP.example.com", true},
+		{"http://app.example.com", false},
+		{"https://other.example.com", false},
+		{"https://a.example.org", true},
+		{"https://a.b.example.org", true},
+		{"https://example.org", false},
+		{"https://evilexample.org", false},
+		{"http://a.example.org", false},
+		{"http://localhost:3000", true},
+		{"http://localhost:3001", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.origin, func(t *testing.T) {
+			assert.Equal(t, tt.expected, originAllowed(patterns, tt.origin))
+		})
+	}
+
+	assert.True(t, originAllowed([]string{"*"}, "https://anything.example.com"))
+	assert.False(t, originAllowed(nil, "https://anything.example.com"))
+
+	assert.NoError(t, ValidateOrigins(patterns))
+	assert.Error(t, ValidateOrigins([]string{"app.example.com"}))
+	assert.Error(t, ValidateOrigins([]string{"https://app.*.example.com"}))
+	assert.Error(t, ValidateOrigins([]string{"https://app.example.com/path"}))
+}
+
+func TestDefaultCORSPolicy(t *testing.T) {
+	srv := setupTestServer(t)
+	req := httptest.NewRequest("GET", "/features/nonexistent", nil)
+	req.Header.Set("Origin", "https://evil.example.com")
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+
+	assert.Panics(t, func() {
+		NewServer(store.NewMemory(), Options{CORS: &CORSPolicy{AllowedOrigins: []string{"*"}, AllowCredentials: true}})
+	})
+}
+
+func TestCORS(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	testStore := store.NewMemory()
+	testUUID := uuid.New().String()
+	testSecret := "test-secret"
+	key := testUUID + "|feature"
+	require.NoError(t, testStore.Create(context.Background(), &store.FeatureToggle{Key: key, Value: "true", Secret: testSecret}))
+
+	srv := NewServer(testStore, Options{Validation: strictValidation, CORS: &CORSPolicy{
+		AllowedOrigins: []string{"https://*.example.com"},
+		MaxAge:         10 * time.Minute,
+	}})
+	defer srv.Close()
+
+	request := func(method string, path string, origin string, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Host = "yaft.internal"
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("preflight lists the methods of the route", func(t *testing.T) {
+		w := request("OPTIONS", "/features/activate/"+key+"/"+testSecret, "https://app.example.com", "")
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Equal(t, "https://app.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "PUT, OPTIONS", w.Header().Get("Access-Control-Allow-Methods"))
+		assert.Equal(t, "600", w.Header().Get("Access-Control-Max-Age"))
+		assert.Empty(t, w.Header().Get("Access-Control-Allow-Credentials"))
+
+		w = request("OPTIONS", "/features/"+key, "https://app.example.com", "")
+		assert.Equal(t, "GET, PATCH, OPTIONS", w.Header().Get("Access-Control-Allow-Methods"))
+	})
+
+	t.Run("requests without origin and same-origin requests pass", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, request("GET", "/features/"+key, "", "").Code)
+		assert.Equal(t, http.StatusOK, request("GET", "/features/"+key, "https://yaft.internal", "").Code)
+	})
+
+	t.Run("other origins are rejected", func(t *testing.T) {
+		w := request("OPTIONS", "/features/"+key, "https://evil.example.net", "")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("group allowlist", func(t *testing.T) {
+		w := request("PUT", "/cors/"+testUUID+"/wrong-secret", "", `{"allowedOrigins": ["https://a.example.com"]}`)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		w = request("PUT", "/cors/"+testUUID+"/"+testSecret, "", `{"allowedOrigins": ["a.example.com"]}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		w = request("PUT", "/cors/"+testUUID+"/"+testSecret, "", `{"allowedOrigins": ["https://a.example.com"]}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		w = request("GET", "/cors/"+testUUID+"/"+testSecret, "", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"allowedOrigins": ["https://a.example.com"]}`, w.Body.String())
+
+		assert.Equal(t, http.StatusOK, request("GET", "/features/"+key, "https://a.example.com", "").Code)
+		assert.Equal(t, http.StatusForbidden, request("GET", "/features/"+key, "https://b.example.com", "").Code)
+		assert.Equal(t, http.StatusForbidden, request("OPTIONS", "/features/deactivate/"+key+"/"+testSecret, "https://b.example.com", "").Code)
+		// The group allowlist cannot widen the instance policy
+		request("PUT", "/cors/"+testUUID+"/"+testSecret, "", `{"allowedOrigins": ["https://a.example.net"]}`)
+		assert.Equal(t, http.StatusForbidden, request("GET", "/features/"+key, "https://a.example.net", "").Code)
+	})
+}
+
+func TestRateLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	srv := NewServer(store.NewMemory(), Options{Validation: strictValidation, RateLimit: RateLimit{RequestsPerSecond: 1, Burst: 2}})
+	defer srv.Close()
+
+	request := func(remoteAddr string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", "/features/nonexistent", nil)
+		req.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusNotFound, request("10.0.0.1:1234").Code)
+	assert.Equal(t, http.StatusNotFound, request("10.0.0.1:1234").Code)
+	w := request("10.0.0.1:1234")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+	// Other clients have their own limit
+	assert.Equal(t, http.StatusNotFound, request("10.0.0.2:1234").Code)
+}
+
+func TestTrustedProxies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	createGroup := func(srv *Server, forwardedFor string) int {
+		req, _ := http.NewRequest("POST", "/features", strings.NewReader(`{"key": "feature", "value": "true"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		req.RemoteAddr = "10.0.0.1:1234"
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	t.Run("spoofed header is ignored", func(t *testing.T) {
+		srv := NewServer(store.NewMemory(), Options{Validation: strictValidation, RateLimit: RateLimit{GroupCreationsPerHour: 1}})
+		defer srv.Close()
+
+		assert.Equal(t, http.StatusCreated, createGroup(srv, "203.0.113.1"))
+		assert.Equal(t, http.StatusTooManyRequests, createGroup(srv, "203.0.113.2"))
+	})
+
+	t.Run("header of a trusted proxy is used", func(t *testing.T) {
+		srv := NewServer(store.NewMemory(), Options{
+			Validation:     strictValidation,
+			RateLimit:      RateLimit{GroupCreationsPerHour: 1},
+			TrustedProxies: []string{"10.0.0.0/8"},
+		})
+		defer srv.Close()
+
+		assert.Equal(t, http.StatusCreated, createGroup(srv, "203.0.113.1"))
+		assert.Equal(t, http.StatusTooManyRequests, createGroup(srv, "203.0.113.1"))
+		assert.Equal(t, http.StatusCreated, createGroup(srv, "203.0.113.2"))
+	})
+}
+
+func TestMemoryLimiter(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 10, 10, 0, 0, 0, 0, time.UTC)
+	limiter := newMemoryLimiter(func() time.Time { return now })
+
+	t.Run("token bucket", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			wait, err := limiter.Allow(ctx, "a", 0.5, 2)
+			require.NoError(t, err)
+			assert.Zero(t, wait)
+		}
+		wait, err := limiter.Allow(ctx, "a", 0.5, 2)
+		require.NoError(t, err)
+		assert.Equal(t, 2*time.Second, wait)
+
+		// Rejected requests do not consume tokens
+		now = now.Add(2 * time.Second)
+		wait, err = limiter.Allow(ctx, "a", 0.5, 2)
+		require.NoError(t, err)
+		assert.Zero(t, wait)
+	})
+
+	t.Run("progressive lockout", func(t *testing.T) {
+		policy := Lockout{Failures: 3, Duration: time.Minute, MaxDuration: 5 * time.Minute}
+		expected := []time.Duration{0, 0, time.Minute, 2 * time.Minute, 4 * time.Minute, 5 * time.Minute}
+		for _, lockout := range expected {
+			actual, err := limiter.Fail(ctx, "group", policy)
+			require.NoError(t, err)
+			assert.Equal(t, lockout, actual)
+		}
+
+		locked, err := limiter.LockedOut(ctx, "group")
+		require.NoError(t, err)
+		assert.Equal(t, 5*time.Minute, locked)
+
+		now = now.Add(5 * time.Minute)
+		locked, err = limiter.LockedOut(ctx, "group")
+		require.NoError(t, err)
+		assert.Zero(t, locked)
+
+		// A valid secret forgets the failures
+		require.NoError(t, limiter.Succeed(ctx, "group"))
+		lockout, err := limiter.Fail(ctx, "group", policy)
+		require.NoError(t, err)
+		assert.Zero(t, lockout)
+
+		// So does a long enough pause
+		limiter.Fail(ctx, "group", policy)
+		now = now.Add(6 * time.Minute)
+		lockout, err = limiter.Fail(ctx, "group", policy)
+		require.NoError(t, err)
+		assert.Zero(t, lockout)
+	})
+}
+
+func TestLockout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	testStore := store.NewMemory()
+	testUUID := uuid.New().String()
+	testSecret := "test-secret"
+	key := testUUID + "|feature"
+	require.NoError(t, testStore.Create(context.Background(), &store.FeatureToggle{Key: key, Value: "false", Secret: testSecret}))
+
+	srv := NewServer(testStore, Options{Validation: strictValidation, RateLimit: RateLimit{
+		Lockout: Lockout{Failures: 2, Duration: time.Minute, MaxDuration: time.Hour},
+	}})
+	defer srv.Close()
+
+	request := func(method string, path string, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, request("PUT", "/features/activate/"+key+"/wrong", "").Code)
+	assert.Equal(t, http.StatusUnauthorized, request("PATCH", "/features/"+key, `{"secret": "wrong", "value": "true"}`).Code)
+
+	// Even the valid secret is rejected while the group is locked
+	for _, w := range []*httptest.ResponseRecorder{
+		request("PUT", "/features/activate/"+key+"/"+testSecret, ""),
+		request("GET", "/stats/"+testUUID+"/"+testSecret, ""),
+		request("POST", "/features", `{"key": "`+testUUID+`|other", "value": "true", "secret": "`+testSecret+`"}`),
+	} {
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Equal(t, "60", w.Header().Get("Retry-After"))
+		assert.Contains(t, w.Body.String(), "Too many invalid secrets")
+	}
+
+	// Reading does not need the secret and is not locked
+	assert.Equal(t, http.StatusOK, request("GET", "/features/"+key, "").Code)
+}
+
+func TestAdminLockout(t *testing.T) {
+	const token = "master-token-master-token-master-token"
+	srv := NewServer(store.NewMemory(), Options{Validation: strictValidation, AdminToken: token, RateLimit: RateLimit{
+		Lockout: Lockout{Failures: 2, Duration: time.Minute, MaxDuration: time.Hour},
+	}})
+	defer srv.Close()
+
+	request := func(remoteAddr string, token string) int {
+		req := httptest.NewRequest("GET", "/admin/stats", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, request("10.0.0.1:1234", "wrong"))
+	assert.Equal(t, http.StatusUnauthorized, request("10.0.0.1:1234", "wrong"))
+	assert.Equal(t, http.StatusTooManyRequests, request("10.0.0.1:1234", token))
+
+	// Invalid tokens of one client do not lock out the others
+	assert.Equal(t, http.StatusOK, request("10.0.0.2:1234", token))
+}
+
+func TestGroupLimits(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	srv := NewServer(store.NewMemory(), Options{Validation: strictValidation, RateLimit: RateLimit{
+		GroupRequestsPerSecond: 1,
+		GroupBurst:             1,
+		GroupCreationsPerHour:  1,
+		GroupCreationBurst:     2,
+	}})
+	defer srv.Close()
+
+	request := func(method string, path string, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		return w
+	}
+
+	var created map[string]interface{}
+	w := request("POST", "/features", `{"key": "first", "value": "true"}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	var second map[string]interface{}
+	w = request("POST", "/features", `{"key": "second", "value": "true"}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &second))
+
+	w = request("POST", "/features", `{"key": "third", "value": "true"}`)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "3600", w.Header().Get("Retry-After"))
+
+	// Adding toggles to an existing group is not a new group
+	key := created["key"].(string)
+	group := store.Group(key)
+	assert.Equal(t, http.StatusCreated, request("POST", "/features", `{"key": "`+group+`|more", "value": "true", "secret": "`+created["secret"].(string)+`"}`).Code)
+
+	// Requests to the group share its limit, other groups have their own
+	assert.Equal(t, http.StatusTooManyRequests, request("GET", "/features/"+key, "").Code)
+	assert.Equal(t, http.StatusOK, request("GET", "/features/"+second["key"].(string), "").Code)
+}
+
+func TestServerClose(t *testing.T) {
+	srv := setupTestServer(t)
+
+	srv.stats.recordRead("a|feature1", "client-1", "true", true)
+	require.NoError(t, srv.Close())
+	// Closing twice is fine
+	require.NoError(t, srv.Close())
+
+	stored, err := srv.store.ListStats(context.Background(), "a|")
+	require.NoError(t, err)
+	assert.Len(t, stored, 1)
+}
+
+func TestHealth(t *testing.T) {
+	get := func(srv *Server, path string) (int, map[string]interface{}) {
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		var body map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		return w.Code, body
+	}
+
+	t.Run("ready", func(t *testing.T) {
+		srv := NewServer(store.NewCache(store.NewMemory(), time.Minute), Options{Validation: strictValidation, Auth: func(r *http.Request) error { return fmt.Errorf("denied") }})
+		defer srv.Close()
+
+		code, body := get(srv, "/healthz")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, "ok", body["status"])
+
+		// Probes skip authentication and warm the cache
+		code, body = get(srv, "/readyz")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, "ready", body["status"])
+		assert.True(t, srv.warmed.Load())
+	})
+
+	t.Run("database unavailable", func(t *testing.T) {
+		testStore, err := store.NewSQLite(":memory:")
+		require.NoError(t, err)
+		srv := NewServer(testStore, Options{Validation: strictValidation})
+		defer srv.Close()
+		require.NoError(t, testStore.Close())
+
+		code, body := get(srv, "/readyz")
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		assert.Equal(t, "unavailable", body["status"])
+		assert.NotEqual(t, "ok", body["checks"].(map[string]interface{})["database"])
+
+		// Liveness does not depend on the database
+		code, _ = get(srv, "/healthz")
+		assert.Equal(t, http.StatusOK, code)
+	})
+
+	t.Run("draining", func(t *testing.T) {
+		srv := setupTestServer(t)
+		srv.Drain()
+
+		code, body := get(srv, "/readyz")
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		assert.Equal(t, "draining", body["status"])
+	})
+}
+
+func TestRequestID(t *testing.T) {
+	logger, hook := logtest.NewNullLogger()
+	srv := NewServer(store.NewMemory(), Options{Logger: logger})
+	defer srv.Close()
+
+	tests := []struct {
+		name   string
+		header string
+		keep   bool
+	}{
+		{"taken from the request", "req-123", true},
+		{"generated if missing", "", false},
+		{"replaced if invalid", "bad\nid", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hook.Reset()
+			req := httptest.NewRequest("GET", "/features/"+uuid.NewString()+"|missing", nil)
+			if tt.header != "" {
+				req.Header.Set(RequestIDHeader, tt.header)
+			}
+			w := httptest.NewRecorder()
+			srv.ServeHTTP(w, req)
+
+			id := w.Header().Get(RequestIDHeader)
+			if tt.keep {
+				assert.Equal(t, tt.header, id)
+			} else {
+				assert.NotEmpty(t, id)
+				assert.NotEqual(t, tt.header, id)
+			}
+
+			// Handler logs and the access log carry the ID
+			require.NotEmpty(t, hook.AllEntries())
+			for _, entry := range hook.AllEntries() {
+				assert.Equal(t, id, entry.Data["requestID"], entry.Message)
+			}
+		})
+	}
+}
+
+func TestTracing(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	logger, hook := logtest.NewNullLogger()
+	srv := NewServer(store.NewMemory(), Options{Logger: logger, TracerProvider: provider})
+	defer srv.Close()
+
+	req := httptest.NewRequest("DELETE", "/features/"+uuid.NewString()+"|missing/mysecret", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	srv.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	span := spans[0]
+	assert.Equal(t, "DELETE /features/:key/:secret", span.Name)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext.TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", span.Parent.SpanID().String())
+	for _, attr := range span.Attributes {
+		assert.NotContains(t, attr.Value.Emit(), "mysecret")
+	}
+
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", hook.LastEntry().Data["traceID"])
+}
+
+func TestListGroup(t *testing.T) {
+	srv := setupTestServer(t)
+	group := uuid.New().String()
+	future := time.Now().Add(time.Hour)
+	for _, toggle := range []store.FeatureToggle{
+		{Key: group + "|alpha", Value: "true", Tags: []string{"beta"}},
+		{Key: group + "|bravo", Value: "false", Tags: []string{"legacy"}},
+		{Key: group + "|charlie", Value: "false", ActiveAt: &future},
+		{Key: group + "|delta", Value: "true", Tags: []string{"beta", "legacy"}},
+		{Key: group + "|echo", Value: "true"},
+	} {
+		toggle := toggle
+		require.NoError(t, srv.store.Create(context.Background(), &toggle))
+	}
+
+	list := func(query string) (int, map[string]interface{}, []string) {
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, httptest.NewRequest("GET", "/features/"+group+"?"+query, nil))
+		var body map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		names := []string{}
+		if toggles, ok := body["toggles"].([]interface{}); ok {
+			for _, toggle := range toggles {
+				names = append(names, store.Name(toggle.(map[string]interface{})["Key"].(string)))
+			}
+		}
+		return w.Code, body, names
+	}
+
+	t.Run("pages follow the cursor", func(t *testing.T) {
+		var pages [][]string
+		query := "limit=2&sort=-key"
+		for {
+			code, body, names := list(query)
+			require.Equal(t, http.StatusOK, code)
+			assert.EqualValues(t, 5, body["total"])
+			pages = append(pages, names)
+			if body["nextCursor"] == "" {
+				break
+			}
+			query = "limit=2&sort=-key&cursor=" + body["nextCursor"].(string)
+		}
+		assert.Equal(t, [][]string{{"echo", "delta"}, {"charlie", "bravo"}, {"alpha"}}, pages)
+	})
+
+	filters := []struct {
+		name     string
+		query    string
+		expected []string
+	}{
+		{"all by default", "", []string{"alpha", "bravo", "charlie", "delta", "echo"}},
+		{"search", "search=LT", []string{"delta"}},
+		{"prefix", "prefix=ch", []string{"charlie"}},
+		{"value", "value=false", []string{"bravo", "charlie"}},
+		{"scheduled", "schedule=scheduled", []string{"charlie"}},
+		{"tags and", "tags=beta,legacy", []string{"delta"}},
+		{"tags or", "tags=" + url.QueryEscape("beta|legacy"), []string{"alpha", "bravo", "delta"}},
+		{"tags not", "tags=" + url.QueryEscape("beta,!legacy"), []string{"alpha"}},
+	}
+	for _, tt := range filters {
+		t.Run(tt.name, func(t *testing.T) {
+			code, body, names := list(tt.query)
+			require.Equal(t, http.StatusOK, code)
+			assert.Equal(t, tt.expected, names)
+			assert.EqualValues(t, len(tt.expected), body["total"])
+		})
+	}
+
+	t.Run("no match", func(t *testing.T) {
+		code, _, _ := list("search=zulu")
+		assert.Equal(t, http.StatusNotFound, code)
+	})
+
+	invalid := []string{"limit=0", "limit=1001", "sort=name", "schedule=soon", "tags=a%7C", "cursor=not-a-cursor!", "sort=key&cursor=" + encodeCursor(listCursor{Sort: "updated"})}
+	for _, query := range invalid {
+		t.Run("invalid "+query, func(t *testing.T) {
+			code, body, _ := list(query)
+			assert.Equal(t, http.StatusBadRequest, code)
+			assert.Contains(t, body, "error")
+		})
+	}
+}
+
+func TestBulkUpdate(t *testing.T) {
+	srv := setupTestServer(t)
+	group := uuid.New().String()
+	for _, toggle := range []store.FeatureToggle{
+		{Key: group + "|a", Value: "false", Secret: "test-secret", Tags: []string{"sprint-1"}},
+		{Key: group + "|b", Value: "false", Secret: "test-secret", Tags: []string{"sprint-1", "legacy"}},
+		{Key: group + "|c", Value: "false", Secret: "test-secret", Tags: []string{"sprint-2"}},
+	} {
+		toggle := toggle
+		require.NoError(t, srv.store.Create(context.Background(), &toggle))
+	}
+
+	request := func(path string, body string) (int, map[string]interface{}) {
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, httptest.NewRequest("PUT", path, strings.NewReader(body)))
+		var response map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return w.Code, response
+	}
+	value := func(name string) string {
+		toggle, err := srv.store.Get(context.Background(), group+"|"+name)
+		require.NoError(t, err)
+		return toggle.Value
+	}
+
+	t.Run("dry run", func(t *testing.T) {
+		code, body := request("/bulk/activate/"+group+"/test-secret?dryRun=true&tags=sprint-1", "")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, []interface{}{group + "|a", group + "|b"}, body["keys"])
+		assert.Equal(t, true, body["dryRun"])
+		assert.Equal(t, "false", value("a"))
+	})
+
+	t.Run("activate by tag expression", func(t *testing.T) {
+		code, body := request("/bulk/activate/"+group+"/test-secret?tags="+url.QueryEscape("sprint-1,!legacy|sprint-2"), "")
+		assert.Equal(t, http.StatusOK, code)
+		assert.EqualValues(t, 2, body["count"])
+		assert.Equal(t, "true", value("a"))
+		assert.Equal(t, "false", value("b"))
+		assert.Equal(t, "true", value("c"))
+	})
+
+	t.Run("invalid schedule changes nothing", func(t *testing.T) {
+		code, _ := request("/bulk/deactivateAt/"+group+"/2030-01-01/test-secret?tags=sprint-1", "")
+		require.Equal(t, http.StatusOK, code)
+
+		code, body := request("/bulk/activateAt/"+group+"/2031-01-01/test-secret?tags=sprint-1", "")
+		assert.Equal(t, http.StatusBadRequest, code)
+		assert.Equal(t, group+"|a", body["key"])
+		toggle, err := srv.store.Get(context.Background(), group+"|b")
+		require.NoError(t, err)
+		assert.Nil(t, toggle.ActiveAt)
+	})
+
+	t.Run("add and remove tags by key", func(t *testing.T) {
+		code, body := request("/bulk/tags/"+group+"/test-secret", `{"keys":["a","c"],"add":["release"],"remove":["sprint-2"]}`)
+		assert.Equal(t, http.StatusOK, code)
+		assert.EqualValues(t, 2, body["count"])
+
+		toggle, err := srv.store.Get(context.Background(), group+"|c")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"release"}, []string(toggle.Tags))
+		toggle, err = srv.store.Get(context.Background(), group+"|a")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"sprint-1", "release"}, []string(toggle.Tags))
+	})
+
+	errorCases := []struct {
+		name   string
+		path   string
+		body   string
+		status int
+	}{
+		{"invalid secret", "/bulk/activate/" + group + "/wrong?tags=sprint-1", "", http.StatusUnauthorized},
+		{"no selection", "/bulk/deactivate/" + group + "/test-secret", "", http.StatusBadRequest},
+		{"invalid expression", "/bulk/deactivate/" + group + "/test-secret?tags=a%7C", "", http.StatusBadRequest},
+		{"invalid date", "/bulk/activateAt/" + group + "/tomorrow/test-secret?tags=a", "", http.StatusBadRequest},
+		{"invalid tag", "/bulk/tags/" + group + "/test-secret?tags=a", `{"add":["!x"]}`, http.StatusBadRequest},
+		{"no tags", "/bulk/tags/" + group + "/test-secret?tags=a", `{}`, http.StatusBadRequest},
+	}
+	for _, tt := range errorCases {
+		t.Run(tt.name, func(t *testing.T) {
+			code, body := request(tt.path, tt.body)
+			assert.Equal(t, tt.status, code)
+			assert.Contains(t, body, "error")
+		})
+	}
+}
+
+func TestCollectionHashFilters(t *testing.T) {
+	srv := setupTestServer(t)
+	group := uuid.New().String()
+	soon := time.Now().Add(time.Hour)
+	for _, toggle := range []store.FeatureToggle{
+		{Key: group + "|a", Value: "true", Tags: []string{"web"}},
+		{Key: group + "|b", Value: "false", Tags: []string{"web"}, ActiveAt: &soon},
+		{Key: group + "|c", Value: "false", Tags: []string{"mobile"}},
+	} {
+		toggle := toggle
+		require.NoError(t, srv.store.Create(context.Background(), &toggle))
+	}
+
+	get := func(path string, etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		return w
+	}
+	hash := func(query string) string {
+		w := get("/collectionHash/"+group+"?"+query, "")
+		require.Equal(t, http.StatusOK, w.Code)
+		var body map[string]string
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		return body["collectionHash"]
+	}
+
+	web := hash("tags=web")
+	assert.NotEqual(t, hash(""), web)
+
+	// The ETag of the filtered listing is the hash of the same filters
+	w := get("/features/"+group+"?tags=web", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"`+web+`"`, w.Header().Get("ETag"))
+	w = get("/features/"+group+"?tags=web&limit=1", `"`+web+`"`)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.String())
+
+	// Changes outside the filter keep the hash
+	toggle, err := srv.store.Get(context.Background(), group+"|c")
+	require.NoError(t, err)
+	toggle.Value = "true"
+	require.NoError(t, srv.store.Save(context.Background(), &toggle))
+	assert.Equal(t, web, hash("tags=web"))
+
+	// A passing schedule changes the served value and the hash
+	toggle, err = srv.store.Get(context.Background(), group+"|b")
+	require.NoError(t, err)
+	past := time.Now().Add(-time.Minute)
+	toggle.ActiveAt = &past
+	toggle.Value = "false"
+	require.NoError(t, srv.store.Save(context.Background(), &toggle))
+	assert.NotEqual(t, web, hash("tags=web"))
+
+	t.Run("single toggle", func(t *testing.T) {
+		w := get("/features/"+group+"|a", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		etag := w.Header().Get("ETag")
+		require.NotEmpty(t, etag)
+		assert.Equal(t, http.StatusNotModified, get("/features/"+group+"|a", etag).Code)
+		assert.Equal(t, http.StatusOK, get("/features/"+group+"|a", `"other"`).Code)
+	})
+
+	t.Run("invalid filter", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, get("/collectionHash/"+group+"?schedule=soon", "").Code)
+	})
+
+	t.Run("no match", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, get("/collectionHash/"+group+"?tags=none", "").Code)
+	})
+}
+
+// Benchmark Tests
+func BenchmarkPrependUUID(b *testing.B) {
+	srv := setupTestServer(b)
+
+	for i := 0; i < b.N; i++ {
+		srv.prependUUID(context.Background(), "benchmarkfeature")
+	}
+}
+
+func BenchmarkGenerateSecret(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		generateSecret()
+	}
+}
+
+func BenchmarkIsURLParseable(b *testing.B) {
+	secret := "test-secret-123-456-789"
+	for i := 0; i < b.N; i++ {
+		isURLParseable(secret)
+	}
+}
+func TestMoveFeature(t *testing.T) {
+	srv := setupTestServer(t)
+	source := uuid.New().String()
+	target := uuid.New().String()
+	for _, toggle := range []store.FeatureToggle{
+		{Key: source + "|a", Value: "true", Secret: "source-secret"},
+		{Key: source + "|b", Value: "false", Secret: "source-secret"},
+		{Key: target + "|c", Value: "false", Secret: "target-secret"},
+	} {
+		toggle := toggle
+		require.NoError(t, srv.store.Create(context.Background(), &toggle))
+	}
+
+	request := func(method string, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+
+	t.Run("rename with alias", func(t *testing.T) {
+		w := request("PUT", "/features/move/"+source+"|a/source-secret/"+source+"|renamed/source-secret?alias=1h")
+		require.Equal(t, http.StatusOK, w.Code)
+		var body map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		assert.Equal(t, source+"|renamed", body["key"])
+		assert.Contains(t, body, "aliasUntil")
+
+		// The old key resolves to the new toggle until the alias expires
+		w = request("GET", "/features/"+source+"|a")
+		require.Equal(t, http.StatusOK, w.Code)
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		assert.Equal(t, source+"|renamed", body["key"])
+		assert.NotEmpty(t, w.Header().Get("Deprecation"))
+		assert.NotEmpty(t, w.Header().Get("Sunset"))
+		assert.Equal(t, "/features/"+source+"|renamed", w.Header().Get("Content-Location"))
+	})
+
+	t.Run("alias below a prefix", func(t *testing.T) {
+		prefixed := NewServer(srv.store, Options{Validation: strictValidation, Prefix: "/toggles"})
+		t.Cleanup(func() { prefixed.Close() })
+
+		w := httptest.NewRecorder()
+		prefixed.ServeHTTP(w, httptest.NewRequest("GET", "/toggles/features/"+source+"|a", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "/toggles/features/"+source+"|renamed", w.Header().Get("Content-Location"))
+
+		w = httptest.NewRecorder()
+		prefixed.ServeHTTP(w, httptest.NewRequest("GET", "/toggles/v2/groups/"+source+"/toggles/a", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "/toggles/v2/groups/"+source+"/toggles/renamed", w.Header().Get("Content-Location"))
+	})
+
+	t.Run("move into another group", func(t *testing.T) {
+		w := request("PUT", "/features/move/"+source+"|b/source-secret/"+target+"|b/target-secret")
+		require.Equal(t, http.StatusOK, w.Code)
+
+		toggle, err := srv.store.Get(context.Background(), target+"|b")
+		require.NoError(t, err)
+		assert.Equal(t, "target-secret", toggle.Secret)
+		assert.Equal(t, http.StatusNotFound, request("GET", "/features/"+source+"|b").Code)
+	})
+
+	errorCases := []struct {
+		name   string
+		path   string
+		status int
+	}{
+		{"invalid source secret", "/features/move/" + source + "|renamed/wrong/" + target + "|x/target-secret", http.StatusUnauthorized},
+		{"invalid target secret", "/features/move/" + source + "|renamed/source-secret/" + target + "|x/wrong", http.StatusUnauthorized},
+		{"unknown target group", "/features/move/" + source + "|renamed/source-secret/" + uuid.New().String() + "|x/source-secret", http.StatusUnauthorized},
+		{"target without name", "/features/move/" + source + "|renamed/source-secret/" + target + "|/target-secret", http.StatusBadRequest},
+		{"target without UUID", "/features/move/" + source + "|renamed/source-secret/x/target-secret", http.StatusBadRequest},
+		{"invalid alias", "/features/move/" + source + "|renamed/source-secret/" + target + "|x/target-secret?alias=forever", http.StatusBadRequest},
+		{"target exists", "/features/move/" + source + "|renamed/source-secret/" + target + "|c/target-secret", http.StatusConflict},
+	}
+	for _, tt := range errorCases {
+		t.Run(tt.name, func(t *testing.T) {
+			w := request("PUT", tt.path)
+			assert.Equal(t, tt.status, w.Code)
+			assert.Contains(t, w.Body.String(), "error")
+		})
+	}
+}
+
+func TestCloneGroup(t *testing.T) {
+	srv := setupTestServer(t)
+	group := uuid.New().String()
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+	for _, toggle := range []store.FeatureToggle{
+		{Key: group + "|a", Value: "false", Secret: "test-secret", Tags: []string{"web"}, ActiveAt: &past},
+		{Key: group + "|b", Value: "true", Secret: "test-secret", Tags: []string{"web"}, DisabledAt: &future},
+		{Key: group + "|c", Value: "false", Secret: "test-secret", Tags: []string{"mobile"}},
+	} {
+		toggle := toggle
+		require.NoError(t, srv.store.Create(context.Background(), &toggle))
+	}
+
+	request := func(path string) (int, map[string]interface{}) {
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, httptest.NewRequest("POST", path, nil))
+		var response map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return w.Code, response
+	}
+
+	t.Run("all toggles", func(t *testing.T) {
+		code, body := request("/clone/" + group + "/test-secret")
+		require.Equal(t, http.StatusCreated, code)
+		clone := body["uuid"].(string)
+		assert.NotEqual(t, group, clone)
+		assert.EqualValues(t, 3, body["count"])
+		assert.True(t, isURLParseable(body["secret"].(string)))
+
+		toggle, err := srv.store.Get(context.Background(), clone+"|b")
+		require.NoError(t, err)
+		assert.Equal(t, "true", toggle.Value)
+		assert.Equal(t, []string{"web"}, []string(toggle.Tags))
+		require.NotNil(t, toggle.DisabledAt)
+		assert.Equal(t, body["secret"], toggle.Secret)
+
+		// The source group keeps its secret
+		match, err := srv.store.SecretsMatch(context.Background(), group, "test-secret")
+		require.NoError(t, err)
+		assert.True(t, match)
+	})
+
+	t.Run("by tag with reset schedules", func(t *testing.T) {
+		code, body := request("/clone/" + group + "/test-secret?tags=web&resetSchedules=true")
+		require.Equal(t, http.StatusCreated, code)
+		clone := body["uuid"].(string)
+		assert.Equal(t, []interface{}{clone + "|a", clone + "|b"}, body["keys"])
+
+		toggle, err := srv.store.Get(context.Background(), clone+"|a")
+		require.NoError(t, err)
+		assert.Equal(t, "true", toggle.Value)
+		assert.Nil(t, toggle.ActiveAt)
+		toggle, err = srv.store.Get(context.Background(), clone+"|b")
+		require.NoError(t, err)
+		assert.Equal(t, "true", toggle.Value)
+		assert.Nil(t, toggle.DisabledAt)
+	})
+
+	errorCases := []struct {
+		name   string
+		path   string
+		status int
+	}{
+		{"invalid secret", "/clone/" + group + "/wrong", http.StatusUnauthorized},
+		{"unknown group", "/clone/" + uuid.New().String() + "/test-secret", http.StatusUnauthorized},
+		{"invalid expression", "/clone/" + group + "/test-secret?tags=a%7C", http.StatusBadRequest},
+		{"no match", "/clone/" + group + "/test-secret?tags=desktop", http.StatusNotFound},
+	}
+	for _, tt := range errorCases {
+		t.Run(tt.name, func(t *testing.T) {
+			code, body := request(tt.path)
+			assert.Equal(t, tt.status, code)
+			assert.Contains(t, body, "error")
+		})
+	}
+}
+
+func TestCheckNewSecret(t *testing.T) {
+	tests := []struct {
+		name   string
+		secret string
+		valid  bool
+	}{
+		{"generated", generateSecret(), true},
+		{"long and varied", "correct-horse-battery-staple-1234", true},
+		{"too short", "Sh0rt-but-varied", false},
+		{"repetitive", strings.Repeat("ab", 32), false},
+		{"not URL parseable", "correct horse battery staple 1234%", false},
+		{"same as old", "old-secret-old-secret-old-secret-0123", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkNewSecret(tt.secret, "old-secret-old-secret-old-secret-0123")
+			assert.Equal(t, tt.valid, err == nil, err)
+		})
+	}
+}
+
+func TestRotateSecret(t *testing.T) {
+	srv := NewServer(store.NewMemory(), Options{Validation: strictValidation, SecretRotation: SecretRotation{Overlap: time.Hour, MaxOverlap: 24 * time.Hour}})
+	t.Cleanup(func() { srv.Close() })
+	group := uuid.New().String()
+	require.NoError(t, srv.store.Create(context.Background(), &store.FeatureToggle{Key: group + "|a", Value: "true", Secret: "old-secret"}))
+
+	request := func(method string, path string, body string) (int, map[string]interface{}) {
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+		var response map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return w.Code, response
+	}
+
+	code, _ := request("GET", "/secret/status/"+group+"/old-secret", "")
+	assert.Equal(t, http.StatusNotFound, code)
+
+	code, body := request("PUT", "/secret/rotate/"+group+"/old-secret", "")
+	require.Equal(t, http.StatusOK, code)
+	newSecret := body["secret"].(string)
+	assert.NoError(t, checkNewSecret(newSecret, "old-secret"))
+
+	// Both secrets work during the overlap
+	code, _ = request("PUT", "/features/activate/"+group+"|a/"+newSecret, "")
+	assert.Equal(t, http.StatusOK, code)
+	code, _ = request("PUT", "/features/deactivate/"+group+"|a/old-secret", "")
+	assert.Equal(t, http.StatusOK, code)
+
+	// Toggles created with the old secret get the new one
+	code, _ = request("POST", "/features", `{"key":"`+group+`|0","value":"true","secret":"old-secret"}`)
+	require.Equal(t, http.StatusCreated, code)
+	toggle, err := srv.store.Get(context.Background(), group+"|0")
+	require.NoError(t, err)
+	assert.Equal(t, newSecret, toggle.Secret)
+
+	code, body = request("GET", "/secret/status/"+group+"/"+newSecret, "")
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, true, body["previousSecretValid"])
+	assert.NotNil(t, body["previousSecretLastUsedAt"])
+
+	// Only the current secret manages the secret, even during the overlap
+	code, _ = request("PUT", "/secret/update/"+group+"/old-secret/correct-horse-battery-staple-5678", "")
+	assert.Equal(t, http.StatusUnauthorized, code)
+	code, _ = request("PUT", "/secret/rotate/"+group+"/old-secret", "")
+	assert.Equal(t, http.StatusUnauthorized, code)
+	code, _ = request("GET", "/secret/status/"+group+"/old-secret", "")
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	t.Run("client chosen secret without overlap", func(t *testing.T) {
+		chosen := "correct-horse-battery-staple-1234"
+		code, body := request("PUT", "/secret/rotate/"+group+"/"+newSecret+"/"+chosen+"?overlap=0s", "")
+		require.Equal(t, http.StatusOK, code)
+		assert.NotContains(t, body, "secret")
+
+		code, _ = request("PUT", "/features/activate/"+group+"|a/"+newSecret, "")
+		assert.Equal(t, http.StatusUnauthorized, code)
+		code, body = request("GET", "/secret/status/"+group+"/"+chosen, "")
+		require.Equal(t, http.StatusOK, code)
+		assert.Equal(t, false, body["previousSecretValid"])
+		assert.Nil(t, body["previousSecretLastUsedAt"])
+	})
+
+	errorCases := []struct {
+		name   string
+		path   string
+		status int
+	}{
+		{"invalid secret", "/secret/rotate/" + group + "/wrong", http.StatusUnauthorized},
+		{"weak secret", "/secret/rotate/" + group + "/correct-horse-battery-staple-1234/password", http.StatusNotAcceptable},
+		{"weak secret on update", "/secret/update/" + group + "/correct-horse-battery-staple-1234/password", http.StatusNotAcceptable},
+		{"overlap above max", "/secret/rotate/" + group + "/correct-horse-battery-staple-1234?overlap=25h", http.StatusBadRequest},
+		{"invalid overlap", "/secret/rotate/" + group + "/correct-horse-battery-staple-1234?overlap=-1h", http.StatusBadRequest},
+	}
+	for _, tt := range errorCases {
+		t.Run(tt.name, func(t *testing.T) {
+			code, body := request("PUT", tt.path, "")
+			assert.Equal(t, tt.status, code)
+			assert.Contains(t, body, "error")
+		})
+	}
+}
+
+func TestAdmin(t *testing.T) {
+	const token = "master-token-master-token-master-token"
+	srv := NewServer(store.NewMemory(), Options{Validation: strictValidation, AdminToken: token})
+	t.Cleanup(func() { srv.Close() })
+	group, other := uuid.New().String(), uuid.New().String()
+	for _, toggle := range []store.FeatureToggle{
+		{Key: group + "|a", Value: "true", Secret: "test-secret"},
+		{Key: group + "|b", Value: "false", Secret: "test-secret"},
+		{Key: other + "|a", Value: "true", Secret: "other-secret"},
+	} {
+		toggle := toggle
+		require.NoError(t, srv.store.Create(context.Background(), &toggle))
+	}
+
+	request := func(method string, path string, token string) (int, map[string]interface{}) {
+		req := httptest.NewRequest(method, path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		var response map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return w.Code, response
+	}
+
+	t.Run("requires the token", func(t *testing.T) {
+		code, _ := request("GET", "/admin/groups", "")
+		assert.Equal(t, http.StatusUnauthorized, code)
+		code, _ = request("GET", "/admin/groups", "wrong")
+		assert.Equal(t, http.StatusUnauthorized, code)
+	})
+
+	t.Run("list groups and stats", func(t *testing.T) {
+		code, body := request("GET", "/admin/groups", token)
+		require.Equal(t, http.StatusOK, code)
+		assert.EqualValues(t, 2, body["total"])
+
+		code, body = request("GET", "/admin/stats", token)
+		require.Equal(t, http.StatusOK, code)
+		assert.EqualValues(t, 2, body["groups"])
+		assert.EqualValues(t, 3, body["toggles"])
+	})
+
+	t.Run("freeze", func(t *testing.T) {
+		code, _ := request("PUT", "/admin/groups/"+group+"/freeze", token)
+		require.Equal(t, http.StatusOK, code)
+
+		code, body := request("PUT", "/features/activate/"+group+"|b/test-secret", "")
+		assert.Equal(t, http.StatusForbidden, code)
+		assert.Equal(t, "Group is frozen", body["error"])
+		code, _ = request("GET", "/features/"+group+"|b", "")
+		assert.Equal(t, http.StatusOK, code)
+		// Cloning only reads the frozen group
+		code, _ = request("POST", "/clone/"+group+"/test-secret", "")
+		assert.Equal(t, http.StatusCreated, code)
+
+		code, _ = request("DELETE", "/admin/groups/"+group+"/freeze", token)
+		require.Equal(t, http.StatusOK, code)
+		code, _ = request("PUT", "/features/activate/"+group+"|b/test-secret", "")
+		assert.Equal(t, http.StatusOK, code)
+	})
+
+	t.Run("reset secret", func(t *testing.T) {
+		code, body := request("PUT", "/admin/groups/"+group+"/secret", token)
+		require.Equal(t, http.StatusOK, code)
+		secret := body["secret"].(string)
+
+		code, _ = request("PUT", "/features/deactivate/"+group+"|b/test-secret", "")
+		assert.Equal(t, http.StatusUnauthorized, code)
+		code, _ = request("PUT", "/features/deactivate/"+group+"|b/"+secret, "")
+		assert.Equal(t, http.StatusOK, code)
+	})
+
+	t.Run("delete group", func(t *testing.T) {
+		code, _ := request("DELETE", "/admin/groups/"+other, token)
+		require.Equal(t, http.StatusOK, code)
+		code, _ = request("GET", "/features/"+other+"|a", "")
+		assert.Equal(t, http.StatusNotFound, code)
+		code, _ = request("DELETE", "/admin/groups/"+other, token)
+		assert.Equal(t, http.StatusNotFound, code)
+	})
+
+	t.Run("audit", func(t *testing.T) {
+		code, body := request("GET", "/admin/audit?group="+other, token)
+		require.Equal(t, http.StatusOK, code)
+		entries := body["entries"].([]interface{})
+		require.Len(t, entries, 2)
+		newest := entries[0].(map[string]interface{})
+		assert.Equal(t, "DELETE /admin/groups/:uuid", newest["action"])
+		assert.EqualValues(t, http.StatusNotFound, newest["status"])
+
+		// Rejected requests are audited as well
+		code, body = request("GET", "/admin/audit?limit=1000", token)
+		require.Equal(t, http.StatusOK, code)
+		var unauthorized int
+		for _, entry := range body["entries"].([]interface{}) {
+			if entry.(map[string]interface{})["status"] == float64(http.StatusUnauthorized) {
+				unauthorized++
+			}
+		}
+		assert.Equal(t, 2, unauthorized)
+
+		code, _ = request("GET", "/admin/audit?limit=0", token)
+		assert.Equal(t, http.StatusBadRequest, code)
+	})
+
+	t.Run("disabled without token", func(t *testing.T) {
+		srv := setupTestServer(t)
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, httptest.NewRequest("GET", "/admin/groups", nil))
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestAdmission(t *testing.T) {
+	const token = "master-token-master-token-master-token"
+	create := func(srv *Server, query string, header string) (int, map[string]interface{}) {
+		req := httptest.NewRequest("POST", "/features"+query, strings.NewReader(`{"key":"new","value":"true"}`))
+		if header != "" {
+			req.Header.Set("Authorization", "Bearer "+header)
+		}
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		var response map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return w.Code, response
+	}
+
+	t.Run("admin only", func(t *testing.T) {
+		srv := NewServer(store.NewMemory(), Options{Validation: strictValidation, AdminToken: token, Admission: Admission{Policy: AdmissionAdmin}})
+		t.Cleanup(func() { srv.Close() })
+
+		code, _ := create(srv, "", "")
+		assert.Equal(t, http.StatusForbidden, code)
+		code, _ = create(srv, "", token)
+		assert.Equal(t, http.StatusCreated, code)
+	})
+
+	t.Run("admin only locks out token guesses", func(t *testing.T) {
+		srv := NewServer(store.NewMemory(), Options{Validation: strictValidation, AdminToken: token, Admission: Admission{Policy: AdmissionAdmin}, RateLimit: RateLimit{
+			Lockout: Lockout{Failures: 2, Duration: time.Minute, MaxDuration: time.Hour},
+		}})
+		t.Cleanup(func() { srv.Close() })
+
+		for i := 0; i < 2; i++ {
+			code, _ := create(srv, "", "wrong")
+			assert.Equal(t, http.StatusForbidden, code)
+		}
+		code, body := create(srv, "", token)
+		assert.Equal(t, http.StatusTooManyRequests, code)
+		assert.Equal(t, "Too many invalid secrets", body["error"])
+
+		// The lockout is shared with the admin API
+		req := httptest.NewRequest("GET", "/admin/stats", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	})
+
+	t.Run("quota", func(t *testing.T) {
+		srv := NewServer(store.NewMemory(), Options{Validation: strictValidation, Admission: Admission{Policy: AdmissionQuota, GroupsPerIP: 2}})
+		t.Cleanup(func() { srv.Close() })
+
+		for i := 0; i < 2; i++ {
+			code, _ := create(srv, "", "")
+			require.Equal(t, http.StatusCreated, code)
+		}
+		code, body := create(srv, "", "")
+		assert.Equal(t, http.StatusForbidden, code)
+		assert.Equal(t, "Group quota exceeded", body["error"])
+	})
+
+	t.Run("quota ignores spoofed forwarded header", func(t *testing.T) {
+		srv := NewServer(store.NewMemory(), Options{Validation: strictValidation, Admission: Admission{Policy: AdmissionQuota, GroupsPerIP: 1}})
+		t.Cleanup(func() { srv.Close() })
+
+		spoofed := func(forwardedFor string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest("POST", "/features", strings.NewReader(`{"key":"new","value":"true"}`))
+			req.Header.Set("X-Forwarded-For", forwardedFor)
+			w := httptest.NewRecorder()
+			srv.ServeHTTP(w, req)
+			return w
+		}
+
+		w := spoofed("203.0.113.1")
+		require.Equal(t, http.StatusCreated, w.Code)
+		var body map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		settings, err := srv.store.GetGroupSettings(context.Background(), store.Group(body["key"].(string)))
+		require.NoError(t, err)
+		assert.Equal(t, "192.0.2.1", settings.CreatorIP)
+
+		assert.Equal(t, http.StatusForbidden, spoofed("203.0.113.2").Code)
+	})
+
+	t.Run("invite with toggle limit", func(t *testing.T) {
+		srv := NewServer(store.NewMemory(), Options{Validation: strictValidation, AdminToken: token, Admission: Admission{Policy: AdmissionInvite}})
+		t.Cleanup(func() { srv.Close() })
+
+		code, _ := create(srv, "", "")
+		assert.Equal(t, http.StatusForbidden, code)
+
+		req := httptest.NewRequest("POST", "/admin/invites", strings.NewReader(`{"maxToggles":2,"uses":1}`))
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		require.Equal(t, http.StatusCreated, w.Code)
+		var invite map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &invite))
+		invitation := invite["token"].(string)
+
+		code, body := create(srv, "?invite="+invitation, "")
+		require.Equal(t, http.StatusCreated, code)
+		group := store.Group(body["key"].(string))
+		secret := body["secret"].(string)
+
+		// The invite was used up
+		code, _ = create(srv, "?invite="+invitation, "")
+		assert.Equal(t, http.StatusForbidden, code)
+
+		add := func(name string) int {
+			w := httptest.NewRecorder()
+			srv.ServeHTTP(w, httptest.NewRequest("POST", "/features", strings.NewReader(`{"key":"`+group+`|`+name+`","value":"true","secret":"`+secret+`"}`)))
+			return w.Code
+		}
+		assert.Equal(t, http.StatusCreated, add("second"))
+		assert.Equal(t, http.StatusForbidden, add("third"))
+	})
+
+	t.Run("invite is given back if the group is not created", func(t *testing.T) {
+		srv := NewServer(store.NewMemory(), Options{Validation: strictValidation, AdminToken: token, Admission: Admission{Policy: AdmissionInvite}})
+		t.Cleanup(func() { srv.Close() })
+		source := uuid.New().String()
+		for _, name := range []string{"a", "b"} {
+			require.NoError(t, srv.store.Create(context.Background(), &store.FeatureToggle{Key: source + "|" + name, Value: "true", Secret: "test-secret"}))
+		}
+
+		req := httptest.NewRequest("POST", "/admin/invites", strings.NewReader(`{"maxToggles":1,"uses":1}`))
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		require.Equal(t, http.StatusCreated, w.Code)
+		var invite map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &invite))
+		invitation := invite["token"].(string)
+
+		// Cloning two toggles exceeds the toggle limit of the invite
+		w = httptest.NewRecorder()
+		srv.ServeHTTP(w, httptest.NewRequest("POST", "/clone/"+source+"/test-secret?invite="+invitation, nil))
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		code, _ := create(srv, "?invite="+invitation, "")
+		assert.Equal(t, http.StatusCreated, code)
+		code, _ = create(srv, "?invite="+invitation, "")
+		assert.Equal(t, http.StatusForbidden, code)
+	})
+
+	t.Run("invalid invite", func(t *testing.T) {
+		srv := setupTestServer(t)
+		code, body := create(srv, "?invite=unknown", "")
+		assert.Equal(t, http.StatusForbidden, code)
+		assert.Equal(t, "Invalid invite token", body["error"])
+	})
+}
+
+func TestGRPC(t *testing.T) {
+	srv := setupTestServer(t)
+	group := uuid.New().String()
+	past := time.Now().Add(-time.Hour)
+	for _, toggle := range []store.FeatureToggle{
+		{Key: group + "|a", Value: "false", Secret: "test-secret", Tags: []string{"web"}, ActiveAt: &past},
+		{Key: group + "|b", Value: "false", Secret: "test-secret", Tags: []string{"mobile"}},
+	} {
+		toggle := toggle
+		require.NoError(t, srv.store.Create(context.Background(), &toggle))
+	}
+
+	listener := bufconn.Listen(1 << 20)
+	grpcServer := NewGRPCServer(srv, GRPCOptions{WatchInterval: 10 * time.Millisecond})
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	client := yaftv1.NewToggleServiceClient(conn)
+	ctx := context.Background()
+	authorized := metadata.AppendToOutgoingContext(ctx, SecretMetadata, "test-secret")
+
+	t.Run("get toggle evaluates the schedule", func(t *testing.T) {
+		response, err := client.GetToggle(ctx, &yaftv1.GetToggleRequest{Key: group + "|a"})
+		require.NoError(t, err)
+		assert.True(t, response.GetToggle().GetValue())
+		assert.Equal(t, []string{"web"}, response.GetToggle().GetTags())
+		assert.NotNil(t, response.GetToggle().GetActiveAt())
+	})
+
+	t.Run("get toggle resolves aliases", func(t *testing.T) {
+		moved := uuid.New().String()
+		require.NoError(t, srv.store.Create(ctx, &store.FeatureToggle{Key: moved + "|old", Value: "true", Secret: "test-secret"}))
+		aliasUntil := time.Now().Add(time.Hour)
+		_, err := srv.store.Move(ctx, moved+"|old", moved+"|new", "test-secret", &aliasUntil)
+		require.NoError(t, err)
+
+		response, err := client.GetToggle(ctx, &yaftv1.GetToggleRequest{Key: moved + "|old"})
+		require.NoError(t, err)
+		assert.Equal(t, moved+"|new", response.GetToggle().GetKey())
+		assert.True(t, response.GetToggle().GetValue())
+	})
+
+	t.Run("list group by tags", func(t *testing.T) {
+		response, err := client.ListGroup(ctx, &yaftv1.ListGroupRequest{Group: group, Tags: "mobile"})
+		require.NoError(t, err)
+		require.Len(t, response.GetToggles(), 1)
+		assert.Equal(t, group+"|b", response.GetToggles()[0].GetKey())
+	})
+
+	t.Run("evaluate", func(t *testing.T) {
+		response, err := client.Evaluate(ctx, &yaftv1.EvaluateRequest{Keys: []string{group + "|a", group + "|unknown"}})
+		require.NoError(t, err)
+		require.Len(t, response.GetEvaluations(), 2)
+		assert.True(t, response.GetEvaluations()[0].GetFound())
+		assert.True(t, response.GetEvaluations()[0].GetValue())
+		assert.False(t, response.GetEvaluations()[1].GetFound())
+	})
+
+	t.Run("mutations and errors", func(t *testing.T) {
+		_, err := client.SetValue(ctx, &yaftv1.SetValueRequest{Key: group + "|b", Value: true})
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+		response, err := client.SetValue(authorized, &yaftv1.SetValueRequest{Key: group + "|b", Value: true})
+		require.NoError(t, err)
+		assert.True(t, response.GetToggle().GetValue())
+
+		created, err := client.CreateToggle(authorized, &yaftv1.CreateToggleRequest{Toggle: &yaftv1.Toggle{Key: group + "|c", Kind: "release"}})
+		require.NoError(t, err)
+		assert.False(t, created.GetToggle().GetValue())
+		_, err = client.CreateToggle(authorized, &yaftv1.CreateToggleRequest{Toggle: &yaftv1.Toggle{Key: group + "|c"}})
+		assert.Equal(t, codes.AlreadyExists, status.Code(err))
+		_, err = client.CreateToggle(authorized, &yaftv1.CreateToggleRequest{Toggle: &yaftv1.Toggle{Key: "new"}})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+		_, err = client.SetSchedule(authorized, &yaftv1.SetScheduleRequest{
+			Key:        group + "|c",
+			ActiveAt:   timestamppb.New(time.Now().Add(time.Hour)),
+			DisabledAt: timestamppb.New(time.Now()),
+		})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+		_, err = client.DeleteToggle(authorized, &yaftv1.DeleteToggleRequest{Key: group + "|c"})
+		require.NoError(t, err)
+		_, err = client.GetToggle(ctx, &yaftv1.GetToggleRequest{Key: group + "|c"})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+
+		_, err = client.ListGroup(ctx, &yaftv1.ListGroupRequest{Group: "not-a-uuid"})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		_, err = client.ListGroup(ctx, &yaftv1.ListGroupRequest{Group: uuid.New().String()})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("watch", func(t *testing.T) {
+		watchCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		stream, err := client.Watch(watchCtx, &yaftv1.WatchRequest{Group: group})
+		require.NoError(t, err)
+
+		initial := map[string]bool{}
+		for i := 0; i < 2; i++ {
+			event, err := stream.Recv()
+			require.NoError(t, err)
+			assert.Equal(t, yaftv1.WatchResponse_TYPE_CHANGED, event.GetType())
+			initial[event.GetToggle().GetKey()] = event.GetToggle().GetValue()
+		}
+		assert.Equal(t, map[string]bool{group + "|a": true, group + "|b": true}, initial)
+
+		_, err = client.SetValue(authorized, &yaftv1.SetValueRequest{Key: group + "|b", Value: false})
+		require.NoError(t, err)
+		event, err := stream.Recv()
+		require.NoError(t, err)
+		assert.Equal(t, yaftv1.WatchResponse_TYPE_CHANGED, event.GetType())
+		assert.Equal(t, group+"|b", event.GetToggle().GetKey())
+		assert.False(t, event.GetToggle().GetValue())
+
+		require.NoError(t, srv.store.Delete(ctx, group+"|a"))
+		event, err = stream.Recv()
+		require.NoError(t, err)
+		assert.Equal(t, yaftv1.WatchResponse_TYPE_DELETED, event.GetType())
+		assert.Equal(t, group+"|a", event.GetToggle().GetKey())
+	})
+}
+
+// failingListStore fails to list toggles once fail is set
+type failingListStore struct {
+	store.Store
+	fail atomic.Bool
+}
+
+func (s *failingListStore) List(ctx context.Context, filter store.Filter) ([]store.FeatureToggle, error) {
+	if s.fail.Load() {
+		return nil, errors.New("database is gone")
+	}
+	return s.Store.List(ctx, filter)
+}
+
+func TestGRPCWatchFailures(t *testing.T) {
+	testStore := &failingListStore{Store: store.NewMemory()}
+	group := uuid.New().String()
+	require.NoError(t, testStore.Create(context.Background(), &store.FeatureToggle{Key: group + "|a", Value: "true", Secret: "test-secret"}))
+	logger, hook := logtest.NewNullLogger()
+	srv := NewServer(testStore, Options{Logger: logger})
+	t.Cleanup(func() { srv.Close() })
+
+	listener := bufconn.Listen(1 << 20)
+	grpcServer := NewGRPCServer(srv, GRPCOptions{WatchInterval: 10 * time.Millisecond})
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stream, err := yaftv1.NewToggleServiceClient(conn).Watch(ctx, &yaftv1.WatchRequest{Group: group})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	require.NoError(t, err)
+
+	// Repeated store errors end the stream, each of them is logged with the group
+	testStore.fail.Store(true)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+
+	var polls int
+	for _, entry := range hook.AllEntries() {
+		if entry.Message == "Failed to poll watched group" {
+			assert.Equal(t, group, entry.Data["group"])
+			polls++
+		}
+	}
+	assert.Equal(t, maxWatchFailures, polls)
+}
+
+func TestUI(t *testing.T) {
+	srv := NewServer(store.NewMemory(), Options{UI: true, Prefix: "/toggles"})
+	t.Cleanup(func() { srv.Close() })
+	disabled := NewServer(store.NewMemory(), Options{})
+	t.Cleanup(func() { disabled.Close() })
+
+	tests := []struct {
+		name        string
+		srv         *Server
+		path        string
+		status      int
+		contentType string
+		body        string
+	}{
+		{"redirect", srv, "/toggles/ui", http.StatusMovedPermanently, "", ""},
+		{"page", srv, "/toggles/ui/", http.StatusOK, "text/html", `<script src="app.js" defer></script>`},
+		{"script", srv, "/toggles/ui/app.js", http.StatusOK, "javascript", "'X-Client-ID': 'yaft-ui'"},
+		{"stylesheet", srv, "/toggles/ui/style.css", http.StatusOK, "text/css", ""},
+		{"logo", srv, "/toggles/ui/logo.svg", http.StatusOK, "image/svg+xml", ""},
+		{"unknown file", srv, "/toggles/ui/missing.js", http.StatusNotFound, "", ""},
+		{"disabled", disabled, "/ui/", http.StatusNotFound, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			tt.srv.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+
+			assert.Equal(t, tt.status, w.Code)
+			assert.Contains(t, w.Header().Get("Content-Type"), tt.contentType)
+			assert.Contains(t, w.Body.String(), tt.body)
+			if tt.status == http.StatusOK {
+				assert.Contains(t, w.Header().Get("Content-Security-Policy"), "script-src 'self'")
+				assert.Equal(t, "DENY", w.Header().Get("X-Frame-Options"))
+			}
+		})
+	}
+}
+
+func TestOpenAPI(t *testing.T) {
+	const token = "master-token-master-token-master-token"
+	srv := NewServer(store.NewMemory(), Options{Prefix: "/toggles", AdminToken: token, UI: true, Validation: Validation{Requests: true}})
+	t.Cleanup(func() { srv.Close() })
+	group := uuid.New().String()
+	toggle := store.FeatureToggle{Key: group + "|a", Value: "true", Secret: "test-secret"}
+	require.NoError(t, srv.store.Create(context.Background(), &toggle))
+
+	t.Run("served document", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, httptest.NewRequest("GET", "/toggles/openapi.json", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var document struct {
+			OpenAPI string                     `json:"openapi"`
+			Servers []map[string]string        `json:"servers"`
+			Paths   map[string]json.RawMessage `json:"paths"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &document))
+		assert.Equal(t, "3.0.3", document.OpenAPI)
+		assert.Equal(t, []map[string]string{{"url": "/toggles/"}}, document.Servers)
+		assert.Contains(t, document.Paths, "/features/{key}")
+	})
+
+	t.Run("every route is documented", func(t *testing.T) {
+		param := regexp.MustCompile(`[:*]([A-Za-z]+)`)
+		registered := map[string]bool{}
+		for _, route := range srv.handler.(*gin.Engine).Routes() {
+			path := strings.TrimPrefix(route.Path, "/toggles")
+			if path == "/ui" || strings.HasPrefix(path, "/ui/") {
+				continue
+			}
+			registered[route.Method+" "+param.ReplaceAllString(path, "{$1}")] = true
+		}
+
+		documented := map[string]bool{}
+		for path, item := range srv.openAPI.document.Paths.Map() {
+			for method := range item.Operations() {
+				documented[method+" "+path] = true
+			}
+		}
+		assert.Equal(t, registered, documented)
+	})
+
+	t.Run("request validation", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			method string
+			path   string
+			body   string
+			status int
+			error  string
+		}{
+			{"valid listing", "GET", "/toggles/features/" + group + "?limit=10", "", http.StatusOK, ""},
+			{"limit too large", "GET", "/toggles/features/" + group + "?limit=5000", "", http.StatusBadRequest, "Invalid query parameter limit: number must be at most 1000"},
+			{"unknown sort", "GET", "/toggles/features/" + group + "?sort=name", "", http.StatusBadRequest, "Invalid query parameter sort: value is not one of the allowed values"},
+			{"invalid value", "POST", "/toggles/features", `{"key":"new","value":"yes"}`, http.StatusBadRequest, "Invalid request body: field value: value is not one of the allowed values"},
+			{"missing key", "POST", "/toggles/features", `{"value":"true"}`, http.StatusBadRequest, `property "key" is missing`},
+			{"valid creation", "POST", "/toggles/features", `{"key":"new","value":"true","tags":["web"]}`, http.StatusCreated, ""},
+			{"undocumented route", "GET", "/toggles/ui/", "", http.StatusOK, ""},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				w := httptest.NewRecorder()
+				req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+				req.Header.Set("Content-Type", "application/json")
+				srv.ServeHTTP(w, req)
+
+				assert.Equal(t, tt.status, w.Code)
+				if tt.error != "" {
+					var response map[string]string
+					require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+					assert.Contains(t, response["error"], tt.error)
+				}
+			})
+		}
+	})
+
+	t.Run("response drift", func(t *testing.T) {
+		for _, strict := range []bool{true, false} {
+			drifting := NewServer(store.NewMemory(), Options{Validation: Validation{Responses: true, Strict: strict}})
+			defer drifting.Close()
+			engine := gin.New()
+			engine.Use(drifting.validate())
+			engine.GET("/healthz", func(c *gin.Context) {
+				c.JSON(http.StatusOK, gin.H{"Status": "ok"})
+			})
+
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, httptest.NewRequest("GET", "/healthz", nil))
+			if strict {
+				assert.Equal(t, http.StatusInternalServerError, w.Code)
+				assert.JSONEq(t, `{"error":"Response does not match the OpenAPI document"}`, w.Body.String())
+			} else {
+				assert.Equal(t, http.StatusOK, w.Code, "violations are only logged")
+				assert.JSONEq(t, `{"Status":"ok"}`, w.Body.String())
+			}
+		}
+	})
+}
+
+func TestV2(t *testing.T) {
+	srv := setupTestServer(t)
+
+	do := func(method, path, secret, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if secret != "" {
+			req.Header.Set(SecretHeader, secret)
+		}
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		return w
+	}
+	problemCode := func(t *testing.T, w *httptest.ResponseRecorder) string {
+		t.Helper()
+		assert.Equal(t, problemContentType, w.Header().Get("Content-Type"))
+		var p problem
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &p))
+		assert.Equal(t, "about:blank", p.Type)
+		assert.Equal(t, w.Code, p.Status)
+		assert.Equal(t, http.StatusText(w.Code), p.Title)
+		return p.Code
+	}
+
+	w := do("POST", "/v2/groups", "", `{"name":"checkout","value":"true","tags":["web"]}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var created struct {
+		Group  string   `json:"group"`
+		Secret string   `json:"secret"`
+		Toggle toggleV2 `json:"toggle"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	group, secret := created.Group, created.Secret
+	assert.NotEmpty(t, secret)
+	assert.Equal(t, group+"|checkout", created.Toggle.Key)
+	assert.Equal(t, "checkout", created.Toggle.Name)
+	assert.Equal(t, "/v2/groups/"+group+"/toggles/checkout", w.Header().Get("Location"))
+	base := "/v2/groups/" + group + "/toggles"
+
+	t.Run("create", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			secret string
+			body   string
+			status int
+			code   string
+		}{
+			{"created", secret, `{"name":"search","description":"New search"}`, http.StatusCreated, ""},
+			{"invalid secret", "wrong", `{"name":"other"}`, http.StatusUnauthorized, codeInvalidSecret},
+			{"missing secret", "", `{"name":"other"}`, http.StatusUnauthorized, codeInvalidSecret},
+			{"taken name", secret, `{"name":"checkout"}`, http.StatusConflict, codeToggleExists},
+			{"invalid name", secret, `{"name":"a|b"}`, http.StatusBadRequest, codeInvalidName},
+			{"invalid value", secret, `{"name":"other","value":"yes"}`, http.StatusBadRequest, codeInvalidValue},
+			{"invalid kind", secret, `{"name":"other","kind":"feature"}`, http.StatusBadRequest, codeInvalidKind},
+			{"capitalized field", secret, `{"Name":"other"}`, http.StatusBadRequest, codeInvalidRequest},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				w := do("POST", base, tt.secret, tt.body)
+				require.Equal(t, tt.status, w.Code, w.Body.String())
+				if tt.code != "" {
+					assert.Equal(t, tt.code, problemCode(t, w))
+				}
+			})
+		}
+	})
+
+	t.Run("get", func(t *testing.T) {
+		w := do("GET", base+"/search", "", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `["key","group","name","value","storedValue","activeAt","disabledAt","removeAt","tags","description","owner","kind","createdAt","updatedAt"]`, jsonKeys(t, w.Body.Bytes()))
+		var toggle toggleV2
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &toggle))
+		assert.Equal(t, "false", toggle.Value)
+		assert.Equal(t, "New search", toggle.Description)
+		assert.Equal(t, []string{}, toggle.Tags)
+
+		w = do("GET", base+"/missing", "", "")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Equal(t, codeToggleNotFound, problemCode(t, w))
+
+		w = do("GET", "/v2/groups/not-a-uuid/toggles/search", "", "")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Equal(t, codeGroupNotFound, problemCode(t, w))
+	})
+
+	t.Run("list", func(t *testing.T) {
+		w := do("GET", base+"?sort=-key&limit=1", "", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		var page struct {
+			Toggles    []toggleV2 `json:"toggles"`
+			Total      int        `json:"total"`
+			NextCursor string     `json:"nextCursor"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+		require.Len(t, page.Toggles, 1)
+		assert.Equal(t, "search", page.Toggles[0].Name)
+		assert.Equal(t, 2, page.Total)
+		assert.NotEmpty(t, page.NextCursor)
+
+		// Listed toggles have the same fields as single toggles
+		var raw struct {
+			Toggles []json.RawMessage `json:"toggles"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &raw))
+		single := do("GET", base+"/search", "", "")
+		assert.Equal(t, jsonKeys(t, single.Body.Bytes()), jsonKeys(t, raw.Toggles[0]))
+
+		w = do("GET", base+"?tags=missing", "", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"toggles":[],"total":0,"nextCursor":""}`, w.Body.String())
+
+		w = do("GET", "/v2/groups/"+uuid.New().String()+"/toggles", "", "")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Equal(t, codeGroupNotFound, problemCode(t, w))
+
+		w = do("GET", base+"?sort=name", "", "")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, codeInvalidQuery, problemCode(t, w))
+	})
+
+	t.Run("patch", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			secret string
+			body   string
+			status int
+			code   string
+		}{
+			{"invalid secret", "wrong", `{"value":"true"}`, http.StatusUnauthorized, codeInvalidSecret},
+			{"capitalized field", secret, `{"Value":"true"}`, http.StatusBadRequest, codeInvalidRequest},
+			{"secret field", secret, `{"secret":"x"}`, http.StatusBadRequest, codeInvalidRequest},
+			{"invalid value", secret, `{"value":"yes"}`, http.StatusBadRequest, codeInvalidValue},
+			{"invalid schedule", secret, `{"activeAt":"2026-11-02T00:00:00Z","disabledAt":"2026-11-01T00:00:00Z"}`, http.StatusBadRequest, codeInvalidSchedule},
+			{"scheduled", secret, `{"activeAt":"2020-01-01T00:00:00Z","tags":["web"]}`, http.StatusOK, ""},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				w := do("PATCH", base+"/search", tt.secret, tt.body)
+				require.Equal(t, tt.status, w.Code, w.Body.String())
+				if tt.code != "" {
+					assert.Equal(t, tt.code, problemCode(t, w))
+				}
+			})
+		}
+
+		var toggle toggleV2
+		require.NoError(t, json.Unmarshal(do("GET", base+"/search", "", "").Body.Bytes(), &toggle))
+		assert.Equal(t, "true", toggle.Value, "the passed activation is evaluated")
+		assert.Equal(t, "false", toggle.StoredValue)
+		assert.Equal(t, []string{"web"}, toggle.Tags)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		w := do("DELETE", base+"/search", "wrong", "")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+		w = do("DELETE", base+"/search", secret, "")
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Empty(t, w.Body.String())
+
+		w = do("DELETE", base+"/search", secret, "")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Equal(t, codeToggleNotFound, problemCode(t, w))
+	})
+
+	t.Run("unknown route", func(t *testing.T) {
+		w := do("GET", "/v2/features", "", "")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Equal(t, codeRouteNotFound, problemCode(t, w))
+
+		w = do("GET", "/features-unknown", "", "")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.NotEqual(t, problemContentType, w.Header().Get("Content-Type"))
+	})
+
+	t.Run("middleware", func(t *testing.T) {
+		denied := NewServer(store.NewMemory(), Options{
+			Validation: strictValidation,
+			Auth:       func(r *http.Request) error { return fmt.Errorf("denied") },
+		})
+		defer denied.Close()
+
+		w := httptest.NewRecorder()
+		denied.ServeHTTP(w, httptest.NewRequest("GET", base, nil))
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Equal(t, codeUnauthorized, problemCode(t, w))
+
+		// v1 routes keep their error bodies
+		w = httptest.NewRecorder()
+		denied.ServeHTTP(w, httptest.NewRequest("GET", "/features/"+group, nil))
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.JSONEq(t, `{"error":"Unauthorized"}`, w.Body.String())
+
+		validating := NewServer(store.NewMemory(), Options{Validation: Validation{Requests: true}})
+		defer validating.Close()
+		w = httptest.NewRecorder()
+		validating.ServeHTTP(w, httptest.NewRequest("GET", base+"?limit=5000", nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, codeInvalidRequest, problemCode(t, w))
+	})
+}
+
+// jsonKeys returns the field names of a JSON object in their order as a JSON array
+func jsonKeys(t *testing.T, data []byte) string {
+	t.Helper()
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	var keys []string
+	depth := 0
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			break
+		}
+		switch token {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+			continue
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+			continue
+		}
+		if key, ok := token.(string); ok && depth == 1 && decoder.More() {
+			keys = append(keys, key)
+			// Skip the value of the field
+			var value json.RawMessage
+			require.NoError(t, decoder.Decode(&value))
+		}
+	}
+	encoded, err := json.Marshal(keys)
+	require.NoError(t, err)
+	return string(encoded)
+}
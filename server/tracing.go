@@ -0,0 +1,124 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"time"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequestIDHeader carries the ID correlating the logs and spans of a request
+const RequestIDHeader = "X-Request-ID"
+
+// tracerName identifies the spans created by the server
+const tracerName = "tehwolf.de/tehw0lf/yaft/server"
+
+type requestIDKey struct{}
+
+// RequestID returns the ID of the request ctx belongs to, or "" outside a request
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// validRequestID accepts short printable IDs, anything else is replaced so
+// clients cannot inject into the logs
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, r := range id {
+		if r > unicode.MaxASCII || !unicode.IsPrint(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// log returns the logger with the request ID and trace ID of ctx
+func (s *Server) log(ctx context.Context) logrus.FieldLogger {
+	fields := logrus.Fields{}
+	if id := RequestID(ctx); id != "" {
+		fields["requestID"] = id
+	}
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.HasTraceID() {
+		fields["traceID"] = spanContext.TraceID().String()
+	}
+	if len(fields) == 0 {
+		return s.logger
+	}
+	return s.logger.WithFields(fields)
+}
+
+// requestID takes the X-Request-ID of the request or generates one, and
+// returns it in the response
+func (s *Server) requestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = uuid.NewString()
+		}
+		c.Header(RequestIDHeader, id)
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), requestIDKey{}, id))
+		c.Next()
+	}
+}
+
+// trace continues the trace of an incoming traceparent header and records a
+// span per request, named after the route so secrets never end up in spans
+func (s *Server) trace() gin.HandlerFunc {
+	tracer := s.options.TracerProvider.Tracer(tracerName)
+	propagator := s.options.Propagator
+
+	return func(c *gin.Context) {
+		ctx := propagator.Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+
+		route := c.FullPath()
+		name := c.Request.Method + " " + route
+		if route == "" {
+			name = c.Request.Method
+		}
+		ctx, span := tracer.Start(ctx, name,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", c.Request.Method),
+				attribute.String("http.route", route),
+				attribute.String("request.id", RequestID(ctx)),
+			),
+		)
+		defer span.End()
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	}
+}
+
+// accessLog logs every request once it has been answered
+func (s *Server) accessLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		s.log(c.Request.Context()).WithFields(logrus.Fields{
+			"method":  c.Request.Method,
+			"route":   c.FullPath(),
+			"status":  c.Writer.Status(),
+			"latency": time.Since(start).String(),
+			"client":  c.ClientIP(),
+		}).Info("Handled request")
+	}
+}
@@ -0,0 +1,41 @@
+package server
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// uiFiles is the web admin UI, a static page calling the HTTP API
+//
+//go:embed ui
+var uiFiles embed.FS
+
+// uiContentSecurityPolicy only allows the embedded files and requests to the API
+const uiContentSecurityPolicy = "default-src 'none'; script-src 'self'; style-src 'self'; img-src 'self'; connect-src 'self'; form-action 'none'; frame-ancestors 'none'; base-uri 'none'"
+
+// uiRoutes serves the web admin UI at /ui. The files are static, the page
+// opens groups and changes toggles through the same routes as every other
+// client, so it needs no credentials of its own.
+func (s *Server) uiRoutes(router *gin.Engine) {
+	files, err := fs.Sub(uiFiles, "ui")
+	if err != nil {
+		panic(err)
+	}
+	fileServer := http.StripPrefix(s.options.Prefix+"/ui", http.FileServer(http.FS(files)))
+
+	router.GET(s.options.Prefix+"/ui", func(c *gin.Context) {
+		c.Redirect(http.StatusMovedPermanently, s.options.Prefix+"/ui/")
+	})
+	router.GET(s.options.Prefix+"/ui/*filepath", func(c *gin.Context) {
+		// The page keeps the secret of the opened group, it must not be framed
+		c.Header("Content-Security-Policy", uiContentSecurityPolicy)
+		c.Header("X-Frame-Options", "DENY")
+		c.Header("X-Content-Type-Options", "nosniff")
+		c.Header("Referrer-Policy", "no-referrer")
+		c.Header("Cache-Control", "no-cache")
+		fileServer.ServeHTTP(c.Writer, c.Request)
+	})
+}
@@ -0,0 +1,320 @@
+'use strict';
+
+// The API is served next to the UI, below the same prefix
+const base = location.pathname.replace(/\/ui(\/.*)?$/, '');
+const recentChanges = 10;
+
+const state = {
+  group: sessionStorage.getItem('yaft.group') || '',
+  secret: sessionStorage.getItem('yaft.secret') || '',
+  tags: '',
+  toggles: [],
+  hash: '',
+  changes: [],
+  scheduling: null,
+};
+
+const $ = (selector) => document.querySelector(selector);
+
+// api calls the HTTP API and returns the decoded JSON, error responses are
+// thrown with their status. Reads are reported as client yaft-ui in the
+// read statistics.
+async function api(method, segments, query) {
+  let url = base + '/' + segments.map(encodeURIComponent).join('/');
+  const params = new URLSearchParams(query || {});
+  if ([...params].length > 0) {
+    url += '?' + params;
+  }
+
+  const response = await fetch(url, { method, headers: { Accept: 'application/json', 'X-Client-ID': 'yaft-ui' } });
+  const body = await response.json().catch(() => ({}));
+  if (!response.ok) {
+    const error = new Error(body.error || response.statusText);
+    error.status = response.status;
+    throw error;
+  }
+  return body;
+}
+
+// list returns all toggles of the group matching the query, following the
+// pages of the listing. A group without matching toggles answers 404.
+async function list(query) {
+  const toggles = [];
+  const params = Object.assign({ limit: '1000' }, query);
+  for (;;) {
+    let page;
+    try {
+      page = await api('GET', ['features', state.group], params);
+    } catch (error) {
+      if (error.status === 404) {
+        return toggles;
+      }
+      throw error;
+    }
+    toggles.push(...page.toggles);
+    if (!page.nextCursor) {
+      return toggles;
+    }
+    params.cursor = page.nextCursor;
+  }
+}
+
+function notify(message, isError) {
+  const notice = $('#notice');
+  notice.textContent = message;
+  notice.className = isError ? 'error' : '';
+  notice.hidden = !message;
+}
+
+function toggleName(key) {
+  return key.slice(key.indexOf('|') + 1);
+}
+
+function formatTime(value) {
+  return value ? new Date(value).toLocaleString() : '';
+}
+
+// localInput formats a date for a datetime-local input in the browser's time zone
+function localInput(value) {
+  if (!value) {
+    return '';
+  }
+  const date = new Date(value);
+  const pad = (n) => String(n).padStart(2, '0');
+  return date.getFullYear() + '-' + pad(date.getMonth() + 1) + '-' + pad(date.getDate()) +
+    'T' + pad(date.getHours()) + ':' + pad(date.getMinutes()) + ':' + pad(date.getSeconds());
+}
+
+function element(tag, text, className) {
+  const node = document.createElement(tag);
+  if (text !== undefined) {
+    node.textContent = text;
+  }
+  if (className) {
+    node.className = className;
+  }
+  return node;
+}
+
+async function openGroup(group, secret) {
+  // The status of the secret rotation is only returned for a valid secret
+  await api('GET', ['secret', 'status', group, secret]);
+  state.group = group;
+  state.secret = secret;
+  sessionStorage.setItem('yaft.group', group);
+  sessionStorage.setItem('yaft.secret', secret);
+}
+
+function closeGroup() {
+  sessionStorage.removeItem('yaft.group');
+  sessionStorage.removeItem('yaft.secret');
+  state.group = '';
+  state.secret = '';
+  state.toggles = [];
+  state.hash = '';
+  state.changes = [];
+  render();
+}
+
+async function load() {
+  const filter = state.tags ? { tags: state.tags } : {};
+  const [toggles, active, hash, changes] = await Promise.all([
+    list(filter),
+    // Listings return the value clients see, the stored value is found by filtering on it
+    list(Object.assign({ value: 'true' }, filter)),
+    api('GET', ['collectionHash', state.group], filter).catch(() => ({ collectionHash: '' })),
+    api('GET', ['features', state.group], { sort: '-updated', limit: String(recentChanges) })
+      .then((page) => page.toggles, () => []),
+  ]);
+
+  const stored = new Set(active.map((toggle) => toggle.Key));
+  state.toggles = toggles.map((toggle) => Object.assign({ stored: stored.has(toggle.Key) }, toggle));
+  state.hash = hash.collectionHash;
+  state.changes = changes;
+  render();
+}
+
+function render() {
+  const opened = state.group !== '';
+  $('#open').hidden = opened;
+  $('#group').hidden = !opened;
+  $('#close').hidden = !opened;
+  $('#opened').hidden = !opened;
+  $('#opened').textContent = state.group;
+  if (!opened) {
+    return;
+  }
+
+  $('#hash').textContent = state.hash || '-';
+  $('#filter').tags.value = state.tags;
+
+  const rows = state.toggles.map((toggle) => {
+    const row = element('tr');
+
+    const name = element('td', toggleName(toggle.Key), 'name');
+    if (toggle.Kind) {
+      name.append(' ', element('span', toggle.Kind, 'badge'));
+    }
+    if (toggle.Description) {
+      name.append(element('small', toggle.Description));
+    }
+
+    const value = element('input');
+    value.type = 'checkbox';
+    value.checked = toggle.stored;
+    value.setAttribute('aria-label', 'Turn ' + toggleName(toggle.Key) + (toggle.stored ? ' off' : ' on'));
+    value.addEventListener('change', () => run(() => setValue(toggle, value.checked)));
+
+    const effective = toggle.Value === 'true' ? 'on' : 'off';
+    const tags = element('td');
+    for (const tag of toggle.Tags || []) {
+      const button = element('button', tag, 'tag');
+      button.type = 'button';
+      button.title = 'Only show toggles tagged ' + tag;
+      button.addEventListener('click', () => run(() => filter(tag)));
+      tags.append(button);
+    }
+
+    row.append(
+      name,
+      cell(value),
+      element('td', effective, effective),
+      cell(scheduleButton(toggle, toggle.ActiveAt)),
+      cell(scheduleButton(toggle, toggle.DisabledAt)),
+      tags,
+      element('td', toggle.Owner || ''),
+    );
+    return row;
+  });
+  $('#toggles tbody').replaceChildren(...rows);
+  $('#toggles').hidden = rows.length === 0;
+  $('#empty').hidden = rows.length > 0;
+
+  $('#changes').replaceChildren(...state.changes.map((toggle) => {
+    const item = element('li');
+    item.append(
+      element('strong', toggleName(toggle.Key)),
+      ' is ' + (toggle.Value === 'true' ? 'on' : 'off') + ', changed ',
+      element('time', formatTime(toggle.UpdatedAt)),
+    );
+    return item;
+  }));
+}
+
+function cell(child) {
+  const td = element('td');
+  td.append(child);
+  return td;
+}
+
+function scheduleButton(toggle, date) {
+  const button = element('button', date ? formatTime(date) : 'not set', 'date');
+  button.type = 'button';
+  button.title = 'Edit the schedule of ' + toggleName(toggle.Key);
+  button.addEventListener('click', () => editSchedule(toggle));
+  return button;
+}
+
+async function setValue(toggle, on) {
+  await api('PUT', ['features', on ? 'activate' : 'deactivate', toggle.Key, state.secret]);
+  notify(toggleName(toggle.Key) + ' turned ' + (on ? 'on' : 'off'));
+  await load();
+}
+
+async function filter(tags) {
+  state.tags = tags;
+  await load();
+}
+
+function editSchedule(toggle) {
+  state.scheduling = toggle;
+  const form = $('#schedule form');
+  $('#schedule-name').textContent = toggleName(toggle.Key);
+  $('#schedule-zone').textContent = Intl.DateTimeFormat().resolvedOptions().timeZone;
+  form.activeAt.value = localInput(toggle.ActiveAt);
+  form.disabledAt.value = localInput(toggle.DisabledAt);
+  $('#schedule').returnValue = '';
+  $('#schedule').showModal();
+}
+
+// saveSchedule sends the changed dates. Removals go first and dates are set
+// in an order that keeps the activation before the deactivation at every step.
+async function saveSchedule(toggle, activeInput, disabledInput) {
+  const activeAt = activeInput ? new Date(activeInput) : null;
+  const disabledAt = disabledInput ? new Date(disabledInput) : null;
+  if (activeAt && disabledAt && activeAt >= disabledAt) {
+    throw new Error('The activation must be before the deactivation');
+  }
+
+  const changed = (previous, next) => (previous ? new Date(previous).getTime() : null) !== (next ? next.getTime() : null);
+  const steps = [];
+  for (const [route, previous, next] of [['activateAt', toggle.ActiveAt, activeAt], ['deactivateAt', toggle.DisabledAt, disabledAt]]) {
+    if (!changed(previous, next)) {
+      continue;
+    }
+    if (next) {
+      steps.push(['PUT', ['features', route, toggle.Key, next.toISOString(), state.secret]]);
+    } else {
+      steps.unshift(['DELETE', ['features', route, toggle.Key, state.secret]]);
+    }
+  }
+  const sets = steps.filter(([method]) => method === 'PUT');
+  if (sets.length === 2 && toggle.DisabledAt && activeAt >= new Date(toggle.DisabledAt)) {
+    sets.reverse();
+  }
+
+  for (const [method, segments] of steps.filter(([method]) => method === 'DELETE').concat(sets)) {
+    await api(method, segments);
+  }
+  notify('Schedule of ' + toggleName(toggle.Key) + ' saved');
+  await load();
+}
+
+// run reports failures of an action, invalid secrets close the group
+async function run(action) {
+  try {
+    notify('');
+    await action();
+  } catch (error) {
+    if (error.status === 401) {
+      closeGroup();
+    }
+    notify(error.message, true);
+    render();
+  }
+}
+
+$('#open').addEventListener('submit', (event) => {
+  event.preventDefault();
+  const form = event.target;
+  run(async () => {
+    await openGroup(form.group.value.trim(), form.secret.value);
+    form.reset();
+    await load();
+  });
+});
+
+$('#filter').addEventListener('submit', (event) => {
+  event.preventDefault();
+  run(() => filter(event.target.tags.value.trim()));
+});
+
+$('#filter').addEventListener('reset', () => run(() => filter('')));
+$('#refresh').addEventListener('click', () => run(load));
+$('#close').addEventListener('click', closeGroup);
+
+$('#schedule').addEventListener('close', () => {
+  const dialog = $('#schedule');
+  const form = dialog.querySelector('form');
+  if (dialog.returnValue === 'save' && state.scheduling) {
+    const toggle = state.scheduling;
+    run(() => saveSchedule(toggle, form.activeAt.value, form.disabledAt.value));
+  }
+  state.scheduling = null;
+});
+
+if (state.group) {
+  run(load);
+} else {
+  render();
+}
@@ -0,0 +1,478 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"tehwolf.de/tehw0lf/yaft/store"
+)
+
+// SecretHeader carries the secret of the group on changes through the v2 API
+const SecretHeader = "X-Yaft-Secret"
+
+var errInvalidName = errors.New("Invalid name, names must not be empty or contain \"|\" or \"/\"")
+
+// toggleV2 is a feature toggle as the v2 API returns it, for single toggles,
+// listings and changes alike. Value is what clients see now, with the
+// schedule evaluated, storedValue the value set without the schedule.
+type toggleV2 struct {
+	Key         string     `json:"key"`
+	Group       string     `json:"group"`
+	Name        string     `json:"name"`
+	Value       string     `json:"value"`
+	StoredValue string     `json:"storedValue"`
+	ActiveAt    *time.Time `json:"activeAt"`
+	DisabledAt  *time.Time `json:"disabledAt"`
+	RemoveAt    *time.Time `json:"removeAt"`
+	Tags        []string   `json:"tags"`
+	Description string     `json:"description"`
+	Owner       string     `json:"owner"`
+	Kind        string     `json:"kind"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+}
+
+func toV2(toggle store.FeatureToggle, now time.Time) toggleV2 {
+	tags := []string(toggle.Tags)
+	if tags == nil {
+		tags = []string{}
+	}
+	return toggleV2{
+		Key:         toggle.Key,
+		Group:       store.Group(toggle.Key),
+		Name:        store.Name(toggle.Key),
+		Value:       effectiveValue(toggle, now),
+		StoredValue: toggle.Value,
+		ActiveAt:    toggle.ActiveAt,
+		DisabledAt:  toggle.DisabledAt,
+		RemoveAt:    toggle.RemoveAt,
+		Tags:        tags,
+		Description: toggle.Description,
+		Owner:       toggle.Owner,
+		Kind:        toggle.Kind,
+		CreatedAt:   toggle.CreatedAt,
+		UpdatedAt:   toggle.UpdatedAt,
+	}
+}
+
+// newToggleV2 is the request body creating a toggle, unknown fields are rejected
+type newToggleV2 struct {
+	Name        string     `json:"name"`
+	Value       string     `json:"value"`
+	ActiveAt    *time.Time `json:"activeAt"`
+	DisabledAt  *time.Time `json:"disabledAt"`
+	RemoveAt    *time.Time `json:"removeAt"`
+	Tags        []string   `json:"tags"`
+	Description string     `json:"description"`
+	Owner       string     `json:"owner"`
+	Kind        string     `json:"kind"`
+}
+
+// patchFieldsV2 are the fields a patch may change, spelled as they are returned
+var patchFieldsV2 = map[string]bool{
+	"value":       true,
+	"activeAt":    true,
+	"disabledAt":  true,
+	"removeAt":    true,
+	"tags":        true,
+	"description": true,
+	"owner":       true,
+	"kind":        true,
+}
+
+// newToggleFieldsV2 are the fields of a new toggle
+var newToggleFieldsV2 = map[string]bool{
+	"name":        true,
+	"value":       true,
+	"activeAt":    true,
+	"disabledAt":  true,
+	"removeAt":    true,
+	"tags":        true,
+	"description": true,
+	"owner":       true,
+	"kind":        true,
+}
+
+// v2Routes registers the v2 API. Toggles are resources of their group,
+// addressed by group UUID and name, reads need no credentials and changes
+// send the secret of the group in the X-Yaft-Secret header. Errors are
+// answered with problem details.
+func (s *Server) v2Routes(routes *gin.RouterGroup) {
+	v2 := routes.Group("/v2")
+	v2.POST("/groups", s.createGroupV2)
+	v2.GET("/groups/:uuid/toggles", s.listTogglesV2)
+	v2.POST("/groups/:uuid/toggles", s.createToggleV2)
+	v2.GET("/groups/:uuid/toggles/:name", s.getToggleV2)
+	v2.PATCH("/groups/:uuid/toggles/:name", s.patchToggleV2)
+	v2.DELETE("/groups/:uuid/toggles/:name", s.deleteToggleV2)
+}
+
+// notFoundV2 answers requests below /v2 that match no route
+func (s *Server) notFoundV2(c *gin.Context) {
+	if s.isV2(c) {
+		s.fail(c, http.StatusNotFound, codeRouteNotFound, "Route not found")
+	}
+}
+
+// groupV2 returns the group of the route, groups that are no UUID cannot
+// exist and are answered with 404
+func (s *Server) groupV2(c *gin.Context) (string, bool) {
+	group := c.Param("uuid")
+	if _, err := uuid.Parse(group); err != nil {
+		s.fail(c, http.StatusNotFound, codeGroupNotFound, "Group not found")
+		return "", false
+	}
+	return group, true
+}
+
+// authorizeV2 checks the secret header against the group and returns it
+func (s *Server) authorizeV2(c *gin.Context, group string) (string, bool) {
+	secret := c.GetHeader(SecretHeader)
+	if !s.secretsMatch(c.Request.Context(), group, secret) {
+		s.log(c.Request.Context()).WithFields(logrus.Fields{
+			"method": c.Request.Method,
+			"path":   c.Request.URL.Path,
+			"group":  group,
+		}).Error("Invalid secret, returning 401")
+
+		s.fail(c, http.StatusUnauthorized, codeInvalidSecret, "Invalid secret")
+		return "", false
+	}
+	return secret, true
+}
+
+// bindV2 decodes a JSON object from the request body. Unlike in the v1 API,
+// field names are case-sensitive and must be one of fields.
+func (s *Server) bindV2(c *gin.Context, fields map[string]bool, target any) bool {
+	body, err := io.ReadAll(c.Request.Body)
+	var object map[string]json.RawMessage
+	if err == nil {
+		err = json.Unmarshal(body, &object)
+	}
+	for name := range object {
+		if err == nil && !fields[name] {
+			err = fmt.Errorf("unknown field %q", name)
+		}
+	}
+	if err == nil {
+		err = json.Unmarshal(body, target)
+	}
+	if err != nil {
+		s.log(c.Request.Context()).WithFields(logrus.Fields{
+			"method": c.Request.Method,
+			"path":   c.Request.URL.Path,
+			"error":  err.Error(),
+		}).Error("Failed to decode request body, returning 400")
+
+		s.fail(c, http.StatusBadRequest, codeInvalidRequest, "Invalid request body: "+err.Error())
+		return false
+	}
+	return true
+}
+
+// storeFailure answers a failed store call, unexpected errors are logged
+func (s *Server) storeFailure(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		s.fail(c, http.StatusNotFound, codeToggleNotFound, "Feature not found")
+		return
+	case errors.Is(err, store.ErrConflict):
+		s.fail(c, http.StatusConflict, codeToggleExists, "Feature already exists")
+		return
+	}
+
+	s.log(c.Request.Context()).WithFields(logrus.Fields{
+		"method": c.Request.Method,
+		"path":   c.Request.URL.Path,
+		"error":  err.Error(),
+	}).Error(message)
+
+	s.fail(c, http.StatusInternalServerError, codeInternal, message)
+}
+
+// newToggleFromV2 checks a new toggle and returns it with the key in the group
+func newToggleFromV2(group string, in newToggleV2) (store.FeatureToggle, error) {
+	if in.Name == "" || strings.ContainsAny(in.Name, "|/") {
+		return store.FeatureToggle{}, errInvalidName
+	}
+	toggle := store.FeatureToggle{
+		Key:         group + "|" + in.Name,
+		Value:       in.Value,
+		ActiveAt:    in.ActiveAt,
+		DisabledAt:  in.DisabledAt,
+		RemoveAt:    in.RemoveAt,
+		Tags:        in.Tags,
+		Description: in.Description,
+		Owner:       in.Owner,
+		Kind:        in.Kind,
+	}
+	return toggle, validateToggle(&toggle)
+}
+
+// toggleLocation is the path of a toggle in the v2 API
+func (s *Server) toggleLocation(key string) string {
+	return s.options.Prefix + "/v2/groups/" + store.Group(key) + "/toggles/" + url.PathEscape(store.Name(key))
+}
+
+// createGroupV2 creates a group with its first toggle and returns the
+// generated secret once
+func (s *Server) createGroupV2(c *gin.Context) {
+	var in newToggleV2
+	if !s.bindV2(c, newToggleFieldsV2, &in) {
+		return
+	}
+	// The group is not known yet, the toggle is checked with a placeholder
+	toggle, err := newToggleFromV2(uuid.Nil.String(), in)
+	if err != nil {
+		s.fail(c, http.StatusBadRequest, validationCode(err), err.Error())
+		return
+	}
+
+	admission, ok := s.admitGroup(c)
+	if !ok {
+		return
+	}
+	if toggle.Key, err = s.prependUUID(c.Request.Context(), in.Name); err != nil {
+		s.releaseAdmission(c, admission)
+		s.storeFailure(c, err, "Failed to create group")
+		return
+	}
+	secret := generateSecret()
+	toggle.Secret = secret
+
+	if err := s.store.Create(c.Request.Context(), &toggle); err != nil {
+		s.releaseAdmission(c, admission)
+		s.storeFailure(c, err, "Failed to create group")
+		return
+	}
+	s.saveAdmission(c, store.Group(toggle.Key), admission)
+
+	s.log(c.Request.Context()).WithFields(logrus.Fields{
+		"method": "POST",
+		"path":   c.Request.URL.Path,
+		"key":    toggle.Key,
+		"value":  toggle.Value,
+	}).Info("Successfully created feature toggle group")
+
+	c.Header("Location", s.toggleLocation(toggle.Key))
+	c.JSON(http.StatusCreated, gin.H{
+		"group":  store.Group(toggle.Key),
+		"secret": secret,
+		"toggle": toV2(toggle, time.Now()),
+	})
+}
+
+func (s *Server) listTogglesV2(c *gin.Context) {
+	group, ok := s.groupV2(c)
+	if !ok {
+		return
+	}
+	query, err := parseListQuery(c, group+"|")
+	if err != nil {
+		s.log(c.Request.Context()).WithFields(logrus.Fields{
+			"method": "GET",
+			"path":   c.Request.URL.Path,
+			"group":  group,
+			"error":  err.Error(),
+		}).Error("Invalid listing parameters, returning 400")
+
+		s.fail(c, http.StatusBadRequest, codeInvalidQuery, err.Error())
+		return
+	}
+
+	toggles, err := s.store.List(c.Request.Context(), query.filter)
+	if err != nil {
+		s.storeFailure(c, err, "Failed to list feature toggles")
+		return
+	}
+	// A group without matching toggles is an empty listing, unknown groups are not
+	if len(toggles) == 0 {
+		exists, err := s.store.GroupExists(c.Request.Context(), group)
+		if err != nil {
+			s.storeFailure(c, err, "Failed to list feature toggles")
+			return
+		}
+		if !exists {
+			s.fail(c, http.StatusNotFound, codeGroupNotFound, "Group not found")
+			return
+		}
+	}
+
+	now := time.Now()
+	total := len(toggles)
+	hash := servedHash(toggles, now)
+	toggles, nextCursor := query.page(toggles)
+
+	s.log(c.Request.Context()).WithFields(logrus.Fields{
+		"method": "GET",
+		"path":   c.Request.URL.Path,
+		"group":  group,
+		"length": len(toggles),
+		"total":  total,
+	}).Info("Returning feature toggles without secrets")
+
+	client := clientID(c)
+	listed := make([]toggleV2, 0, len(toggles))
+	for _, toggle := range toggles {
+		listed = append(listed, toV2(toggle, now))
+		s.stats.recordRead(toggle.Key, client, effectiveValue(toggle, now), false)
+	}
+	if notModified(c, hash) {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"toggles":    listed,
+		"total":      total,
+		"nextCursor": nextCursor,
+	})
+}
+
+func (s *Server) getToggleV2(c *gin.Context) {
+	group, ok := s.groupV2(c)
+	if !ok {
+		return
+	}
+	key := group + "|" + c.Param("name")
+
+	toggle, err := s.store.Get(c.Request.Context(), key)
+	var alias *store.ToggleAlias
+	if errors.Is(err, store.ErrNotFound) {
+		alias, toggle, err = s.resolveAlias(c.Request.Context(), key)
+	}
+	if err != nil {
+		s.storeFailure(c, err, "Failed to find feature toggle")
+		return
+	}
+
+	now := time.Now()
+	response := toV2(toggle, now)
+
+	s.log(c.Request.Context()).WithFields(logrus.Fields{
+		"method": "GET",
+		"path":   c.Request.URL.Path,
+		"key":    key,
+		"value":  response.Value,
+	}).Info("Returning feature toggle value without secret")
+
+	s.stats.recordRead(toggle.Key, clientID(c), response.Value, true)
+	setDeprecationHeaders(c, toggle)
+	s.setAliasHeaders(c, alias)
+	toggle.Value = response.Value
+	if notModified(c, store.HashToggles([]store.FeatureToggle{toggle})) {
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+func (s *Server) createToggleV2(c *gin.Context) {
+	group, ok := s.groupV2(c)
+	if !ok {
+		return
+	}
+	secret, ok := s.authorizeV2(c, group)
+	if !ok {
+		return
+	}
+	var in newToggleV2
+	if !s.bindV2(c, newToggleFieldsV2, &in) {
+		return
+	}
+	toggle, err := newToggleFromV2(group, in)
+	if err != nil {
+		s.fail(c, http.StatusBadRequest, validationCode(err), err.Error())
+		return
+	}
+	if !s.allowToggles(c, group, 1) {
+		return
+	}
+	toggle.Secret = s.currentSecret(c.Request.Context(), group, secret)
+
+	if err := s.store.Create(c.Request.Context(), &toggle); err != nil {
+		s.storeFailure(c, err, "Failed to create feature toggle")
+		return
+	}
+
+	s.log(c.Request.Context()).WithFields(logrus.Fields{
+		"method": "POST",
+		"path":   c.Request.URL.Path,
+		"key":    toggle.Key,
+		"value":  toggle.Value,
+	}).Info("Successfully created feature toggle")
+
+	c.Header("Location", s.toggleLocation(toggle.Key))
+	c.JSON(http.StatusCreated, toV2(toggle, time.Now()))
+}
+
+// patchToggleV2 applies a JSON merge patch with the field names of toggleV2
+func (s *Server) patchToggleV2(c *gin.Context) {
+	group, ok := s.groupV2(c)
+	if !ok {
+		return
+	}
+	if _, ok := s.authorizeV2(c, group); !ok {
+		return
+	}
+	var patch map[string]json.RawMessage
+	if !s.bindV2(c, patchFieldsV2, &patch) {
+		return
+	}
+
+	key := group + "|" + c.Param("name")
+	toggle, err := s.store.Get(c.Request.Context(), key)
+	if err != nil {
+		s.storeFailure(c, err, "Failed to find feature toggle")
+		return
+	}
+	if err := applyPatch(&toggle, patch); err != nil {
+		s.fail(c, http.StatusBadRequest, validationCode(err), err.Error())
+		return
+	}
+	if err := s.store.Save(c.Request.Context(), &toggle); err != nil {
+		s.storeFailure(c, err, "Failed to patch feature toggle")
+		return
+	}
+
+	s.log(c.Request.Context()).WithFields(logrus.Fields{
+		"method":     "PATCH",
+		"path":       c.Request.URL.Path,
+		"key":        key,
+		"value":      toggle.Value,
+		"activeAt":   toggle.ActiveAt,
+		"disabledAt": toggle.DisabledAt,
+	}).Info("Successfully patched feature toggle")
+
+	c.JSON(http.StatusOK, toV2(toggle, time.Now()))
+}
+
+func (s *Server) deleteToggleV2(c *gin.Context) {
+	group, ok := s.groupV2(c)
+	if !ok {
+		return
+	}
+	if _, ok := s.authorizeV2(c, group); !ok {
+		return
+	}
+
+	key := group + "|" + c.Param("name")
+	if err := s.store.Delete(c.Request.Context(), key); err != nil {
+		s.storeFailure(c, err, "Failed to delete feature toggle")
+		return
+	}
+
+	s.log(c.Request.Context()).WithFields(logrus.Fields{
+		"method": "DELETE",
+		"path":   c.Request.URL.Path,
+		"key":    key,
+	}).Info("Successfully deleted feature toggle")
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,163 @@
+package store
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// cachedStore serves toggle reads from memory for up to ttl. Every write
+// through the cache clears it, writes by other instances show up once the
+// cached reads expire.
+type cachedStore struct {
+	Store
+	ttl time.Duration
+
+	mu      sync.Mutex
+	toggles map[string]cachedToggle
+	lists   map[string]cachedList
+}
+
+type cachedToggle struct {
+	toggle  FeatureToggle
+	err     error
+	expires time.Time
+}
+
+type cachedList struct {
+	toggles []FeatureToggle
+	expires time.Time
+}
+
+// NewCache wraps a store with a read cache, a ttl of 0 returns the store unchanged
+func NewCache(s Store, ttl time.Duration) Store {
+	if ttl <= 0 {
+		return s
+	}
+	return &cachedStore{
+		Store:   s,
+		ttl:     ttl,
+		toggles: make(map[string]cachedToggle),
+		lists:   make(map[string]cachedList),
+	}
+}
+
+func (s *cachedStore) clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.toggles = make(map[string]cachedToggle)
+	s.lists = make(map[string]cachedList)
+}
+
+func copyToggles(toggles []FeatureToggle) []FeatureToggle {
+	copied := make([]FeatureToggle, len(toggles))
+	for i, toggle := range toggles {
+		copied[i] = clone(toggle)
+	}
+	return copied
+}
+
+func (s *cachedStore) Get(ctx context.Context, key string) (FeatureToggle, error) {
+	now := time.Now()
+
+	s.mu.Lock()
+	cached, ok := s.toggles[key]
+	s.mu.Unlock()
+	if ok && now.Before(cached.expires) {
+		return clone(cached.toggle), cached.err
+	}
+
+	toggle, err := s.Store.Get(ctx, key)
+	// Missing toggles are cached as well, group reads look them up first
+	if err == nil || err == ErrNotFound {
+		s.mu.Lock()
+		s.toggles[key] = cachedToggle{toggle: clone(toggle), err: err, expires: now.Add(s.ttl)}
+		s.mu.Unlock()
+	}
+	return toggle, err
+}
+
+func (s *cachedStore) List(ctx context.Context, filter Filter) ([]FeatureToggle, error) {
+	now := time.Now()
+	cacheKey := strings.Join([]string{filter.Prefix, strings.Join(filter.Keys, ","), strings.Join(filter.Tags, ","), filter.TagExpr.String(), filter.Owner, filter.Kind, filter.Search, filter.Value, string(filter.Schedule)}, "\x00")
+
+	s.mu.Lock()
+	cached, ok := s.lists[cacheKey]
+	s.mu.Unlock()
+	if ok && now.Before(cached.expires) {
+		return copyToggles(cached.toggles), nil
+	}
+
+	toggles, err := s.Store.List(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.lists[cacheKey] = cachedList{toggles: copyToggles(toggles), expires: now.Add(s.ttl)}
+	s.mu.Unlock()
+	return toggles, nil
+}
+
+func (s *cachedStore) Create(ctx context.Context, toggle *FeatureToggle) error {
+	defer s.clear()
+	return s.Store.Create(ctx, toggle)
+}
+
+func (s *cachedStore) CreateAll(ctx context.Context, toggles []FeatureToggle) error {
+	defer s.clear()
+	return s.Store.CreateAll(ctx, toggles)
+}
+
+func (s *cachedStore) Save(ctx context.Context, toggle *FeatureToggle) error {
+	defer s.clear()
+	return s.Store.Save(ctx, toggle)
+}
+
+func (s *cachedStore) Move(ctx context.Context, from string, to string, secret string, aliasUntil *time.Time) (FeatureToggle, error) {
+	defer s.clear()
+	return s.Store.Move(ctx, from, to, secret, aliasUntil)
+}
+
+func (s *cachedStore) UpdateAll(ctx context.Context, filter Filter, update func(toggle *FeatureToggle) error) ([]FeatureToggle, error) {
+	defer s.clear()
+	return s.Store.UpdateAll(ctx, filter, update)
+}
+
+func (s *cachedStore) Delete(ctx context.Context, key string) error {
+	defer s.clear()
+	return s.Store.Delete(ctx, key)
+}
+
+func (s *cachedStore) UpdateSecret(ctx context.Context, group string, secret string) error {
+	defer s.clear()
+	return s.Store.UpdateSecret(ctx, group, secret)
+}
+
+func (s *cachedStore) RotateSecret(ctx context.Context, group string, secret string, expiresAt time.Time) (SecretRotation, error) {
+	defer s.clear()
+	return s.Store.RotateSecret(ctx, group, secret, expiresAt)
+}
+
+func (s *cachedStore) DeleteGroup(ctx context.Context, group string) error {
+	defer s.clear()
+	return s.Store.DeleteGroup(ctx, group)
+}
+
+// Warm loads every toggle into the cache
+func (s *cachedStore) Warm(ctx context.Context) error {
+	toggles, err := s.Store.List(ctx, Filter{})
+	if err != nil {
+		return err
+	}
+
+	expires := time.Now().Add(s.ttl)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, toggle := range toggles {
+		s.toggles[toggle.Key] = cachedToggle{toggle: clone(toggle), expires: expires}
+	}
+	return nil
+}
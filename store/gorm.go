@@ -0,0 +1,485 @@
+package store
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// gormStore implements Store on top of GORM, it is shared by the SQL backends
+type gormStore struct {
+	db *gorm.DB
+	// postgres enables filtering on the native tags array
+	postgres bool
+}
+
+// models are the tables managed by the SQL backends
+var models = []interface{}{&FeatureToggle{}, &ToggleStat{}, &GroupSettings{}, &ToggleAlias{}, &SecretRotation{}, &AuditEntry{}, &InviteToken{}}
+
+func newGormStore(db *gorm.DB, postgres bool) (*gormStore, error) {
+	if err := db.Use(newTracing()); err != nil {
+		return nil, err
+	}
+
+	// Auto-migrate the schema
+	if err := db.AutoMigrate(models...); err != nil {
+		return nil, err
+	}
+	return &gormStore{db: db, postgres: postgres}, nil
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// withPrefix selects rows of the model whose key starts with prefix
+func (s *gormStore) withPrefix(ctx context.Context, model interface{}, prefix string) *gorm.DB {
+	return s.db.WithContext(ctx).Model(model).Where(`key LIKE ? ESCAPE '\'`, likeEscaper.Replace(prefix)+"%")
+}
+
+func translate(err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return ErrNotFound
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return ErrConflict
+	}
+	return err
+}
+
+func (s *gormStore) Get(ctx context.Context, key string) (FeatureToggle, error) {
+	var toggle FeatureToggle
+	err := s.db.WithContext(ctx).First(&toggle, "key = ?", key).Error
+	return toggle, translate(err)
+}
+
+func (s *gormStore) Create(ctx context.Context, toggle *FeatureToggle) error {
+	return translate(s.db.WithContext(ctx).Create(toggle).Error)
+}
+
+func (s *gormStore) CreateAll(ctx context.Context, toggles []FeatureToggle) error {
+	if len(toggles) == 0 {
+		return nil
+	}
+	return translate(s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return tx.Create(&toggles).Error
+	}))
+}
+
+func (s *gormStore) Save(ctx context.Context, toggle *FeatureToggle) error {
+	if toggle.ID == 0 {
+		return ErrNotFound
+	}
+	// Selecting the columns explicitly prevents GORM from inserting missing rows
+	result := s.db.WithContext(ctx).Select("*").Omit("created_at").Save(toggle)
+	if result.Error != nil {
+		return translate(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *gormStore) Delete(ctx context.Context, key string) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&FeatureToggle{}, "key = ?", key)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+		return tx.Delete(&ToggleStat{}, "key = ?", key).Error
+	})
+}
+
+func (s *gormStore) List(ctx context.Context, filter Filter) ([]FeatureToggle, error) {
+	return s.list(s.withPrefix(ctx, &FeatureToggle{}, filter.Prefix), filter)
+}
+
+// list finds the toggles matching the filter, query selects the key prefix
+func (s *gormStore) list(query *gorm.DB, filter Filter) ([]FeatureToggle, error) {
+	if len(filter.Keys) > 0 {
+		query = query.Where("key IN ?", filter.Keys)
+	}
+	if filter.Owner != "" {
+		query = query.Where("owner = ?", filter.Owner)
+	}
+	if filter.Kind != "" {
+		query = query.Where("kind = ?", filter.Kind)
+	}
+	if filter.Value != "" {
+		query = query.Where("value = ?", filter.Value)
+	}
+	if filter.Search != "" {
+		// Narrows down by the whole key, the name is matched below
+		query = query.Where(`LOWER(key) LIKE ? ESCAPE '\'`, "%"+likeEscaper.Replace(strings.ToLower(filter.Search))+"%")
+	}
+	if s.postgres {
+		for _, tag := range filter.Tags {
+			query = query.Where("? = ANY(tags)", tag)
+		}
+		if len(filter.TagExpr) == 1 {
+			for _, tag := range filter.TagExpr[0].All {
+				query = query.Where("? = ANY(tags)", tag)
+			}
+		}
+	}
+
+	var toggles []FeatureToggle
+	if err := query.Order("key").Find(&toggles).Error; err != nil {
+		return nil, err
+	}
+
+	// Other databases store tags as text, so tags, names and schedules are matched here
+	matching := toggles[:0]
+	for _, toggle := range toggles {
+		if filter.Matches(toggle) {
+			matching = append(matching, toggle)
+		}
+	}
+	return matching, nil
+}
+
+func (s *gormStore) Move(ctx context.Context, from string, to string, secret string, aliasUntil *time.Time) (FeatureToggle, error) {
+	var toggle FeatureToggle
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&toggle, "key = ?", from).Error; err != nil {
+			return err
+		}
+		toggle.Key = to
+		toggle.Secret = secret
+		if err := tx.Select("*").Omit("created_at").Save(&toggle).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&ToggleStat{}, "key = ?", to).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&ToggleStat{}).Where("key = ?", from).Update("key", to).Error; err != nil {
+			return err
+		}
+
+		// The new key shadows a previous alias, older aliases follow the toggle
+		if err := tx.Delete(&ToggleAlias{}, "key = ?", to).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&ToggleAlias{}).Where("target = ?", from).Update("target", to).Error; err != nil {
+			return err
+		}
+		if aliasUntil == nil {
+			return nil
+		}
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "key"}},
+			UpdateAll: true,
+		}).Create(&ToggleAlias{Key: from, Target: to, ExpiresAt: *aliasUntil}).Error
+	})
+	return toggle, translate(err)
+}
+
+func (s *gormStore) ResolveAlias(ctx context.Context, key string) (ToggleAlias, error) {
+	var alias ToggleAlias
+	err := s.db.WithContext(ctx).First(&alias, "key = ? AND expires_at > ?", key, time.Now()).Error
+	return alias, translate(err)
+}
+
+func (s *gormStore) UpdateAll(ctx context.Context, filter Filter, update func(toggle *FeatureToggle) error) ([]FeatureToggle, error) {
+	var updated []FeatureToggle
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		query := tx.Model(&FeatureToggle{}).Where(`key LIKE ? ESCAPE '\'`, likeEscaper.Replace(filter.Prefix)+"%")
+		if s.postgres {
+			// Concurrent updates of the same toggles wait for this transaction
+			query = query.Clauses(clause.Locking{Strength: "UPDATE"})
+		}
+		toggles, err := s.list(query, filter)
+		if err != nil {
+			return err
+		}
+
+		for i := range toggles {
+			if err := update(&toggles[i]); err != nil {
+				return err
+			}
+			if err := tx.Select("*").Omit("created_at").Save(&toggles[i]).Error; err != nil {
+				return err
+			}
+		}
+		updated = toggles
+		return nil
+	})
+	if err != nil {
+		return nil, translate(err)
+	}
+	return updated, nil
+}
+
+func (s *gormStore) GroupExists(ctx context.Context, group string) (bool, error) {
+	var count int64
+	err := s.withPrefix(ctx, &FeatureToggle{}, group+"|").Count(&count).Error
+	return count > 0, err
+}
+
+func (s *gormStore) SecretsMatch(ctx context.Context, group string, secret string) (bool, error) {
+	current, err := s.GroupSecret(ctx, group)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if subtle.ConstantTimeCompare([]byte(secret), []byte(current)) == 1 {
+		return true, nil
+	}
+
+	var rotation SecretRotation
+	err = s.db.WithContext(ctx).First(&rotation, "\"group\" = ? AND expires_at > ?", group, time.Now()).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if subtle.ConstantTimeCompare([]byte(secret), []byte(rotation.PreviousSecret)) != 1 {
+		return false, nil
+	}
+	err = s.db.WithContext(ctx).Model(&SecretRotation{}).Where("\"group\" = ?", group).UpdateColumn("last_used_at", time.Now()).Error
+	return err == nil, err
+}
+
+func (s *gormStore) GroupSecret(ctx context.Context, group string) (string, error) {
+	var toggle FeatureToggle
+	err := s.withPrefix(ctx, &FeatureToggle{}, group+"|").Order("key").First(&toggle).Error
+	return toggle.Secret, translate(err)
+}
+
+func (s *gormStore) UpdateSecret(ctx context.Context, group string, secret string) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&FeatureToggle{}).Where(`key LIKE ? ESCAPE '\'`, likeEscaper.Replace(group)+"|%").UpdateColumn("secret", secret).Error
+		if err != nil {
+			return err
+		}
+		return tx.Delete(&SecretRotation{}, "\"group\" = ?", group).Error
+	})
+}
+
+func (s *gormStore) RotateSecret(ctx context.Context, group string, secret string, expiresAt time.Time) (SecretRotation, error) {
+	var rotation SecretRotation
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		query := tx.Model(&FeatureToggle{}).Where(`key LIKE ? ESCAPE '\'`, likeEscaper.Replace(group)+"|%")
+		if s.postgres {
+			// Concurrent rotations of the group wait for this transaction
+			query = query.Clauses(clause.Locking{Strength: "UPDATE"})
+		}
+		var toggle FeatureToggle
+		if err := query.Order("key").First(&toggle).Error; err != nil {
+			return err
+		}
+
+		err := tx.Model(&FeatureToggle{}).Where(`key LIKE ? ESCAPE '\'`, likeEscaper.Replace(group)+"|%").UpdateColumn("secret", secret).Error
+		if err != nil {
+			return err
+		}
+
+		rotation = SecretRotation{
+			Group:          group,
+			PreviousSecret: toggle.Secret,
+			ExpiresAt:      expiresAt,
+			RotatedAt:      time.Now(),
+		}
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "group"}},
+			UpdateAll: true,
+		}).Create(&rotation).Error
+	})
+	if err != nil {
+		return SecretRotation{}, translate(err)
+	}
+	return rotation, nil
+}
+
+func (s *gormStore) GetSecretRotation(ctx context.Context, group string) (SecretRotation, error) {
+	var rotation SecretRotation
+	err := s.db.WithContext(ctx).First(&rotation, "\"group\" = ?", group).Error
+	return rotation, translate(err)
+}
+
+func (s *gormStore) GetGroupSettings(ctx context.Context, group string) (GroupSettings, error) {
+	var settings GroupSettings
+	err := s.db.WithContext(ctx).First(&settings, "\"group\" = ?", group).Error
+	return settings, translate(err)
+}
+
+func (s *gormStore) SaveGroupSettings(ctx context.Context, settings *GroupSettings) error {
+	return s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "group"}},
+		UpdateAll: true,
+	}).Create(settings).Error
+}
+
+func (s *gormStore) ListGroups(ctx context.Context) ([]GroupSummary, error) {
+	var toggles []FeatureToggle
+	if err := s.db.WithContext(ctx).Select("key", "updated_at").Order("key").Find(&toggles).Error; err != nil {
+		return nil, err
+	}
+	var frozen []GroupSettings
+	if err := s.db.WithContext(ctx).Where("frozen = ?", true).Find(&frozen).Error; err != nil {
+		return nil, err
+	}
+	return summarize(toggles, frozen), nil
+}
+
+func (s *gormStore) DeleteGroup(ctx context.Context, group string) error {
+	prefix := likeEscaper.Replace(group) + "|%"
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Where(`key LIKE ? ESCAPE '\'`, prefix).Delete(&FeatureToggle{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+		if err := tx.Where(`key LIKE ? ESCAPE '\'`, prefix).Delete(&ToggleStat{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where(`key LIKE ? ESCAPE '\' OR target LIKE ? ESCAPE '\'`, prefix, prefix).Delete(&ToggleAlias{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&SecretRotation{}, "\"group\" = ?", group).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&GroupSettings{}, "\"group\" = ?", group).Error
+	})
+}
+
+func (s *gormStore) CountCreatedGroups(ctx context.Context, clientIP string) (int, error) {
+	var count int64
+	err := s.db.WithContext(ctx).Model(&GroupSettings{}).Where("creator_ip = ?", clientIP).Count(&count).Error
+	return int(count), err
+}
+
+func (s *gormStore) CreateInvite(ctx context.Context, invite *InviteToken) error {
+	return translate(s.db.WithContext(ctx).Create(invite).Error)
+}
+
+func (s *gormStore) ListInvites(ctx context.Context) ([]InviteToken, error) {
+	var invites []InviteToken
+	err := s.db.WithContext(ctx).Order("id").Find(&invites).Error
+	return invites, err
+}
+
+func (s *gormStore) DeleteInvite(ctx context.Context, id uint) error {
+	result := s.db.WithContext(ctx).Delete(&InviteToken{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *gormStore) UseInvite(ctx context.Context, tokenHash string) (InviteToken, error) {
+	var invite InviteToken
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// The condition on uses_left keeps concurrent uses from taking the last use twice
+		result := tx.Model(&InviteToken{}).
+			Where("token_hash = ? AND uses_left > 0 AND expires_at > ?", tokenHash, time.Now()).
+			UpdateColumn("uses_left", gorm.Expr("uses_left - 1"))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+		return tx.First(&invite, "token_hash = ?", tokenHash).Error
+	})
+	if err != nil {
+		return InviteToken{}, translate(err)
+	}
+	return invite, nil
+}
+
+func (s *gormStore) ReturnInvite(ctx context.Context, tokenHash string) error {
+	result := s.db.WithContext(ctx).Model(&InviteToken{}).
+		Where("token_hash = ?", tokenHash).
+		UpdateColumn("uses_left", gorm.Expr("uses_left + 1"))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *gormStore) RecordAudit(ctx context.Context, entry *AuditEntry) error {
+	return s.db.WithContext(ctx).Create(entry).Error
+}
+
+func (s *gormStore) ListAudit(ctx context.Context, group string, limit int) ([]AuditEntry, error) {
+	query := s.db.WithContext(ctx).Order("id DESC").Limit(limit)
+	if group != "" {
+		query = query.Where("\"group\" = ?", group)
+	}
+	var entries []AuditEntry
+	err := query.Find(&entries).Error
+	return entries, err
+}
+
+func (s *gormStore) RecordStats(ctx context.Context, stats []ToggleStat) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, stat := range stats {
+			err := tx.Clauses(clause.OnConflict{
+				Columns: []clause.Column{{Name: "key"}},
+				DoUpdates: clause.Assignments(map[string]interface{}{
+					"evaluations":  gorm.Expr("toggle_stats.evaluations + ?", stat.Evaluations),
+					"reads":        gorm.Expr("toggle_stats.reads + ?", stat.Reads),
+					"last_read_at": stat.LastReadAt,
+					"last_client":  stat.LastClient,
+					"last_value":   stat.LastValue,
+				}),
+			}).Create(&stat).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *gormStore) ListStats(ctx context.Context, prefix string) ([]ToggleStat, error) {
+	var stats []ToggleStat
+	err := s.withPrefix(ctx, &ToggleStat{}, prefix).Order("key").Find(&stats).Error
+	return stats, err
+}
+
+func (s *gormStore) Ping(ctx context.Context) error {
+	sqlDB, err := s.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+func (s *gormStore) Migrated(ctx context.Context) error {
+	migrator := s.db.WithContext(ctx).Migrator()
+	for _, model := range models {
+		if !migrator.HasTable(model) {
+			return fmt.Errorf("table of %T is missing", model)
+		}
+	}
+	return nil
+}
+
+func (s *gormStore) Close() error {
+	sqlDB, err := s.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
@@ -0,0 +1,554 @@
+package store
+
+import (
+	"context"
+	"crypto/subtle"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// memoryStore keeps everything in process memory, it is meant for tests and
+// single instance deployments that do not need persistence
+type memoryStore struct {
+	mu        sync.RWMutex
+	nextID    uint
+	toggles   map[string]FeatureToggle
+	stats     map[string]ToggleStat
+	settings  map[string]GroupSettings
+	aliases   map[string]ToggleAlias
+	rotations map[string]SecretRotation
+	audit     []AuditEntry
+	invites   []InviteToken
+}
+
+func NewMemory() Store {
+	return &memoryStore{
+		toggles:   make(map[string]FeatureToggle),
+		stats:     make(map[string]ToggleStat),
+		settings:  make(map[string]GroupSettings),
+		aliases:   make(map[string]ToggleAlias),
+		rotations: make(map[string]SecretRotation),
+	}
+}
+
+// clone copies the tags, so callers cannot modify stored toggles
+func clone(toggle FeatureToggle) FeatureToggle {
+	if toggle.Tags != nil {
+		toggle.Tags = append([]string(nil), toggle.Tags...)
+	}
+	return toggle
+}
+
+func (s *memoryStore) Get(ctx context.Context, key string) (FeatureToggle, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	toggle, ok := s.toggles[key]
+	if !ok {
+		return FeatureToggle{}, ErrNotFound
+	}
+	return clone(toggle), nil
+}
+
+func (s *memoryStore) Create(ctx context.Context, toggle *FeatureToggle) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.toggles[toggle.Key]; ok {
+		return ErrConflict
+	}
+
+	now := time.Now()
+	s.nextID++
+	toggle.ID = s.nextID
+	if toggle.CreatedAt.IsZero() {
+		toggle.CreatedAt = now
+	}
+	if toggle.UpdatedAt.IsZero() {
+		toggle.UpdatedAt = now
+	}
+	s.toggles[toggle.Key] = clone(*toggle)
+	return nil
+}
+
+func (s *memoryStore) CreateAll(ctx context.Context, toggles []FeatureToggle) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := map[string]bool{}
+	for _, toggle := range toggles {
+		if _, ok := s.toggles[toggle.Key]; ok || keys[toggle.Key] {
+			return ErrConflict
+		}
+		keys[toggle.Key] = true
+	}
+
+	now := time.Now()
+	for i := range toggles {
+		s.nextID++
+		toggles[i].ID = s.nextID
+		if toggles[i].CreatedAt.IsZero() {
+			toggles[i].CreatedAt = now
+		}
+		if toggles[i].UpdatedAt.IsZero() {
+			toggles[i].UpdatedAt = now
+		}
+		s.toggles[toggles[i].Key] = clone(toggles[i])
+	}
+	return nil
+}
+
+func (s *memoryStore) Save(ctx context.Context, toggle *FeatureToggle) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var existing FeatureToggle
+	found := false
+	for _, candidate := range s.toggles {
+		if candidate.ID == toggle.ID {
+			existing, found = candidate, true
+			break
+		}
+	}
+	if toggle.ID == 0 || !found {
+		return ErrNotFound
+	}
+	if other, ok := s.toggles[toggle.Key]; ok && other.ID != toggle.ID {
+		return ErrConflict
+	}
+
+	toggle.CreatedAt = existing.CreatedAt
+	toggle.UpdatedAt = time.Now()
+	delete(s.toggles, existing.Key)
+	s.toggles[toggle.Key] = clone(*toggle)
+	return nil
+}
+
+func (s *memoryStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.toggles[key]; !ok {
+		return ErrNotFound
+	}
+	delete(s.toggles, key)
+	delete(s.stats, key)
+	return nil
+}
+
+func (s *memoryStore) List(ctx context.Context, filter Filter) ([]FeatureToggle, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var toggles []FeatureToggle
+	for _, toggle := range s.toggles {
+		if filter.Matches(toggle) {
+			toggles = append(toggles, clone(toggle))
+		}
+	}
+	sort.Slice(toggles, func(i, j int) bool { return toggles[i].Key < toggles[j].Key })
+	return toggles, nil
+}
+
+func (s *memoryStore) Move(ctx context.Context, from string, to string, secret string, aliasUntil *time.Time) (FeatureToggle, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	toggle, ok := s.toggles[from]
+	if !ok {
+		return FeatureToggle{}, ErrNotFound
+	}
+	if _, ok := s.toggles[to]; ok {
+		return FeatureToggle{}, ErrConflict
+	}
+
+	toggle.Key = to
+	toggle.Secret = secret
+	toggle.UpdatedAt = time.Now()
+	delete(s.toggles, from)
+	s.toggles[to] = toggle
+
+	delete(s.stats, to)
+	if stat, ok := s.stats[from]; ok {
+		stat.Key = to
+		delete(s.stats, from)
+		s.stats[to] = stat
+	}
+
+	delete(s.aliases, to)
+	for key, alias := range s.aliases {
+		if alias.Target == from {
+			alias.Target = to
+			s.aliases[key] = alias
+		}
+	}
+	if aliasUntil != nil {
+		s.aliases[from] = ToggleAlias{Key: from, Target: to, ExpiresAt: *aliasUntil, CreatedAt: time.Now()}
+	}
+	return clone(toggle), nil
+}
+
+func (s *memoryStore) ResolveAlias(ctx context.Context, key string) (ToggleAlias, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	alias, ok := s.aliases[key]
+	if !ok || !time.Now().Before(alias.ExpiresAt) {
+		return ToggleAlias{}, ErrNotFound
+	}
+	return alias, nil
+}
+
+func (s *memoryStore) UpdateAll(ctx context.Context, filter Filter, update func(toggle *FeatureToggle) error) ([]FeatureToggle, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var toggles []FeatureToggle
+	for _, toggle := range s.toggles {
+		if filter.Matches(toggle) {
+			toggles = append(toggles, clone(toggle))
+		}
+	}
+	sort.Slice(toggles, func(i, j int) bool { return toggles[i].Key < toggles[j].Key })
+
+	// Apply all updates before storing any, so errors leave every toggle unchanged
+	originals := make([]string, len(toggles))
+	for i := range toggles {
+		originals[i] = toggles[i].Key
+		if err := update(&toggles[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	keys := map[string]bool{}
+	for i, toggle := range toggles {
+		if other, ok := s.toggles[toggle.Key]; (ok && other.ID != toggle.ID) || keys[toggle.Key] {
+			return nil, ErrConflict
+		}
+		keys[toggle.Key] = true
+		toggles[i].CreatedAt = s.toggles[originals[i]].CreatedAt
+		toggles[i].UpdatedAt = time.Now()
+	}
+	for i, toggle := range toggles {
+		delete(s.toggles, originals[i])
+		s.toggles[toggle.Key] = clone(toggle)
+	}
+	return toggles, nil
+}
+
+// first returns the toggle of a group with the smallest key
+func (s *memoryStore) first(group string) (FeatureToggle, bool) {
+	var first FeatureToggle
+	found := false
+	for key, toggle := range s.toggles {
+		if strings.HasPrefix(key, group+"|") && (!found || key < first.Key) {
+			first, found = toggle, true
+		}
+	}
+	return first, found
+}
+
+func (s *memoryStore) GroupExists(ctx context.Context, group string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, found := s.first(group)
+	return found, nil
+}
+
+func (s *memoryStore) SecretsMatch(ctx context.Context, group string, secret string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	toggle, found := s.first(group)
+	if !found {
+		return false, nil
+	}
+	if subtle.ConstantTimeCompare([]byte(secret), []byte(toggle.Secret)) == 1 {
+		return true, nil
+	}
+
+	rotation, ok := s.rotations[group]
+	now := time.Now()
+	if !ok || !rotation.ExpiresAt.After(now) || subtle.ConstantTimeCompare([]byte(secret), []byte(rotation.PreviousSecret)) != 1 {
+		return false, nil
+	}
+	rotation.LastUsedAt = &now
+	s.rotations[group] = rotation
+	return true, nil
+}
+
+func (s *memoryStore) GroupSecret(ctx context.Context, group string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	toggle, found := s.first(group)
+	if !found {
+		return "", ErrNotFound
+	}
+	return toggle.Secret, nil
+}
+
+func (s *memoryStore) UpdateSecret(ctx context.Context, group string, secret string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.setSecret(group, secret)
+	delete(s.rotations, group)
+	return nil
+}
+
+func (s *memoryStore) RotateSecret(ctx context.Context, group string, secret string, expiresAt time.Time) (SecretRotation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	toggle, found := s.first(group)
+	if !found {
+		return SecretRotation{}, ErrNotFound
+	}
+	s.setSecret(group, secret)
+
+	rotation := SecretRotation{
+		Group:          group,
+		PreviousSecret: toggle.Secret,
+		ExpiresAt:      expiresAt,
+		RotatedAt:      time.Now(),
+	}
+	s.rotations[group] = rotation
+	return rotation, nil
+}
+
+func (s *memoryStore) GetSecretRotation(ctx context.Context, group string) (SecretRotation, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rotation, ok := s.rotations[group]
+	if !ok {
+		return SecretRotation{}, ErrNotFound
+	}
+	return rotation, nil
+}
+
+// setSecret replaces the secret of all toggles in the group, the caller holds the lock
+func (s *memoryStore) setSecret(group string, secret string) {
+	for key, toggle := range s.toggles {
+		if strings.HasPrefix(key, group+"|") {
+			toggle.Secret = secret
+			s.toggles[key] = toggle
+		}
+	}
+}
+
+func (s *memoryStore) GetGroupSettings(ctx context.Context, group string) (GroupSettings, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	settings, ok := s.settings[group]
+	if !ok {
+		return GroupSettings{}, ErrNotFound
+	}
+	settings.AllowedOrigins = append([]string(nil), settings.AllowedOrigins...)
+	return settings, nil
+}
+
+func (s *memoryStore) SaveGroupSettings(ctx context.Context, settings *GroupSettings) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	settings.UpdatedAt = time.Now()
+	stored := *settings
+	stored.AllowedOrigins = append([]string(nil), settings.AllowedOrigins...)
+	s.settings[settings.Group] = stored
+	return nil
+}
+
+func (s *memoryStore) RecordStats(ctx context.Context, stats []ToggleStat) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, stat := range stats {
+		stored, ok := s.stats[stat.Key]
+		if ok {
+			stat.Evaluations += stored.Evaluations
+			stat.Reads += stored.Reads
+		}
+		s.stats[stat.Key] = stat
+	}
+	return nil
+}
+
+func (s *memoryStore) ListGroups(ctx context.Context) ([]GroupSummary, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	toggles := make([]FeatureToggle, 0, len(s.toggles))
+	for _, toggle := range s.toggles {
+		toggles = append(toggles, toggle)
+	}
+	sort.Slice(toggles, func(i, j int) bool { return toggles[i].Key < toggles[j].Key })
+
+	var frozen []GroupSettings
+	for _, settings := range s.settings {
+		if settings.Frozen {
+			frozen = append(frozen, settings)
+		}
+	}
+	return summarize(toggles, frozen), nil
+}
+
+func (s *memoryStore) DeleteGroup(ctx context.Context, group string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, found := s.first(group); !found {
+		return ErrNotFound
+	}
+	for key := range s.toggles {
+		if strings.HasPrefix(key, group+"|") {
+			delete(s.toggles, key)
+		}
+	}
+	for key := range s.stats {
+		if strings.HasPrefix(key, group+"|") {
+			delete(s.stats, key)
+		}
+	}
+	for key, alias := range s.aliases {
+		if strings.HasPrefix(key, group+"|") || strings.HasPrefix(alias.Target, group+"|") {
+			delete(s.aliases, key)
+		}
+	}
+	delete(s.rotations, group)
+	delete(s.settings, group)
+	return nil
+}
+
+func (s *memoryStore) CountCreatedGroups(ctx context.Context, clientIP string) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	count := 0
+	for _, settings := range s.settings {
+		if settings.CreatorIP == clientIP {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (s *memoryStore) CreateInvite(ctx context.Context, invite *InviteToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.invites {
+		if existing.TokenHash == invite.TokenHash {
+			return ErrConflict
+		}
+	}
+	s.nextID++
+	invite.ID = s.nextID
+	if invite.CreatedAt.IsZero() {
+		invite.CreatedAt = time.Now()
+	}
+	s.invites = append(s.invites, *invite)
+	return nil
+}
+
+func (s *memoryStore) ListInvites(ctx context.Context) ([]InviteToken, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return append([]InviteToken(nil), s.invites...), nil
+}
+
+func (s *memoryStore) DeleteInvite(ctx context.Context, id uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, invite := range s.invites {
+		if invite.ID == id {
+			s.invites = append(s.invites[:i], s.invites[i+1:]...)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (s *memoryStore) UseInvite(ctx context.Context, tokenHash string) (InviteToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, invite := range s.invites {
+		if invite.TokenHash == tokenHash && invite.UsesLeft > 0 && invite.ExpiresAt.After(time.Now()) {
+			s.invites[i].UsesLeft--
+			return s.invites[i], nil
+		}
+	}
+	return InviteToken{}, ErrNotFound
+}
+
+func (s *memoryStore) ReturnInvite(ctx context.Context, tokenHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, invite := range s.invites {
+		if invite.TokenHash == tokenHash {
+			s.invites[i].UsesLeft++
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (s *memoryStore) RecordAudit(ctx context.Context, entry *AuditEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry.ID = uint(len(s.audit) + 1)
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+	s.audit = append(s.audit, *entry)
+	return nil
+}
+
+func (s *memoryStore) ListAudit(ctx context.Context, group string, limit int) ([]AuditEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var entries []AuditEntry
+	for i := len(s.audit) - 1; i >= 0 && len(entries) < limit; i-- {
+		if group == "" || s.audit[i].Group == group {
+			entries = append(entries, s.audit[i])
+		}
+	}
+	return entries, nil
+}
+
+func (s *memoryStore) ListStats(ctx context.Context, prefix string) ([]ToggleStat, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var stats []ToggleStat
+	for key, stat := range s.stats {
+		if strings.HasPrefix(key, prefix) {
+			stats = append(stats, stat)
+		}
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Key < stats[j].Key })
+	return stats, nil
+}
+
+func (s *memoryStore) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (s *memoryStore) Migrated(ctx context.Context) error {
+	return nil
+}
+
+func (s *memoryStore) Close() error {
+	return nil
+}
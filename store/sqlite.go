@@ -0,0 +1,25 @@
+package store
+
+import (
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// NewSQLite opens a SQLite database file, or an in-memory database for
+// ":memory:", and migrates the schema. SQLite requires a build with cgo.
+func NewSQLite(path string) (Store, error) {
+	db, err := gorm.Open(sqlite.Open(path), &gorm.Config{TranslateError: true})
+	if err != nil {
+		return nil, err
+	}
+
+	// SQLite serializes writes anyway, a single connection also keeps
+	// in-memory databases from being created once per connection
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	sqlDB.SetMaxOpenConns(1)
+
+	return newGormStore(db, false)
+}
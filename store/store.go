@@ -0,0 +1,366 @@
+// Package store persists feature toggles and their read statistics. All
+// backends implement the Store interface and behave identically, which is
+// verified by a shared conformance test suite.
+package store
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrNotFound = errors.New("feature toggle not found")
+	ErrConflict = errors.New("feature toggle already exists")
+)
+
+type FeatureToggle struct {
+	ID          uint           `gorm:"primaryKey"`
+	Key         string         `gorm:"unique;not null"`
+	Value       string         `gorm:"not null"`
+	ActiveAt    *time.Time     `gorm:"null"`
+	DisabledAt  *time.Time     `gorm:"null"`
+	Secret      string         `gorm:"null"`
+	Tags        pq.StringArray `gorm:"type:text[]"`
+	RemoveAt    *time.Time     `gorm:"null"`
+	Description string         `gorm:"null"`
+	Owner       string         `gorm:"null"`
+	Kind        string         `gorm:"null"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// ToggleStat holds the aggregated read statistics of a single feature toggle.
+// Evaluations count requests for the toggle's own key, Reads count every time
+// the toggle was served, including group listings.
+type ToggleStat struct {
+	Key         string     `gorm:"primaryKey" json:"key"`
+	Evaluations int64      `gorm:"not null;default:0" json:"evaluations"`
+	Reads       int64      `gorm:"not null;default:0" json:"reads"`
+	LastReadAt  *time.Time `gorm:"null" json:"lastReadAt"`
+	LastClient  string     `gorm:"null" json:"lastClient"`
+	LastValue   string     `gorm:"null" json:"lastValue"`
+}
+
+// GroupSettings holds the settings shared by all toggles of a group
+type GroupSettings struct {
+	Group string `gorm:"primaryKey"`
+	// AllowedOrigins restricts the browser origins that may call the group's routes
+	AllowedOrigins pq.StringArray `gorm:"type:text[]"`
+	// Frozen groups can be read but not changed
+	Frozen bool `gorm:"not null;default:false"`
+	// MaxToggles limits the toggles of the group, 0 is unlimited
+	MaxToggles int `gorm:"not null;default:0"`
+	// CreatorIP is the client IP that created the group
+	CreatorIP string `gorm:"index"`
+	UpdatedAt time.Time
+}
+
+// InviteToken admits the creation of groups while it has uses left and is not expired
+type InviteToken struct {
+	ID uint `gorm:"primaryKey" json:"id"`
+	// TokenHash is the SHA-256 hash of the token, the token itself is not stored
+	TokenHash string `gorm:"uniqueIndex;not null" json:"-"`
+	// MaxToggles limits the toggles of groups created with the token, 0 is unlimited
+	MaxToggles int       `json:"maxToggles"`
+	UsesLeft   int       `json:"usesLeft"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// GroupSummary describes a group for operators
+type GroupSummary struct {
+	Group        string    `json:"group"`
+	Toggles      int       `json:"toggles"`
+	LastModified time.Time `json:"lastModified"`
+	Frozen       bool      `json:"frozen"`
+}
+
+// AuditEntry records a request to the admin API
+type AuditEntry struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"createdAt"`
+	// Action is the method and route, e.g. "DELETE /admin/groups/:uuid"
+	Action    string `gorm:"not null" json:"action"`
+	Group     string `gorm:"index" json:"group,omitempty"`
+	Status    int    `json:"status"`
+	ClientIP  string `json:"clientIP"`
+	RequestID string `json:"requestID,omitempty"`
+}
+
+// ToggleAlias lets the old key of a moved toggle resolve to its new key until it expires
+type ToggleAlias struct {
+	Key       string `gorm:"primaryKey"`
+	Target    string `gorm:"not null"`
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+// SecretRotation keeps the previous secret of a group valid until it expires
+type SecretRotation struct {
+	Group          string `gorm:"primaryKey"`
+	PreviousSecret string `gorm:"not null"`
+	ExpiresAt      time.Time
+	// LastUsedAt is the last time the previous secret was accepted
+	LastUsedAt *time.Time
+	RotatedAt  time.Time
+}
+
+// Filter selects feature toggles, empty fields match every toggle
+type Filter struct {
+	// Prefix of the toggle keys, usually the group UUID
+	Prefix string
+	// Keys selects toggles by their full keys
+	Keys []string
+	// Tags that must all be present on a toggle
+	Tags []string
+	// TagExpr selects toggles by tags with OR and NOT
+	TagExpr TagExpr
+	Owner   string
+	Kind    string
+	// Search is a case insensitive substring of the toggle name, the key
+	// without its group
+	Search string
+	// Value is the stored value, regardless of the schedule
+	Value string
+	// Schedule selects toggles by the state of their schedule
+	Schedule ScheduleState
+}
+
+// ScheduleState classifies the schedule of a toggle at a point in time
+type ScheduleState string
+
+const (
+	// ScheduleScheduled toggles have an activation date in the future
+	ScheduleScheduled ScheduleState = "scheduled"
+	// ScheduleActive toggles have a schedule whose activation date passed
+	// and whose deactivation date, if any, did not
+	ScheduleActive ScheduleState = "active"
+	// ScheduleExpired toggles have a deactivation date in the past
+	ScheduleExpired ScheduleState = "expired"
+)
+
+// State returns the schedule state of the toggle at now, "" if it has no schedule
+func State(toggle FeatureToggle, now time.Time) ScheduleState {
+	switch {
+	case toggle.DisabledAt != nil && !now.Before(*toggle.DisabledAt):
+		return ScheduleExpired
+	case toggle.ActiveAt != nil && now.Before(*toggle.ActiveAt):
+		return ScheduleScheduled
+	case toggle.ActiveAt != nil || toggle.DisabledAt != nil:
+		return ScheduleActive
+	}
+	return ""
+}
+
+// Name returns the toggle name of a key, the part after the group
+func Name(key string) string {
+	if _, name, ok := strings.Cut(key, "|"); ok {
+		return name
+	}
+	return key
+}
+
+// Matches reports whether the toggle is selected by the filter now
+func (f Filter) Matches(toggle FeatureToggle) bool {
+	return f.MatchesAt(toggle, time.Now())
+}
+
+// MatchesAt reports whether the toggle is selected by the filter, schedules
+// are evaluated at now
+func (f Filter) MatchesAt(toggle FeatureToggle, now time.Time) bool {
+	if !strings.HasPrefix(toggle.Key, f.Prefix) {
+		return false
+	}
+	if len(f.Keys) > 0 && !slices.Contains(f.Keys, toggle.Key) {
+		return false
+	}
+	if f.Owner != "" && toggle.Owner != f.Owner {
+		return false
+	}
+	if f.Kind != "" && toggle.Kind != f.Kind {
+		return false
+	}
+	if f.Value != "" && toggle.Value != f.Value {
+		return false
+	}
+	if f.Search != "" && !strings.Contains(strings.ToLower(Name(toggle.Key)), strings.ToLower(f.Search)) {
+		return false
+	}
+	if f.Schedule != "" && State(toggle, now) != f.Schedule {
+		return false
+	}
+	for _, tag := range f.Tags {
+		if !hasTag(toggle, tag) {
+			return false
+		}
+	}
+	return f.TagExpr.Matches(toggle.Tags)
+}
+
+func hasTag(toggle FeatureToggle, tag string) bool {
+	for _, t := range toggle.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// Group returns the group UUID of a toggle key
+func Group(key string) string {
+	return strings.Split(key, "|")[0]
+}
+
+// Store is the persistence layer of YaFT
+type Store interface {
+	// Get returns the toggle with the given key or ErrNotFound
+	Get(ctx context.Context, key string) (FeatureToggle, error)
+	// Create stores a new toggle, it returns ErrConflict if the key is taken
+	Create(ctx context.Context, toggle *FeatureToggle) error
+	// CreateAll stores new toggles in one transaction, it returns ErrConflict
+	// and stores none if any key is taken
+	CreateAll(ctx context.Context, toggles []FeatureToggle) error
+	// Save updates all fields of the existing toggle with the ID of the given toggle
+	Save(ctx context.Context, toggle *FeatureToggle) error
+	// Delete removes a toggle and its statistics or returns ErrNotFound
+	Delete(ctx context.Context, key string) error
+	// List returns the toggles matching the filter ordered by key
+	List(ctx context.Context, filter Filter) ([]FeatureToggle, error)
+	// Move changes the key and secret of the toggle from and moves its read
+	// statistics along in one transaction. With aliasUntil set, the old key
+	// resolves to the new key until then. It returns ErrNotFound if from does
+	// not exist and ErrConflict if to does.
+	Move(ctx context.Context, from string, to string, secret string, aliasUntil *time.Time) (FeatureToggle, error)
+	// ResolveAlias returns the unexpired alias of key, or ErrNotFound
+	ResolveAlias(ctx context.Context, key string) (ToggleAlias, error)
+	// UpdateAll calls update for every toggle matching the filter and saves
+	// them in one transaction. If update returns an error, no toggle is
+	// changed and the error is returned. It returns the updated toggles.
+	UpdateAll(ctx context.Context, filter Filter, update func(toggle *FeatureToggle) error) ([]FeatureToggle, error)
+
+	// GroupExists reports whether any toggle belongs to the group
+	GroupExists(ctx context.Context, group string) (bool, error)
+	// SecretsMatch reports whether the secret is the secret of the group or
+	// its previous secret before the rotation expires, using the previous
+	// secret is recorded
+	SecretsMatch(ctx context.Context, group string, secret string) (bool, error)
+	// GroupSecret returns the current secret of the group or ErrNotFound
+	GroupSecret(ctx context.Context, group string) (string, error)
+	// UpdateSecret replaces the secret of all toggles in the group and ends
+	// the validity of a previous secret
+	UpdateSecret(ctx context.Context, group string, secret string) error
+	// RotateSecret replaces the secret of all toggles in the group, the
+	// current secret stays valid until expiresAt. It returns ErrNotFound if
+	// the group has no toggles.
+	RotateSecret(ctx context.Context, group string, secret string, expiresAt time.Time) (SecretRotation, error)
+	// GetSecretRotation returns the last rotation of the group or ErrNotFound
+	GetSecretRotation(ctx context.Context, group string) (SecretRotation, error)
+	// GetGroupSettings returns the settings of the group or ErrNotFound if none were saved
+	GetGroupSettings(ctx context.Context, group string) (GroupSettings, error)
+	// SaveGroupSettings creates or replaces the settings of a group
+	SaveGroupSettings(ctx context.Context, settings *GroupSettings) error
+
+	// ListGroups summarizes every group ordered by UUID
+	ListGroups(ctx context.Context) ([]GroupSummary, error)
+	// DeleteGroup removes the toggles of a group with their statistics,
+	// aliases and the group settings, it returns ErrNotFound if the group
+	// has no toggles
+	DeleteGroup(ctx context.Context, group string) error
+
+	// CountCreatedGroups counts the groups whose settings name the client IP as creator
+	CountCreatedGroups(ctx context.Context, clientIP string) (int, error)
+
+	// CreateInvite stores a new invite token
+	CreateInvite(ctx context.Context, invite *InviteToken) error
+	// ListInvites returns the invite tokens ordered by ID
+	ListInvites(ctx context.Context) ([]InviteToken, error)
+	// DeleteInvite removes an invite token or returns ErrNotFound
+	DeleteInvite(ctx context.Context, id uint) error
+	// UseInvite takes one use of the unexpired invite token with the hash,
+	// it returns ErrNotFound if there is none with uses left
+	UseInvite(ctx context.Context, tokenHash string) (InviteToken, error)
+	// ReturnInvite gives back a use taken by UseInvite when the group could
+	// not be created, it returns ErrNotFound if the token was deleted
+	ReturnInvite(ctx context.Context, tokenHash string) error
+
+	// RecordAudit stores an audit entry
+	RecordAudit(ctx context.Context, entry *AuditEntry) error
+	// ListAudit returns the newest audit entries first, limited to the group if set
+	ListAudit(ctx context.Context, group string, limit int) ([]AuditEntry, error)
+
+	// RecordStats adds the counts of the given statistics to the stored ones
+	// and replaces the details of the last read
+	RecordStats(ctx context.Context, stats []ToggleStat) error
+	// ListStats returns the stored statistics of toggles with the given key prefix
+	ListStats(ctx context.Context, prefix string) ([]ToggleStat, error)
+
+	// Ping checks that the backend is reachable
+	Ping(ctx context.Context) error
+	// Migrated checks that the schema is up to date
+	Migrated(ctx context.Context) error
+
+	Close() error
+}
+
+// Warmer is implemented by stores that can preload their caches
+type Warmer interface {
+	Warm(ctx context.Context) error
+}
+
+// summarize groups toggles ordered by key into summaries, frozen lists the
+// settings of frozen groups
+func summarize(toggles []FeatureToggle, frozen []GroupSettings) []GroupSummary {
+	isFrozen := map[string]bool{}
+	for _, settings := range frozen {
+		isFrozen[settings.Group] = settings.Frozen
+	}
+
+	summaries := []GroupSummary{}
+	for _, toggle := range toggles {
+		group := Group(toggle.Key)
+		if len(summaries) == 0 || summaries[len(summaries)-1].Group != group {
+			summaries = append(summaries, GroupSummary{Group: group, Frozen: isFrozen[group]})
+		}
+		summary := &summaries[len(summaries)-1]
+		summary.Toggles++
+		if toggle.UpdatedAt.After(summary.LastModified) {
+			summary.LastModified = toggle.UpdatedAt
+		}
+	}
+	return summaries
+}
+
+// HashToggles calculates the collection hash of toggles ordered by key, it
+// covers every field that is served to clients except the timestamps
+func HashToggles(toggles []FeatureToggle) string {
+	parts := make([]string, 0, len(toggles))
+	for _, toggle := range toggles {
+		parts = append(parts, strings.Join([]string{
+			toggle.Key,
+			toggle.Value,
+			formatTime(toggle.ActiveAt),
+			formatTime(toggle.DisabledAt),
+			strings.Join(toggle.Tags, ","),
+			formatTime(toggle.RemoveAt),
+			toggle.Description,
+			toggle.Owner,
+			toggle.Kind,
+		}, " "))
+	}
+	sum := sha256.Sum256([]byte(strings.Join(parts, " ")))
+	return hex.EncodeToString(sum[:])
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
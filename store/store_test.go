@@ -0,0 +1,753 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Conformance tests shared by all backends
+func testStore(t *testing.T, open func(t *testing.T) Store) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, s Store)
+	}{
+		{"create and get", testCreateAndGet},
+		{"create duplicate key", testCreateDuplicate},
+		{"create all", testCreateAll},
+		{"save", testSave},
+		{"delete", testDelete},
+		{"list", testList},
+		{"list search", testListSearch},
+		{"update all", testUpdateAll},
+		{"move", testMove},
+		{"collection hash", testCollectionHash},
+		{"secrets", testSecrets},
+		{"secret rotation", testSecretRotation},
+		{"stats", testStats},
+		{"group settings", testGroupSettings},
+		{"groups", testGroups},
+		{"audit", testAudit},
+		{"invites", testInvites},
+		{"health", testHealth},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := open(t)
+			defer s.Close()
+			tt.run(t, s)
+		})
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, func(t *testing.T) Store {
+		return NewMemory()
+	})
+}
+
+func TestSQLiteStore(t *testing.T) {
+	testStore(t, func(t *testing.T) Store {
+		s, err := NewSQLite(":memory:")
+		require.NoError(t, err)
+		return s
+	})
+}
+
+func TestCachedStore(t *testing.T) {
+	testStore(t, func(t *testing.T) Store {
+		return NewCache(NewMemory(), time.Minute)
+	})
+}
+
+func TestCacheExpiry(t *testing.T) {
+	ctx := context.Background()
+	backend := NewMemory()
+	cached := NewCache(backend, 50*time.Millisecond)
+	key := newGroup() + "|feature"
+
+	// Misses are cached until the next write through the cache
+	_, err := cached.Get(ctx, key)
+	assert.ErrorIs(t, err, ErrNotFound)
+	toggle := FeatureToggle{Key: key, Value: "true"}
+	require.NoError(t, cached.Create(ctx, &toggle))
+	stored, err := cached.Get(ctx, key)
+	require.NoError(t, err)
+	assert.Equal(t, "true", stored.Value)
+
+	// Writes by other instances are served once the cached read expired
+	toggle.Value = "false"
+	require.NoError(t, backend.Save(ctx, &toggle))
+	stored, err = cached.Get(ctx, key)
+	require.NoError(t, err)
+	assert.Equal(t, "true", stored.Value)
+
+	time.Sleep(60 * time.Millisecond)
+	stored, err = cached.Get(ctx, key)
+	require.NoError(t, err)
+	assert.Equal(t, "false", stored.Value)
+
+	assert.Same(t, backend, NewCache(backend, 0))
+}
+
+func TestCacheWarm(t *testing.T) {
+	ctx := context.Background()
+	backend := NewMemory()
+	key := newGroup() + "|feature"
+	toggle := FeatureToggle{Key: key, Value: "true"}
+	require.NoError(t, backend.Create(ctx, &toggle))
+
+	cached := NewCache(backend, time.Minute)
+	require.NoError(t, cached.(Warmer).Warm(ctx))
+
+	// Served from the cache, the backend has changed since warming
+	toggle.Value = "false"
+	require.NoError(t, backend.Save(ctx, &toggle))
+	stored, err := cached.Get(ctx, key)
+	require.NoError(t, err)
+	assert.Equal(t, "true", stored.Value)
+}
+
+// Runs against a scratch database given by YAFT_TEST_POSTGRES_DSN
+func TestPostgresStore(t *testing.T) {
+	dsn := os.Getenv("YAFT_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("YAFT_TEST_POSTGRES_DSN is not set")
+	}
+	testStore(t, func(t *testing.T) Store {
+		s, err := NewPostgres(dsn, Pool{})
+		require.NoError(t, err)
+		return s
+	})
+}
+
+func newGroup() string {
+	return uuid.New().String()
+}
+
+func testCreateAndGet(t *testing.T, s Store) {
+	ctx := context.Background()
+	activeAt := time.Date(2026, 10, 10, 0, 0, 0, 0, time.UTC)
+	toggle := FeatureToggle{
+		Key:      newGroup() + "|feature",
+		Value:    "true",
+		Secret:   "secret",
+		ActiveAt: &activeAt,
+		Tags:     []string{"a", "b"},
+		Owner:    "team-a",
+	}
+	require.NoError(t, s.Create(ctx, &toggle))
+	assert.NotZero(t, toggle.ID)
+	assert.False(t, toggle.CreatedAt.IsZero())
+
+	stored, err := s.Get(ctx, toggle.Key)
+	require.NoError(t, err)
+	assert.Equal(t, toggle.ID, stored.ID)
+	assert.Equal(t, "true", stored.Value)
+	assert.Equal(t, "team-a", stored.Owner)
+	assert.Equal(t, []string{"a", "b"}, []string(stored.Tags))
+	require.NotNil(t, stored.ActiveAt)
+	assert.True(t, activeAt.Equal(*stored.ActiveAt))
+
+	_, err = s.Get(ctx, "missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func testCreateDuplicate(t *testing.T, s Store) {
+	ctx := context.Background()
+	key := newGroup() + "|feature"
+	require.NoError(t, s.Create(ctx, &FeatureToggle{Key: key, Value: "true"}))
+	assert.ErrorIs(t, s.Create(ctx, &FeatureToggle{Key: key, Value: "false"}), ErrConflict)
+}
+
+func testSave(t *testing.T, s Store) {
+	ctx := context.Background()
+	group := newGroup()
+	activeAt := time.Date(2026, 10, 10, 0, 0, 0, 0, time.UTC)
+	toggle := FeatureToggle{Key: group + "|feature", Value: "false", ActiveAt: &activeAt, Owner: "team-a"}
+	require.NoError(t, s.Create(ctx, &toggle))
+	createdAt := toggle.CreatedAt
+
+	// Zero values must be saved as well
+	toggle.Value = "true"
+	toggle.ActiveAt = nil
+	toggle.Owner = ""
+	require.NoError(t, s.Save(ctx, &toggle))
+
+	stored, err := s.Get(ctx, toggle.Key)
+	require.NoError(t, err)
+	assert.Equal(t, "true", stored.Value)
+	assert.Nil(t, stored.ActiveAt)
+	assert.Empty(t, stored.Owner)
+	assert.True(t, createdAt.Equal(stored.CreatedAt))
+
+	// Saving never creates toggles
+	assert.ErrorIs(t, s.Save(ctx, &FeatureToggle{Key: group + "|other", Value: "true"}), ErrNotFound)
+	assert.ErrorIs(t, s.Save(ctx, &FeatureToggle{ID: 99999, Key: group + "|other", Value: "true"}), ErrNotFound)
+	_, err = s.Get(ctx, group+"|other")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func testDelete(t *testing.T, s Store) {
+	ctx := context.Background()
+	key := newGroup() + "|feature"
+	require.NoError(t, s.Create(ctx, &FeatureToggle{Key: key, Value: "true"}))
+	require.NoError(t, s.RecordStats(ctx, []ToggleStat{{Key: key, Reads: 1}}))
+
+	require.NoError(t, s.Delete(ctx, key))
+	_, err := s.Get(ctx, key)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	stats, err := s.ListStats(ctx, key)
+	require.NoError(t, err)
+	assert.Empty(t, stats)
+
+	assert.ErrorIs(t, s.Delete(ctx, key), ErrNotFound)
+}
+
+func testList(t *testing.T, s Store) {
+	ctx := context.Background()
+	group := newGroup()
+	for _, toggle := range []FeatureToggle{
+		{Key: group + "|c", Value: "true", Tags: []string{"a"}, Kind: "ops"},
+		{Key: group + "|a", Value: "true", Tags: []string{"a", "b"}, Owner: "team-a"},
+		{Key: group + "|b", Value: "false"},
+		{Key: newGroup() + "|a", Value: "true", Tags: []string{"a"}},
+	} {
+		toggle := toggle
+		require.NoError(t, s.Create(ctx, &toggle))
+	}
+
+	keys := func(toggles []FeatureToggle) []string {
+		result := []string{}
+		for _, toggle := range toggles {
+			result = append(result, toggle.Key)
+		}
+		return result
+	}
+
+	tests := []struct {
+		name     string
+		filter   Filter
+		expected []string
+	}{
+		{"group ordered by key", Filter{Prefix: group}, []string{group + "|a", group + "|b", group + "|c"}},
+		{"key prefix", Filter{Prefix: group + "|a"}, []string{group + "|a"}},
+		{"single tag", Filter{Prefix: group, Tags: []string{"a"}}, []string{group + "|a", group + "|c"}},
+		{"all tags", Filter{Prefix: group, Tags: []string{"a", "b"}}, []string{group + "|a"}},
+		{"owner", Filter{Prefix: group, Owner: "team-a"}, []string{group + "|a"}},
+		{"kind", Filter{Prefix: group, Kind: "ops"}, []string{group + "|c"}},
+		{"like wildcards are literal", Filter{Prefix: group + "|%"}, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			toggles, err := s.List(ctx, tt.filter)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, keys(toggles))
+		})
+	}
+}
+
+func testListSearch(t *testing.T, s Store) {
+	ctx := context.Background()
+	group := newGroup()
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+	for _, toggle := range []FeatureToggle{
+		{Key: group + "|Checkout_Beta", Value: "false", ActiveAt: &future, Tags: []string{"beta", "sprint-1"}},
+		{Key: group + "|checkout", Value: "true", ActiveAt: &past, DisabledAt: &future, Tags: []string{"sprint-1"}},
+		{Key: group + "|search", Value: "true", DisabledAt: &past, Tags: []string{"beta", "legacy"}},
+		{Key: group + "|plain", Value: "true"},
+	} {
+		toggle := toggle
+		require.NoError(t, s.Create(ctx, &toggle))
+	}
+
+	expr := func(e string) TagExpr {
+		parsed, err := ParseTagExpr(e)
+		require.NoError(t, err)
+		return parsed
+	}
+
+	tests := []struct {
+		name     string
+		filter   Filter
+		expected []string
+	}{
+		{"search ignores case", Filter{Prefix: group, Search: "CHECKOUT"}, []string{group + "|Checkout_Beta", group + "|checkout"}},
+		{"search does not match the group", Filter{Prefix: group, Search: group[:8]}, []string{}},
+		{"search wildcards are literal", Filter{Prefix: group, Search: "t_b"}, []string{group + "|Checkout_Beta"}},
+		{"value", Filter{Prefix: group, Value: "false"}, []string{group + "|Checkout_Beta"}},
+		{"scheduled", Filter{Prefix: group, Schedule: ScheduleScheduled}, []string{group + "|Checkout_Beta"}},
+		{"active", Filter{Prefix: group, Schedule: ScheduleActive}, []string{group + "|checkout"}},
+		{"expired", Filter{Prefix: group, Schedule: ScheduleExpired}, []string{group + "|search"}},
+		{"tag or", Filter{Prefix: group, TagExpr: expr("legacy|sprint-1")}, []string{group + "|Checkout_Beta", group + "|checkout", group + "|search"}},
+		{"tag not", Filter{Prefix: group, TagExpr: expr("beta,!legacy")}, []string{group + "|Checkout_Beta"}},
+		{"only not", Filter{Prefix: group, TagExpr: expr("!beta")}, []string{group + "|checkout", group + "|plain"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			toggles, err := s.List(ctx, tt.filter)
+			require.NoError(t, err)
+			keys := []string{}
+			for _, toggle := range toggles {
+				keys = append(keys, toggle.Key)
+			}
+			assert.Equal(t, tt.expected, keys)
+		})
+	}
+}
+
+func testUpdateAll(t *testing.T, s Store) {
+	ctx := context.Background()
+	group := newGroup()
+	for _, toggle := range []FeatureToggle{
+		{Key: group + "|a", Value: "false", Tags: []string{"sprint-1"}},
+		{Key: group + "|b", Value: "false", Tags: []string{"sprint-1", "beta"}},
+		{Key: group + "|c", Value: "false", Tags: []string{"sprint-2"}},
+	} {
+		toggle := toggle
+		require.NoError(t, s.Create(ctx, &toggle))
+	}
+	sprint1 := Filter{Prefix: group + "|", Tags: []string{"sprint-1"}}
+
+	updated, err := s.UpdateAll(ctx, sprint1, func(toggle *FeatureToggle) error {
+		toggle.Value = "true"
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, updated, 2)
+	assert.Equal(t, group+"|a", updated[0].Key)
+
+	for key, value := range map[string]string{"a": "true", "b": "true", "c": "false"} {
+		stored, err := s.Get(ctx, group+"|"+key)
+		require.NoError(t, err)
+		assert.Equal(t, value, stored.Value, key)
+	}
+
+	t.Run("by keys", func(t *testing.T) {
+		updated, err := s.UpdateAll(ctx, Filter{Prefix: group + "|", Keys: []string{group + "|c"}}, func(toggle *FeatureToggle) error {
+			toggle.Tags = append(toggle.Tags, "beta")
+			return nil
+		})
+		require.NoError(t, err)
+		require.Len(t, updated, 1)
+		stored, err := s.Get(ctx, group+"|c")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"sprint-2", "beta"}, []string(stored.Tags))
+	})
+
+	t.Run("error rolls back", func(t *testing.T) {
+		failure := errors.New("failure")
+		_, err := s.UpdateAll(ctx, sprint1, func(toggle *FeatureToggle) error {
+			if toggle.Key == group+"|b" {
+				return failure
+			}
+			toggle.Value = "false"
+			return nil
+		})
+		assert.ErrorIs(t, err, failure)
+
+		stored, err := s.Get(ctx, group+"|a")
+		require.NoError(t, err)
+		assert.Equal(t, "true", stored.Value)
+	})
+
+	t.Run("no match", func(t *testing.T) {
+		updated, err := s.UpdateAll(ctx, Filter{Prefix: group + "|", Tags: []string{"none"}}, func(toggle *FeatureToggle) error {
+			return errors.New("not called")
+		})
+		require.NoError(t, err)
+		assert.Empty(t, updated)
+	})
+}
+
+func testCreateAll(t *testing.T, s Store) {
+	ctx := context.Background()
+	group := newGroup()
+	require.NoError(t, s.Create(ctx, &FeatureToggle{Key: group + "|taken", Value: "true"}))
+
+	err := s.CreateAll(ctx, []FeatureToggle{
+		{Key: group + "|a", Value: "true"},
+		{Key: group + "|taken", Value: "false"},
+	})
+	assert.ErrorIs(t, err, ErrConflict)
+	_, err = s.Get(ctx, group+"|a")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	toggles := []FeatureToggle{
+		{Key: group + "|a", Value: "true", Tags: []string{"x"}},
+		{Key: group + "|b", Value: "false"},
+	}
+	require.NoError(t, s.CreateAll(ctx, toggles))
+	assert.NotZero(t, toggles[0].ID)
+	assert.NotEqual(t, toggles[0].ID, toggles[1].ID)
+
+	stored, err := s.List(ctx, Filter{Prefix: group + "|"})
+	require.NoError(t, err)
+	require.Len(t, stored, 3)
+	assert.Equal(t, []string{"x"}, []string(stored[0].Tags))
+
+	require.NoError(t, s.CreateAll(ctx, nil))
+}
+
+func testMove(t *testing.T, s Store) {
+	ctx := context.Background()
+	source, target := newGroup(), newGroup()
+	activeAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	toggle := FeatureToggle{Key: source + "|a", Value: "true", Secret: "source-secret", ActiveAt: &activeAt}
+	require.NoError(t, s.Create(ctx, &toggle))
+	require.NoError(t, s.Create(ctx, &FeatureToggle{Key: target + "|taken", Value: "true", Secret: "target-secret"}))
+	require.NoError(t, s.RecordStats(ctx, []ToggleStat{{Key: source + "|a", Evaluations: 3, Reads: 3}}))
+
+	_, err := s.Move(ctx, source+"|missing", target+"|b", "target-secret", nil)
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = s.Move(ctx, source+"|a", target+"|taken", "target-secret", nil)
+	assert.ErrorIs(t, err, ErrConflict)
+
+	until := time.Now().Add(time.Hour)
+	moved, err := s.Move(ctx, source+"|a", target+"|b", "target-secret", &until)
+	require.NoError(t, err)
+	assert.Equal(t, target+"|b", moved.Key)
+
+	stored, err := s.Get(ctx, target+"|b")
+	require.NoError(t, err)
+	assert.Equal(t, toggle.ID, stored.ID)
+	assert.True(t, activeAt.Equal(*stored.ActiveAt))
+	match, err := s.SecretsMatch(ctx, target, "target-secret")
+	require.NoError(t, err)
+	assert.True(t, match)
+	_, err = s.Get(ctx, source+"|a")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	stats, err := s.ListStats(ctx, target+"|b")
+	require.NoError(t, err)
+	require.Len(t, stats, 1)
+	assert.EqualValues(t, 3, stats[0].Evaluations)
+
+	alias, err := s.ResolveAlias(ctx, source+"|a")
+	require.NoError(t, err)
+	assert.Equal(t, target+"|b", alias.Target)
+	assert.WithinDuration(t, until, alias.ExpiresAt, time.Second)
+
+	// Moving again keeps the first alias pointing at the toggle
+	_, err = s.Move(ctx, target+"|b", target+"|c", "target-secret", nil)
+	require.NoError(t, err)
+	alias, err = s.ResolveAlias(ctx, source+"|a")
+	require.NoError(t, err)
+	assert.Equal(t, target+"|c", alias.Target)
+	_, err = s.ResolveAlias(ctx, target+"|b")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	expired := time.Now().Add(-time.Second)
+	_, err = s.Move(ctx, target+"|c", target+"|d", "target-secret", &expired)
+	require.NoError(t, err)
+	_, err = s.ResolveAlias(ctx, target+"|c")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func testCollectionHash(t *testing.T, s Store) {
+	ctx := context.Background()
+	group := newGroup()
+	toggle := FeatureToggle{Key: group + "|a", Value: "true"}
+	require.NoError(t, s.Create(ctx, &toggle))
+	require.NoError(t, s.Create(ctx, &FeatureToggle{Key: group + "|b", Value: "false", Tags: []string{"x"}}))
+
+	hash := func(filter Filter) string {
+		toggles, err := s.List(ctx, filter)
+		require.NoError(t, err)
+		return HashToggles(toggles)
+	}
+
+	all := hash(Filter{Prefix: group})
+	assert.Len(t, all, 64)
+	assert.Equal(t, all, hash(Filter{Prefix: group}))
+	assert.NotEqual(t, all, hash(Filter{Prefix: group, Tags: []string{"x"}}))
+
+	toggle.Value = "false"
+	require.NoError(t, s.Save(ctx, &toggle))
+	assert.NotEqual(t, all, hash(Filter{Prefix: group}))
+}
+
+func testSecrets(t *testing.T, s Store) {
+	ctx := context.Background()
+	group := newGroup()
+	require.NoError(t, s.Create(ctx, &FeatureToggle{Key: group + "|a", Value: "true", Secret: "old"}))
+	require.NoError(t, s.Create(ctx, &FeatureToggle{Key: group + "|b", Value: "true", Secret: "old"}))
+
+	exists, err := s.GroupExists(ctx, group)
+	require.NoError(t, err)
+	assert.True(t, exists)
+	exists, err = s.GroupExists(ctx, newGroup())
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	match, err := s.SecretsMatch(ctx, group, "old")
+	require.NoError(t, err)
+	assert.True(t, match)
+	match, err = s.SecretsMatch(ctx, group, "wrong")
+	require.NoError(t, err)
+	assert.False(t, match)
+	match, err = s.SecretsMatch(ctx, newGroup(), "old")
+	require.NoError(t, err)
+	assert.False(t, match)
+
+	require.NoError(t, s.UpdateSecret(ctx, group, "new"))
+	match, err = s.SecretsMatch(ctx, group, "old")
+	require.NoError(t, err)
+	assert.False(t, match)
+	toggle, err := s.Get(ctx, group+"|b")
+	require.NoError(t, err)
+	assert.Equal(t, "new", toggle.Secret)
+}
+
+func testSecretRotation(t *testing.T, s Store) {
+	ctx := context.Background()
+	group := newGroup()
+	require.NoError(t, s.Create(ctx, &FeatureToggle{Key: group + "|a", Value: "true", Secret: "old"}))
+	require.NoError(t, s.Create(ctx, &FeatureToggle{Key: group + "|b", Value: "true", Secret: "old"}))
+
+	_, err := s.RotateSecret(ctx, newGroup(), "new", time.Now().Add(time.Hour))
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = s.GetSecretRotation(ctx, group)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	rotation, err := s.RotateSecret(ctx, group, "new", time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, "old", rotation.PreviousSecret)
+	secret, err := s.GroupSecret(ctx, group)
+	require.NoError(t, err)
+	assert.Equal(t, "new", secret)
+
+	// Both secrets are valid during the overlap, using the old one is recorded
+	for _, secret := range []string{"new", "old"} {
+		match, err := s.SecretsMatch(ctx, group, secret)
+		require.NoError(t, err)
+		assert.True(t, match, secret)
+	}
+	rotation, err = s.GetSecretRotation(ctx, group)
+	require.NoError(t, err)
+	require.NotNil(t, rotation.LastUsedAt)
+	assert.WithinDuration(t, time.Now(), *rotation.LastUsedAt, time.Minute)
+
+	// Rotating again replaces the previous secret
+	_, err = s.RotateSecret(ctx, group, "newer", time.Now().Add(-time.Second))
+	require.NoError(t, err)
+	for secret, valid := range map[string]bool{"newer": true, "new": false, "old": false} {
+		match, err := s.SecretsMatch(ctx, group, secret)
+		require.NoError(t, err)
+		assert.Equal(t, valid, match, secret)
+	}
+
+	_, err = s.RotateSecret(ctx, group, "newest", time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	require.NoError(t, s.UpdateSecret(ctx, group, "replaced"))
+	match, err := s.SecretsMatch(ctx, group, "newest")
+	require.NoError(t, err)
+	assert.False(t, match)
+	_, err = s.GetSecretRotation(ctx, group)
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = s.GroupSecret(ctx, newGroup())
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func testGroups(t *testing.T, s Store) {
+	ctx := context.Background()
+	first, second := newGroup(), newGroup()
+	if second < first {
+		first, second = second, first
+	}
+	require.NoError(t, s.Create(ctx, &FeatureToggle{Key: first + "|a", Value: "true"}))
+	require.NoError(t, s.Create(ctx, &FeatureToggle{Key: first + "|b", Value: "true"}))
+	require.NoError(t, s.Create(ctx, &FeatureToggle{Key: second + "|a", Value: "true"}))
+	require.NoError(t, s.SaveGroupSettings(ctx, &GroupSettings{Group: second, Frozen: true}))
+	require.NoError(t, s.RecordStats(ctx, []ToggleStat{{Key: first + "|a", Reads: 1}}))
+	until := time.Now().Add(time.Hour)
+	_, err := s.Move(ctx, first+"|b", first+"|c", "", &until)
+	require.NoError(t, err)
+
+	groups, err := s.ListGroups(ctx)
+	require.NoError(t, err)
+	require.Len(t, groups, 2)
+	assert.Equal(t, first, groups[0].Group)
+	assert.Equal(t, 2, groups[0].Toggles)
+	assert.False(t, groups[0].Frozen)
+	assert.WithinDuration(t, time.Now(), groups[0].LastModified, time.Minute)
+	assert.Equal(t, second, groups[1].Group)
+	assert.True(t, groups[1].Frozen)
+
+	require.NoError(t, s.DeleteGroup(ctx, first))
+	assert.ErrorIs(t, s.DeleteGroup(ctx, first), ErrNotFound)
+	_, err = s.Get(ctx, first+"|a")
+	assert.ErrorIs(t, err, ErrNotFound)
+	stats, err := s.ListStats(ctx, first+"|")
+	require.NoError(t, err)
+	assert.Empty(t, stats)
+	_, err = s.ResolveAlias(ctx, first+"|b")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	groups, err = s.ListGroups(ctx)
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+	assert.Equal(t, second, groups[0].Group)
+}
+
+func testAudit(t *testing.T, s Store) {
+	ctx := context.Background()
+	group := newGroup()
+	require.NoError(t, s.RecordAudit(ctx, &AuditEntry{Action: "GET /admin/groups", Status: 200}))
+	entry := AuditEntry{Action: "DELETE /admin/groups/:uuid", Group: group, Status: 200, ClientIP: "192.0.2.1"}
+	require.NoError(t, s.RecordAudit(ctx, &entry))
+	assert.NotZero(t, entry.ID)
+
+	entries, err := s.ListAudit(ctx, "", 10)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "DELETE /admin/groups/:uuid", entries[0].Action)
+	assert.Equal(t, "192.0.2.1", entries[0].ClientIP)
+	assert.False(t, entries[0].CreatedAt.IsZero())
+
+	entries, err = s.ListAudit(ctx, group, 10)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	entries, err = s.ListAudit(ctx, "", 1)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func testInvites(t *testing.T, s Store) {
+	ctx := context.Background()
+	invite := InviteToken{TokenHash: "hash", MaxToggles: 5, UsesLeft: 2, ExpiresAt: time.Now().Add(time.Hour)}
+	require.NoError(t, s.CreateInvite(ctx, &invite))
+	assert.NotZero(t, invite.ID)
+	assert.ErrorIs(t, s.CreateInvite(ctx, &InviteToken{TokenHash: "hash", UsesLeft: 1}), ErrConflict)
+	require.NoError(t, s.CreateInvite(ctx, &InviteToken{TokenHash: "expired", UsesLeft: 1, ExpiresAt: time.Now().Add(-time.Second)}))
+
+	for uses := 1; uses >= 0; uses-- {
+		used, err := s.UseInvite(ctx, "hash")
+		require.NoError(t, err)
+		assert.Equal(t, uses, used.UsesLeft)
+		assert.Equal(t, 5, used.MaxToggles)
+	}
+	_, err := s.UseInvite(ctx, "hash")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// A returned use can be taken again
+	require.NoError(t, s.ReturnInvite(ctx, "hash"))
+	used, err := s.UseInvite(ctx, "hash")
+	require.NoError(t, err)
+	assert.Zero(t, used.UsesLeft)
+	assert.ErrorIs(t, s.ReturnInvite(ctx, "unknown"), ErrNotFound)
+
+	_, err = s.UseInvite(ctx, "expired")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	invites, err := s.ListInvites(ctx)
+	require.NoError(t, err)
+	require.Len(t, invites, 2)
+	assert.Equal(t, invite.ID, invites[0].ID)
+	require.NoError(t, s.DeleteInvite(ctx, invite.ID))
+	assert.ErrorIs(t, s.DeleteInvite(ctx, invite.ID), ErrNotFound)
+
+	group := newGroup()
+	require.NoError(t, s.SaveGroupSettings(ctx, &GroupSettings{Group: group, CreatorIP: "192.0.2.7", MaxToggles: 5}))
+	count, err := s.CountCreatedGroups(ctx, "192.0.2.7")
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	settings, err := s.GetGroupSettings(ctx, group)
+	require.NoError(t, err)
+	assert.Equal(t, 5, settings.MaxToggles)
+}
+
+func testStats(t *testing.T, s Store) {
+	ctx := context.Background()
+	group := newGroup()
+	first := time.Date(2026, 10, 10, 0, 0, 0, 0, time.UTC)
+	second := first.Add(time.Hour)
+
+	require.NoError(t, s.RecordStats(ctx, []ToggleStat{
+		{Key: group + "|a", Evaluations: 1, Reads: 2, LastReadAt: &first, LastClient: "one", LastValue: "true"},
+	}))
+	require.NoError(t, s.RecordStats(ctx, []ToggleStat{
+		{Key: group + "|a", Evaluations: 2, Reads: 2, LastReadAt: &second, LastClient: "two", LastValue: "false"},
+		{Key: group + "|b", Reads: 1, LastReadAt: &second, LastClient: "two", LastValue: "true"},
+	}))
+
+	stats, err := s.ListStats(ctx, group)
+	require.NoError(t, err)
+	require.Len(t, stats, 2)
+	assert.Equal(t, group+"|a", stats[0].Key)
+	assert.Equal(t, int64(3), stats[0].Evaluations)
+	assert.Equal(t, int64(4), stats[0].Reads)
+	assert.Equal(t, "two", stats[0].LastClient)
+	assert.Equal(t, "false", stats[0].LastValue)
+	require.NotNil(t, stats[0].LastReadAt)
+	assert.True(t, second.Equal(*stats[0].LastReadAt))
+	assert.Equal(t, group+"|b", stats[1].Key)
+}
+
+func testGroupSettings(t *testing.T, s Store) {
+	ctx := context.Background()
+	group := newGroup()
+
+	_, err := s.GetGroupSettings(ctx, group)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	require.NoError(t, s.SaveGroupSettings(ctx, &GroupSettings{Group: group, AllowedOrigins: []string{"https://a.example.com"}}))
+	require.NoError(t, s.SaveGroupSettings(ctx, &GroupSettings{Group: group, AllowedOrigins: []string{"https://b.example.com", "https://*.example.org"}}))
+
+	settings, err := s.GetGroupSettings(ctx, group)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://b.example.com", "https://*.example.org"}, []string(settings.AllowedOrigins))
+	assert.False(t, settings.UpdatedAt.IsZero())
+
+	_, err = s.GetGroupSettings(ctx, newGroup())
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func testHealth(t *testing.T, s Store) {
+	ctx := context.Background()
+	assert.NoError(t, s.Ping(ctx))
+	assert.NoError(t, s.Migrated(ctx))
+}
+
+func TestParseTagExpr(t *testing.T) {
+	tests := []struct {
+		expr     string
+		expected TagExpr
+		err      bool
+	}{
+		{"", nil, false},
+		{"a, b", TagExpr{{All: []string{"a", "b"}}}, false},
+		{"a,!b|c", TagExpr{{All: []string{"a"}, None: []string{"b"}}, {All: []string{"c"}}}, false},
+		{"!a", TagExpr{{None: []string{"a"}}}, false},
+		{"a|", nil, true},
+		{"a,!", nil, true},
+		{"!!a", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			parsed, err := ParseTagExpr(tt.expr)
+			if tt.err {
+				assert.ErrorIs(t, err, ErrInvalidTagExpr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, parsed)
+			if tt.expected != nil {
+				assert.Equal(t, strings.ReplaceAll(tt.expr, " ", ""), parsed.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package store
+
+import (
+	"errors"
+	"slices"
+	"strings"
+)
+
+// ErrInvalidTagExpr is returned for tag expressions that cannot be parsed
+var ErrInvalidTagExpr = errors.New("invalid tag expression")
+
+// TagExpr selects toggles by their tags. A toggle matches if it matches any
+// of the terms, an empty expression matches every toggle.
+type TagExpr []TagTerm
+
+// TagTerm requires all tags of All and none of None
+type TagTerm struct {
+	All  []string
+	None []string
+}
+
+// ParseTagExpr parses terms separated by "|", each a comma separated list of
+// tags that are required, or excluded if prefixed with "!". "beta,!legacy|qa"
+// selects toggles tagged beta but not legacy, and every toggle tagged qa.
+func ParseTagExpr(expr string) (TagExpr, error) {
+	var parsed TagExpr
+	for _, term := range strings.Split(expr, "|") {
+		var t TagTerm
+		for _, tag := range strings.Split(term, ",") {
+			tag = strings.TrimSpace(tag)
+			if tag == "" {
+				continue
+			}
+			if negated, ok := strings.CutPrefix(tag, "!"); ok {
+				negated = strings.TrimSpace(negated)
+				if negated == "" || strings.HasPrefix(negated, "!") {
+					return nil, ErrInvalidTagExpr
+				}
+				t.None = append(t.None, negated)
+			} else {
+				t.All = append(t.All, tag)
+			}
+		}
+		if len(t.All) == 0 && len(t.None) == 0 {
+			// "a|" or "a||b" leave empty terms, which would match everything
+			if strings.Contains(expr, "|") {
+				return nil, ErrInvalidTagExpr
+			}
+			continue
+		}
+		parsed = append(parsed, t)
+	}
+	return parsed, nil
+}
+
+// Matches reports whether tags satisfy the expression
+func (e TagExpr) Matches(tags []string) bool {
+	if len(e) == 0 {
+		return true
+	}
+	for _, term := range e {
+		if term.matches(tags) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t TagTerm) matches(tags []string) bool {
+	for _, tag := range t.All {
+		if !slices.Contains(tags, tag) {
+			return false
+		}
+	}
+	for _, tag := range t.None {
+		if slices.Contains(tags, tag) {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns the expression in the syntax accepted by ParseTagExpr
+func (e TagExpr) String() string {
+	terms := make([]string, len(e))
+	for i, term := range e {
+		tags := append([]string{}, term.All...)
+		for _, tag := range term.None {
+			tags = append(tags, "!"+tag)
+		}
+		terms[i] = strings.Join(tags, ",")
+	}
+	return strings.Join(terms, "|")
+}
@@ -0,0 +1,82 @@
+package store
+
+import (
+	"errors"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// tracerName identifies the spans created by the store
+const tracerName = "tehwolf.de/tehw0lf/yaft/store"
+
+const spanKey = "yaft:span"
+
+// tracing is a GORM plugin recording a span per query. Statements are
+// recorded with placeholders only, so secrets never end up in spans. Spans go
+// to the global tracer provider, they are dropped unless one is configured.
+type tracing struct {
+	tracer trace.Tracer
+}
+
+func newTracing() tracing {
+	return tracing{tracer: otel.Tracer(tracerName)}
+}
+
+func (tracing) Name() string {
+	return "yaft:tracing"
+}
+
+func (p tracing) Initialize(db *gorm.DB) error {
+	callback := db.Callback()
+	return errors.Join(
+		callback.Create().Before("*").Register("yaft:before_create", p.before("create")),
+		callback.Create().After("*").Register("yaft:after_create", p.after),
+		callback.Query().Before("*").Register("yaft:before_query", p.before("query")),
+		callback.Query().After("*").Register("yaft:after_query", p.after),
+		callback.Update().Before("*").Register("yaft:before_update", p.before("update")),
+		callback.Update().After("*").Register("yaft:after_update", p.after),
+		callback.Delete().Before("*").Register("yaft:before_delete", p.before("delete")),
+		callback.Delete().After("*").Register("yaft:after_delete", p.after),
+		callback.Row().Before("*").Register("yaft:before_row", p.before("row")),
+		callback.Row().After("*").Register("yaft:after_row", p.after),
+		callback.Raw().Before("*").Register("yaft:before_raw", p.before("raw")),
+		callback.Raw().After("*").Register("yaft:after_raw", p.after),
+	)
+}
+
+func (p tracing) before(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		ctx, span := p.tracer.Start(db.Statement.Context, "gorm."+operation,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				attribute.String("db.system.name", db.Dialector.Name()),
+				attribute.String("db.operation.name", operation),
+			),
+		)
+		db.Statement.Context = ctx
+		db.InstanceSet(spanKey, span)
+	}
+}
+
+func (p tracing) after(db *gorm.DB) {
+	value, ok := db.InstanceGet(spanKey)
+	if !ok {
+		return
+	}
+	span := value.(trace.Span)
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("db.collection.name", db.Statement.Table),
+		attribute.String("db.query.text", db.Statement.SQL.String()),
+		attribute.Int64("db.response.returned_rows", db.RowsAffected),
+	)
+	if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+		span.RecordError(db.Error)
+		span.SetStatus(codes.Error, db.Error.Error())
+	}
+}